@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,19 +13,23 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yoga/knowledge-base/internal/api/handler"
 	"github.com/yoga/knowledge-base/internal/api/middleware"
+	"github.com/yoga/knowledge-base/internal/app"
 	"github.com/yoga/knowledge-base/internal/config"
+	"github.com/yoga/knowledge-base/internal/domain/user"
 	mcpservice "github.com/yoga/knowledge-base/internal/mcp"
-	"github.com/yoga/knowledge-base/internal/mcp/tools"
 	"github.com/yoga/knowledge-base/internal/repository/postgres"
 	aiservice "github.com/yoga/knowledge-base/internal/service/ai"
-	"github.com/yoga/knowledge-base/internal/service/booking"
+	authservice "github.com/yoga/knowledge-base/internal/service/auth"
+	instructorservice "github.com/yoga/knowledge-base/internal/service/instructor"
 	"github.com/yoga/knowledge-base/internal/service/knowledge"
-	"github.com/yoga/knowledge-base/pkg/embedding"
+	orderservice "github.com/yoga/knowledge-base/internal/service/order"
+	"github.com/yoga/knowledge-base/pkg/auth"
 	mcppkg "github.com/yoga/knowledge-base/pkg/mcp"
 	"github.com/yoga/knowledge-base/pkg/observability"
 	"github.com/yoga/knowledge-base/pkg/openai"
-	"github.com/yoga/knowledge-base/pkg/storage"
-	"github.com/yoga/knowledge-base/pkg/vector"
+	"github.com/yoga/knowledge-base/pkg/payment"
+	"github.com/yoga/knowledge-base/pkg/scheduler"
+	"github.com/yoga/knowledge-base/pkg/wechat"
 	"go.uber.org/zap"
 )
 
@@ -58,60 +63,124 @@ func main() {
 		}()
 	}
 
-	// 初始化存储
-	storageClient, err := storage.NewMinIOStorage(
-		cfg.MinIO.Endpoint,
-		cfg.MinIO.AccessKeyID,
-		cfg.MinIO.SecretAccessKey,
-		cfg.MinIO.UseSSL,
-	)
+	// 初始化存储、数据库与共用的服务
+	svc, err := app.NewServices(cfg, logger)
 	if err != nil {
-		logger.Fatal("初始化存储失败", zap.Error(err))
+		logger.Fatal("初始化服务失败", zap.Error(err))
 	}
 
 	// 确保存储桶存在
 	ctx := context.Background()
-	if err := storageClient.EnsureBucket(ctx, cfg.MinIO.BucketName); err != nil {
+	if err := svc.Storage.EnsureBucket(ctx, cfg.MinIO.BucketName); err != nil {
 		logger.Fatal("创建存储桶失败", zap.Error(err))
 	}
 
-	// 初始化数据库连接（共享连接）
-	db, err := postgres.GetDB(cfg.Database.DSN())
-	if err != nil {
-		logger.Fatal("初始化数据库失败", zap.Error(err))
-	}
-
 	// 初始化仓储
-	kbRepo, err := postgres.NewKnowledgeRepository(cfg.Database.DSN())
-	if err != nil {
-		logger.Fatal("初始化知识库仓储失败", zap.Error(err))
+	convRepo := postgres.NewConversationRepository(svc.DB)
+	userRepo := postgres.NewUserRepository(svc.DB)
+
+	// 启动后台向量化worker
+	embeddingWorker := knowledge.NewEmbeddingWorker(svc.Knowledge, knowledge.WorkerConfig{
+		Concurrency:  cfg.Worker.Concurrency,
+		PollInterval: cfg.Worker.PollInterval,
+		LeaseTimeout: cfg.Worker.LeaseTimeout,
+		MaxAttempts:  cfg.Worker.MaxAttempts,
+		BaseBackoff:  cfg.Worker.BaseBackoff,
+		MaxBackoff:   cfg.Worker.MaxBackoff,
+	}, logger)
+	embeddingWorker.Start()
+
+	// 初始化认证服务
+	tokenSecret := []byte(cfg.Auth.TokenSecret)
+	if len(tokenSecret) == 0 {
+		logger.Warn("AUTH_TOKEN_SECRET未设置，使用随机密钥，服务重启后已签发的令牌将失效")
+		tokenSecret = make([]byte, 32)
+		if _, err := rand.Read(tokenSecret); err != nil {
+			logger.Fatal("生成令牌密钥失败", zap.Error(err))
+		}
+	}
+	var wechatClient authservice.WeChatClient = svc.WeChat
+	if cfg.WeChat.FakeLogin {
+		logger.Warn("已启用本地假微信登录，任意code都能登录，请勿在生产环境使用")
+		wechatClient = wechat.NewFakeClient()
 	}
-	bookingRepo := postgres.NewBookingRepository(db)
+	authService := authservice.NewService(userRepo, wechatClient, auth.NewTokenSigner(tokenSecret, cfg.Auth.TokenTTL), logger)
 
-	// 初始化服务客户端
-	embeddingClient := embedding.NewClient(cfg.Embedding.URL)
-	vectorClient := vector.NewClient(cfg.Vector.URL)
+	// 初始化教练档案服务，教练照片与知识库文件使用同一存储桶
+	instructorService := instructorservice.NewService(postgres.NewInstructorRepository(svc.DB), svc.Storage, cfg.MinIO.BucketName, logger)
 
-	kbService := knowledge.NewService(kbRepo, storageClient, embeddingClient, vectorClient, cfg.MinIO.BucketName, logger)
+	// 初始化支付网关与订单服务
+	var paymentGateway payment.Gateway
+	if cfg.Payment.Fake {
+		logger.Warn("已启用本地假支付网关，可通过接口模拟付款，请勿在生产环境使用")
+		paymentGateway = payment.NewFakeGateway(cfg.Payment.FakeSecret)
+	} else {
+		paymentGateway, err = payment.NewWeChatPay(payment.WeChatPayConfig{
+			AppID:              cfg.WeChat.AppID,
+			MchID:              cfg.Payment.MchID,
+			MchSerialNo:        cfg.Payment.MchSerialNo,
+			PrivateKeyPath:     cfg.Payment.PrivateKeyPath,
+			APIv3Key:           cfg.Payment.APIv3Key,
+			PlatformPublicPath: cfg.Payment.PlatformPublicKeyPath,
+			NotifyURL:          cfg.Payment.NotifyURL,
+		})
+		if err != nil {
+			logger.Fatal("初始化微信支付失败", zap.Error(err))
+		}
+	}
+	orderService := orderservice.NewService(postgres.NewOrderRepository(svc.DB), svc.Membership, paymentGateway, orderservice.Config{
+		OrderTTL: cfg.Payment.OrderTTL,
+	}, logger)
 
-	// 初始化定课服务
-	bookingService := booking.NewService(bookingRepo, logger)
+	// 启动定时任务：按滚动时间窗口生成课程系列的课程；结束已下课的课程并记录爽约；关闭超时未支付的订单；发送上课提醒
+	jobScheduler := scheduler.New(logger)
+	jobScheduler.Every("class-series", cfg.Booking.SeriesGenerateInterval, func(ctx context.Context) error {
+		_, err := svc.Booking.GenerateSeriesClasses(ctx)
+		return err
+	})
+	jobScheduler.Every("class-complete", cfg.Booking.CompleteInterval, func(ctx context.Context) error {
+		_, err := svc.Booking.CompleteEndedClasses(ctx)
+		return err
+	})
+	jobScheduler.Every("order-expire", cfg.Payment.ExpireInterval, func(ctx context.Context) error {
+		_, err := orderService.CloseExpiredOrders(ctx)
+		return err
+	})
+	jobScheduler.Every("class-reminder", cfg.Notify.ReminderInterval, func(ctx context.Context) error {
+		_, err := svc.Notification.SendDueReminders(ctx)
+		return err
+	})
+	jobScheduler.Start()
 
 	// 初始化MCP服务器
-	mcpServer := mcppkg.NewServer()
-	tools.RegisterBookingTools(mcpServer, bookingService, logger)
+	mcpServer := mcppkg.NewServer(cfg.MCP.ToolTimeout)
+	svc.RegisterMCPTools(mcpServer, true)
 	mcpService := mcpservice.NewService(mcpServer, logger)
+	mcpHTTPHandler := mcppkg.NewHTTPHandler(mcppkg.NewHandler(mcpServer, mcppkg.ServerInfo{
+		Name:    "yoga-knowledge-base",
+		Version: "1.0.0",
+	}), cfg.MCP.AdminToken)
 
 	// 初始化AI服务
 	openAIClient := openai.NewClient(cfg.OpenAI.APIKey, cfg.OpenAI.BaseURL, cfg.OpenAI.Model)
 	openAIAdapter := openai.NewAdapter(openAIClient)
-	aiRetriever := aiservice.NewRetriever(vectorClient, kbRepo, logger)
-	aiService := aiservice.NewService(openAIAdapter, aiRetriever, mcpService, kbRepo, logger)
+	aiService := aiservice.NewService(openAIAdapter, svc.Retriever, mcpService, svc.KnowledgeRepo, convRepo, aiservice.AgentConfig{
+		MaxIterations:    cfg.OpenAI.MaxIterations,
+		MaxToolCalls:     cfg.OpenAI.MaxToolCalls,
+		MaxHistoryTokens: cfg.OpenAI.MaxHistoryTokens,
+	}, logger)
 
 	// 初始化处理器
-	kbHandler := handler.NewKnowledgeHandler(kbService, logger)
+	authHandler := handler.NewAuthHandler(authService, logger)
+	kbHandler := handler.NewKnowledgeHandler(svc.Knowledge, logger)
 	aiHandler := handler.NewAIHandler(aiService, logger)
-	bookingHandler := handler.NewBookingHandler(bookingService, logger)
+	bookingHandler := handler.NewBookingHandler(svc.Booking, logger)
+	instructorHandler := handler.NewInstructorHandler(instructorService, logger)
+	locationHandler := handler.NewLocationHandler(svc.Location, logger)
+	membershipHandler := handler.NewMembershipHandler(svc.Membership, logger)
+	orderHandler := handler.NewOrderHandler(orderService, logger)
+	notificationHandler := handler.NewNotificationHandler(svc.Notification, logger)
+	calendarHandler := handler.NewCalendarHandler(authService, svc.Booking, logger)
 
 	// 设置Gin
 	if cfg.Log.Level != "debug" {
@@ -126,31 +195,131 @@ func main() {
 
 	// API路由
 	api := router.Group("/api/v1")
+	api.Use(middleware.AuthMiddleware(authService, logger))
 	{
-		// 知识库路由
+		// 登录认证路由
+		authGroup := api.Group("/auth")
+		{
+			authGroup.POST("/wechat", authHandler.WeChatLogin)
+			authGroup.GET("/me", middleware.RequireAuth(), authHandler.Me)
+			authGroup.PUT("/me", middleware.RequireAuth(), authHandler.UpdateProfile)
+		}
+
+		// 用户管理路由（管理员）
+		users := api.Group("/users", middleware.RequireRole(user.RoleAdmin))
+		{
+			users.PUT("/:id/role", authHandler.SetUserRole)
+			users.GET("/:id/subscriptions", membershipHandler.ListUserSubscriptions)
+		}
+
+		// 日历订阅：会员预订的私有订阅地址（以地址中的令牌识别用户）与公开的课程表
+		api.GET("/users/me/bookings.ics", calendarHandler.UserBookingFeed)
+		api.GET("/users/me/calendar", middleware.RequireAuth(), calendarHandler.GetFeedURL)
+		api.POST("/users/me/calendar/reset", middleware.RequireAuth(), calendarHandler.ResetFeedURL)
+		api.GET("/classes.ics", calendarHandler.ClassFeed)
+
+		// 知识库路由（读取公开，增删改仅管理员）
+		adminOnly := middleware.RequireRole(user.RoleAdmin)
 		bases := api.Group("/knowledge-bases")
 		{
-			bases.POST("", kbHandler.CreateBase)
+			bases.POST("", adminOnly, kbHandler.CreateBase)
 			bases.GET("", kbHandler.ListBases)
 			bases.GET("/:base_id", kbHandler.GetBase)
-			bases.PUT("/:base_id", kbHandler.UpdateBase)
-			bases.DELETE("/:base_id", kbHandler.DeleteBase)
+			bases.PUT("/:base_id", adminOnly, kbHandler.UpdateBase)
+			bases.DELETE("/:base_id", adminOnly, kbHandler.DeleteBase)
 
 			// 知识项路由
 			items := bases.Group("/:base_id/items")
 			{
-				items.POST("/text", kbHandler.CreateTextItem)
-				items.POST("/file", kbHandler.CreateFileItem)
+				items.POST("/text", adminOnly, kbHandler.CreateTextItem)
+				items.POST("/file", adminOnly, kbHandler.CreateFileItem)
 				items.GET("", kbHandler.ListItems)
 				items.GET("/:id", kbHandler.GetItem)
-				items.DELETE("/:id", kbHandler.DeleteItem)
+				items.DELETE("/:id", adminOnly, kbHandler.DeleteItem)
 			}
 		}
 
-		// AI问答路由
-		ai := api.Group("/ai")
+		// AI问答路由：管理员使用站内助手时同样可调用管理员工具
+		ai := api.Group("/ai", middleware.MCPAdminFromRole())
 		{
 			ai.POST("/chat", aiHandler.Chat)
+			ai.POST("/chat/stream", aiHandler.ChatStream)
+
+			// 会话路由（需要登录）
+			conversations := ai.Group("/conversations", middleware.RequireAuth())
+			conversations.POST("", aiHandler.CreateConversation)
+			conversations.GET("", aiHandler.ListConversations)
+			conversations.GET("/:id", aiHandler.GetConversation)
+			conversations.DELETE("/:id", aiHandler.DeleteConversation)
+		}
+
+		// 课程系列路由（教练、管理员）
+		series := api.Group("/class-series", middleware.RequireRole(user.RoleInstructor))
+		{
+			series.POST("", bookingHandler.CreateSeries)
+			series.GET("", bookingHandler.ListSeries)
+			series.GET("/:id", bookingHandler.GetSeries)
+			series.PUT("/:id", bookingHandler.UpdateSeries)
+		}
+
+		// 教练路由：列表、主页与课程表公开；档案由管理员创建，管理员或教练本人维护（由service校验归属）
+		instructors := api.Group("/instructors")
+		{
+			instructors.GET("", instructorHandler.ListInstructors)
+			instructors.GET("/:id", instructorHandler.GetInstructor)
+			instructors.GET("/:id/schedule", instructorHandler.GetSchedule)
+			instructors.POST("", adminOnly, instructorHandler.CreateInstructor)
+			instructors.PUT("/:id", middleware.RequireRole(user.RoleInstructor), instructorHandler.UpdateInstructor)
+			instructors.POST("/:id/photo", middleware.RequireRole(user.RoleInstructor), instructorHandler.UploadPhoto)
+		}
+
+		// 场馆路由：场馆与教室列表公开，维护仅管理员
+		locations := api.Group("/locations")
+		{
+			locations.GET("", locationHandler.ListLocations)
+			locations.GET("/:id", locationHandler.GetLocation)
+			locations.POST("", adminOnly, locationHandler.CreateLocation)
+			locations.PUT("/:id", adminOnly, locationHandler.UpdateLocation)
+			locations.POST("/:id/rooms", adminOnly, locationHandler.CreateRoom)
+			locations.PUT("/:id/rooms/:room_id", adminOnly, locationHandler.UpdateRoom)
+		}
+
+		// 会员卡路由：方案列表公开，开卡、调整课时与方案管理仅管理员
+		plans := api.Group("/membership-plans")
+		{
+			plans.GET("", membershipHandler.ListPlans)
+			plans.POST("", adminOnly, membershipHandler.CreatePlan)
+			plans.PUT("/:id", adminOnly, membershipHandler.UpdatePlan)
+		}
+		subscriptions := api.Group("/subscriptions", middleware.RequireAuth())
+		{
+			subscriptions.GET("", membershipHandler.ListMySubscriptions)
+			subscriptions.POST("", adminOnly, membershipHandler.GrantSubscription)
+			subscriptions.POST("/:id/freeze", membershipHandler.FreezeSubscription)
+			subscriptions.POST("/:id/unfreeze", membershipHandler.UnfreezeSubscription)
+			subscriptions.POST("/:id/adjust", adminOnly, membershipHandler.AdjustCredits)
+			subscriptions.GET("/:id/ledger", membershipHandler.ListLedger)
+		}
+
+		// 订单与支付路由：购买会员卡方案（次卡、期限卡、单次卡）
+		orders := api.Group("/orders", middleware.RequireAuth())
+		{
+			orders.POST("", orderHandler.CreateOrder)
+			orders.GET("", orderHandler.ListMyOrders)
+			orders.GET("/:id", orderHandler.GetOrder)
+			orders.POST("/:id/sync", orderHandler.SyncOrder)
+			orders.POST("/:id/refund", adminOnly, orderHandler.RefundOrder)
+			// 仅本地假支付网关可用，其他网关返回404
+			orders.POST("/:id/simulate-pay", orderHandler.SimulatePayment)
+		}
+		// 支付网关回调，按网关签名校验，无需登录
+		api.POST("/payments/notify", orderHandler.PaymentNotify)
+
+		// 通知偏好：按类别关闭通知，填写接收短信与邮件的联系方式
+		preferences := api.Group("/notification-preferences", middleware.RequireAuth())
+		{
+			preferences.GET("", notificationHandler.GetPreference)
+			preferences.PUT("", notificationHandler.UpdatePreference)
 		}
 
 		// 课程和预订路由
@@ -158,15 +327,34 @@ func main() {
 		{
 			classes.GET("", bookingHandler.ListClasses)
 			classes.GET("/:id", bookingHandler.GetClass)
-			classes.POST("/:id/book", bookingHandler.BookClass)
-			classes.DELETE("/bookings/:id", bookingHandler.CancelBooking)
-			classes.GET("/bookings", bookingHandler.ListUserBookings)
+			// 课程管理（教练只能管理自己授课的课程，由service校验归属）
+			staffOnly := middleware.RequireRole(user.RoleInstructor)
+			classes.POST("", staffOnly, bookingHandler.CreateClass)
+			classes.PUT("/:id", staffOnly, bookingHandler.UpdateClass)
+			classes.POST("/:id/reschedule", staffOnly, bookingHandler.RescheduleClass)
+			classes.POST("/:id/substitute", adminOnly, bookingHandler.SubstituteInstructor)
+			classes.POST("/:id/cancel", staffOnly, bookingHandler.CancelClass)
+			classes.POST("/:id/complete", staffOnly, bookingHandler.CompleteClass)
+			classes.POST("/:id/book", middleware.RequireAuth(), bookingHandler.BookClass)
+			classes.POST("/:id/waitlist", middleware.RequireAuth(), bookingHandler.JoinWaitlist)
+			classes.GET("/:id/waitlist", staffOnly, bookingHandler.ListWaitlist)
+			classes.DELETE("/bookings/:id", middleware.RequireAuth(), bookingHandler.CancelBooking)
+			classes.POST("/bookings/:id/no-show", staffOnly, bookingHandler.MarkNoShow)
+			// 签到：工作人员扫码，或会员在签到时间内自助签到
+			classes.POST("/check-in", staffOnly, bookingHandler.CheckInByToken)
+			classes.POST("/bookings/:id/check-in", middleware.RequireAuth(), bookingHandler.CheckIn)
+			classes.GET("/:id/attendance", staffOnly, bookingHandler.ClassAttendance)
+			classes.GET("/bookings", middleware.RequireAuth(), bookingHandler.ListUserBookings)
 			// 评价路由
-			classes.POST("/:id/reviews", bookingHandler.CreateReview)
+			classes.POST("/:id/reviews", middleware.RequireAuth(), bookingHandler.CreateReview)
 			classes.GET("/:id/reviews", bookingHandler.ListClassReviews)
 		}
 	}
 
+	// MCP Streamable HTTP端点，供外部MCP客户端接入
+	// 携带会话令牌时以对应用户身份调用工具，管理员角色可使用管理员工具
+	router.Any("/mcp", middleware.AuthMiddleware(authService, logger), middleware.MCPAdminFromRole(), gin.WrapH(mcpHTTPHandler))
+
 	// 健康检查
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -203,5 +391,16 @@ func main() {
 		logger.Error("服务器关闭失败", zap.Error(err))
 	}
 
+	if err := jobScheduler.Shutdown(ctx); err != nil {
+		logger.Error("定时任务关闭超时", zap.Error(err))
+	}
+
+	// 等待进行中的向量化任务完成
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.Worker.DrainTimeout)
+	defer drainCancel()
+	if err := embeddingWorker.Shutdown(drainCtx); err != nil {
+		logger.Error("向量化worker关闭超时，未完成的任务将在租约过期后重新处理", zap.Error(err))
+	}
+
 	logger.Info("服务器已关闭")
 }
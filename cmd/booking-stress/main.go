@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/config"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/repository/postgres"
+	"gorm.io/gorm"
+)
+
+// 预订并发压测工具：连接配置中的数据库，从多个goroutine同时预订同一节课，
+// 校验名额不超卖、同一用户不重复预订、booked_count与已确认预订数一致、候补顺位连续。
+// 测试课程与预订在结束后删除（-keep保留）。任一校验失败时以非零状态退出。
+//
+//	go run ./cmd/booking-stress -users 200 -capacity 20
+func main() {
+	users := flag.Int("users", 200, "并发预订的用户数")
+	capacity := flag.Int("capacity", 20, "课程容量")
+	dupAttempts := flag.Int("dup", 20, "同一用户并发预订的次数")
+	churn := flag.Int("churn", 50, "取消与新预订交错进行的并发数")
+	conns := flag.Int("conns", 50, "数据库连接池大小")
+	keep := flag.Bool("keep", false, "保留测试课程与预订")
+	flag.Parse()
+
+	// 只需要数据库配置
+	cfg := config.LoadMCP()
+	db, err := postgres.GetDB(cfg.Database.DSN())
+	if err != nil {
+		fail("连接数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fail("获取数据库连接池失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(*conns)
+
+	ctx := context.Background()
+	st := &stress{db: db, repo: postgres.NewBookingRepository(db)}
+
+	ok := st.run("抢最后的名额", func() error { return st.lastSeats(ctx, *users, *capacity) }) &&
+		st.run("同一用户重复预订", func() error { return st.duplicates(ctx, *dupAttempts) }) &&
+		st.run("取消、候补与预订交错", func() error { return st.churn(ctx, *capacity, *churn) })
+
+	if !*keep {
+		st.cleanup(ctx)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+type stress struct {
+	db      *gorm.DB
+	repo    *postgres.BookingRepository
+	classes []uuid.UUID
+}
+
+// run 执行一个场景并输出结果
+func (s *stress) run(name string, scenario func() error) bool {
+	start := time.Now()
+	if err := scenario(); err != nil {
+		fmt.Printf("FAIL %s（%s）: %v\n", name, time.Since(start).Round(time.Millisecond), err)
+		return false
+	}
+	fmt.Printf("ok   %s（%s）\n", name, time.Since(start).Round(time.Millisecond))
+	return true
+}
+
+// lastSeats users个用户同时预订容量为capacity的课程，只能有capacity个成功，其余返回课程已满
+func (s *stress) lastSeats(ctx context.Context, users, capacity int) error {
+	class, err := s.newClass(ctx, capacity)
+	if err != nil {
+		return err
+	}
+
+	results := s.parallel(users, func(i int) error {
+		return s.book(ctx, class.ID, uuid.NewString())
+	})
+
+	booked, full := 0, 0
+	for _, err := range results {
+		switch {
+		case err == nil:
+			booked++
+		case errors.Is(err, booking.ErrClassFull):
+			full++
+		default:
+			return fmt.Errorf("预订返回了意外的错误: %w", err)
+		}
+	}
+
+	want := min(users, capacity)
+	if booked != want || full != users-want {
+		return fmt.Errorf("成功%d个、满员%d个，期望成功%d个、满员%d个", booked, full, want, users-want)
+	}
+	return s.verify(ctx, class.ID)
+}
+
+// duplicates 同一用户并发多次预订同一课程，只能成功一次，其余返回已预订
+func (s *stress) duplicates(ctx context.Context, attempts int) error {
+	class, err := s.newClass(ctx, attempts+1)
+	if err != nil {
+		return err
+	}
+
+	userID := uuid.NewString()
+	results := s.parallel(attempts, func(i int) error {
+		return s.book(ctx, class.ID, userID)
+	})
+
+	booked := 0
+	for _, err := range results {
+		switch {
+		case err == nil:
+			booked++
+		case errors.Is(err, booking.ErrAlreadyBooked):
+		default:
+			return fmt.Errorf("预订返回了意外的错误: %w", err)
+		}
+	}
+	if booked != 1 {
+		return fmt.Errorf("同一用户预订成功%d次，期望1次", booked)
+	}
+	return s.verify(ctx, class.ID)
+}
+
+// churn 课程满员后，n个已预订的用户取消（名额自动转给候补）的同时，n个新用户预订，满员时加入候补
+func (s *stress) churn(ctx context.Context, capacity, n int) error {
+	class, err := s.newClass(ctx, capacity)
+	if err != nil {
+		return err
+	}
+
+	var bookingIDs []uuid.UUID
+	for i := 0; i < capacity; i++ {
+		b := &booking.Booking{ClassID: class.ID, UserID: uuid.NewString(), Status: "confirmed"}
+		if err := s.repo.CreateBooking(ctx, b, false); err != nil {
+			return fmt.Errorf("预订课程失败: %w", err)
+		}
+		bookingIDs = append(bookingIDs, b.ID)
+	}
+
+	cancels := min(n, capacity)
+	results := s.parallel(cancels+n, func(i int) error {
+		if i < cancels {
+			_, _, err := s.repo.CancelBooking(ctx, bookingIDs[i], booking.Cancellation{
+				By:            "booking-stress",
+				PromoteBefore: class.StartTime,
+			})
+			return err
+		}
+
+		userID := uuid.NewString()
+		err := s.book(ctx, class.ID, userID)
+		if errors.Is(err, booking.ErrClassFull) {
+			err = s.repo.JoinWaitlist(ctx, &booking.Booking{ClassID: class.ID, UserID: userID}, false)
+			// 加入候补前恰好有人取消
+			if errors.Is(err, booking.ErrClassAvailable) {
+				err = s.book(ctx, class.ID, userID)
+			}
+		}
+		return err
+	})
+
+	for _, err := range results {
+		// 重试预订时名额又被候补转正占用，属于正常结果
+		if err != nil && !errors.Is(err, booking.ErrClassFull) {
+			return fmt.Errorf("操作返回了意外的错误: %w", err)
+		}
+	}
+	return s.verify(ctx, class.ID)
+}
+
+// book 以用户身份预订课程，不扣除课时
+func (s *stress) book(ctx context.Context, classID uuid.UUID, userID string) error {
+	return s.repo.CreateBooking(ctx, &booking.Booking{
+		ClassID:  classID,
+		UserID:   userID,
+		UserName: "booking-stress",
+		Status:   "confirmed",
+	}, false)
+}
+
+// parallel 同时启动n个goroutine执行fn，返回各自的结果
+func (s *stress) parallel(n int, fn func(i int) error) []error {
+	results := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return results
+}
+
+// verify 校验课程的预订数据：booked_count等于已确认预订数且不超过容量，
+// 每个用户最多一个有效预订，候补顺位从1开始连续
+func (s *stress) verify(ctx context.Context, classID uuid.UUID) error {
+	class, err := s.repo.GetClass(ctx, classID)
+	if err != nil {
+		return err
+	}
+
+	var bookings []*booking.Booking
+	if err := s.db.WithContext(ctx).
+		Where("class_id = ? AND status IN ?", classID, []string{"confirmed", "waitlisted"}).
+		Order("waitlist_position ASC").
+		Find(&bookings).Error; err != nil {
+		return fmt.Errorf("查询预订失败: %w", err)
+	}
+
+	confirmed := 0
+	position := 0
+	seen := make(map[string]bool)
+	for _, b := range bookings {
+		if seen[b.UserID] {
+			return fmt.Errorf("用户%s有多个有效预订", b.UserID)
+		}
+		seen[b.UserID] = true
+
+		if b.Status == "confirmed" {
+			confirmed++
+			continue
+		}
+		position++
+		if b.WaitlistPosition == nil || *b.WaitlistPosition != position {
+			return fmt.Errorf("候补顺位不连续：第%d位候补的顺位为%v", position, b.WaitlistPosition)
+		}
+	}
+
+	if class.BookedCount != confirmed {
+		return fmt.Errorf("booked_count为%d，已确认预订%d个", class.BookedCount, confirmed)
+	}
+	if class.BookedCount > class.Capacity {
+		return fmt.Errorf("超卖：容量%d，已预订%d", class.Capacity, class.BookedCount)
+	}
+	if position > 0 && class.BookedCount < class.Capacity {
+		return fmt.Errorf("课程有空余名额（%d/%d）但仍有%d人候补", class.BookedCount, class.Capacity, position)
+	}
+	return nil
+}
+
+// newClass 创建一节明天开始的测试课程
+func (s *stress) newClass(ctx context.Context, capacity int) (*booking.Class, error) {
+	start := time.Now().Add(24 * time.Hour)
+	class := &booking.Class{
+		Name:      "booking-stress",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Capacity:  capacity,
+		Status:    "scheduled",
+	}
+	if err := s.repo.CreateClass(ctx, class); err != nil {
+		return nil, err
+	}
+	s.classes = append(s.classes, class.ID)
+	return class, nil
+}
+
+// cleanup 删除测试课程，预订随课程级联删除
+func (s *stress) cleanup(ctx context.Context) {
+	if len(s.classes) == 0 {
+		return
+	}
+	if err := s.db.WithContext(ctx).Where("id IN ?", s.classes).Delete(&booking.Class{}).Error; err != nil {
+		fmt.Fprintf(os.Stderr, "删除测试课程失败: %v\n", err)
+	}
+	s.classes = nil
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yoga/knowledge-base/internal/app"
+	"github.com/yoga/knowledge-base/internal/config"
+	mcppkg "github.com/yoga/knowledge-base/pkg/mcp"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// MCP stdio服务器：由MCP客户端（桌面应用、IDE等）以子进程方式启动，
+// 通过stdin/stdout交换JSON-RPC消息。stdout只能写协议消息，日志输出到stderr。
+func main() {
+	// 加载配置
+	cfg := config.LoadMCP()
+
+	// 初始化日志（zap默认输出到stderr）
+	logger, err := config.NewLogger(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	// 初始化追踪
+	tp, err := observability.InitTracer("mcp-server", cfg.Jaeger.Endpoint)
+	if err != nil {
+		logger.Error("初始化追踪失败", zap.Error(err))
+	} else {
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tp.Shutdown(ctx); err != nil {
+				logger.Error("关闭追踪器失败", zap.Error(err))
+			}
+		}()
+	}
+
+	// 初始化共用的服务（向量化由API服务器的后台worker完成）
+	svc, err := app.NewServices(cfg, logger)
+	if err != nil {
+		logger.Fatal("初始化服务失败", zap.Error(err))
+	}
+
+	// 注册MCP工具。stdio接入没有登录用户，不注册预订、会员卡等以当前用户身份操作的工具
+	mcpServer := mcppkg.NewServer(cfg.MCP.ToolTimeout)
+	svc.RegisterMCPTools(mcpServer, false)
+	handler := mcppkg.NewHandler(mcpServer, mcppkg.ServerInfo{
+		Name:    "yoga-knowledge-base",
+		Version: "1.0.0",
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// stdio服务器由本机运维人员启动，默认开放管理员工具
+	ctx = mcppkg.WithAdmin(ctx, cfg.MCP.StdioAdmin)
+
+	logger.Info("MCP stdio服务器启动")
+	if err := mcppkg.ServeStdio(ctx, handler, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		logger.Error("MCP stdio服务器异常退出", zap.Error(err))
+	}
+	logger.Info("MCP stdio服务器已关闭")
+}
@@ -20,47 +20,320 @@ CREATE TABLE IF NOT EXISTS knowledge_items (
     mime_type VARCHAR(100),
     metadata JSONB,
     vector_id VARCHAR(255), -- Qdrant中的向量ID
-    embedding_status VARCHAR(50) DEFAULT 'pending', -- 'pending', 'processing', 'completed', 'failed'
+    embedding_status VARCHAR(50) DEFAULT 'pending', -- 'pending', 'processing', 'completed', 'failed'（failed 为超过最大重试次数的死信）
+    embedding_attempts INTEGER NOT NULL DEFAULT 0, -- 已尝试向量化次数
+    embedding_error TEXT, -- 最近一次向量化失败原因
+    next_attempt_at TIMESTAMP WITH TIME ZONE, -- 下次重试时间（指数退避）
+    lease_expires_at TIMESTAMP WITH TIME ZONE, -- 处理租约到期时间，过期后可被其他worker回收
+    lease_token UUID, -- 处理租约令牌，每次领取重新生成，续租和写回状态时校验
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 已有数据库补充向量化任务队列的列
+ALTER TABLE knowledge_items ADD COLUMN IF NOT EXISTS embedding_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE knowledge_items ADD COLUMN IF NOT EXISTS embedding_error TEXT;
+ALTER TABLE knowledge_items ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE knowledge_items ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE knowledge_items ADD COLUMN IF NOT EXISTS lease_token UUID;
+
 -- 创建索引
 CREATE INDEX IF NOT EXISTS idx_knowledge_items_base_id ON knowledge_items(knowledge_base_id);
 CREATE INDEX IF NOT EXISTS idx_knowledge_items_vector_id ON knowledge_items(vector_id);
 CREATE INDEX IF NOT EXISTS idx_knowledge_items_embedding_status ON knowledge_items(embedding_status);
+CREATE INDEX IF NOT EXISTS idx_knowledge_items_embedding_queue ON knowledge_items(created_at)
+    WHERE embedding_status IN ('pending', 'processing');
+
+-- 知识项分块表（长文档按段落/句子切分后逐块向量化）
+CREATE TABLE IF NOT EXISTS knowledge_chunks (
+    id UUID PRIMARY KEY, -- 同时作为Qdrant中的向量ID
+    knowledge_item_id UUID NOT NULL REFERENCES knowledge_items(id) ON DELETE CASCADE,
+    knowledge_base_id UUID NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    heading TEXT, -- 所属Markdown标题路径
+    content TEXT NOT NULL,
+    start_offset INTEGER NOT NULL, -- 在知识项内容中的字符偏移
+    end_offset INTEGER NOT NULL,
+    vector_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (knowledge_item_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_base_id ON knowledge_chunks(knowledge_base_id);
+
+-- 用户表（微信小程序用户）
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    open_id VARCHAR(128) NOT NULL UNIQUE, -- 小程序openid
+    union_id VARCHAR(128), -- 开放平台unionid
+    nickname VARCHAR(255),
+    avatar_url VARCHAR(512),
+    role VARCHAR(50) NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'instructor', 'admin')), -- 角色
+    calendar_token VARCHAR(64) UNIQUE, -- 私有日历订阅地址的令牌
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 已有数据库补充用户表新增的列
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(50) NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'instructor', 'admin'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token VARCHAR(64) UNIQUE;
+
+-- AI会话表
+CREATE TABLE IF NOT EXISTS conversations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id VARCHAR(255) NOT NULL, -- 用户ID（users.id）
+    title VARCHAR(255),
+    summary TEXT, -- 早期消息的摘要（超出上下文窗口时生成）
+    summarized_count INTEGER NOT NULL DEFAULT 0, -- 已被摘要覆盖的最早消息条数
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- AI会话消息表
+CREATE TABLE IF NOT EXISTS messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL, -- 'user', 'assistant'
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_user_id ON conversations(user_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id, created_at);
+
+-- 会员卡方案表
+CREATE TABLE IF NOT EXISTS membership_plans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('unlimited', 'class_pack', 'drop_in')), -- 期限卡、次卡、单次卡
+    credits INTEGER CHECK (credits > 0), -- 可用节数，期限卡为空
+    validity_days INTEGER NOT NULL CHECK (validity_days > 0), -- 开卡后的有效天数
+    price_cents INTEGER NOT NULL DEFAULT 0 CHECK (price_cents >= 0), -- 售价（分）
+    active BOOLEAN NOT NULL DEFAULT TRUE, -- 是否在售
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 会员卡表
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id VARCHAR(255) NOT NULL, -- 用户ID（users.id）
+    plan_id UUID NOT NULL REFERENCES membership_plans(id),
+    plan_name VARCHAR(255) NOT NULL, -- 开卡时的方案名称
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'frozen', 'cancelled')),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    remaining_credits INTEGER CHECK (remaining_credits >= 0), -- 剩余节数，期限卡为空
+    frozen_at TIMESTAMP WITH TIME ZONE, -- 冻结开始时间，解冻时按冻结时长顺延有效期
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 课时流水表：每次课时变动一条，只增不改
+CREATE TABLE IF NOT EXISTS credit_ledger (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id),
+    user_id VARCHAR(255) NOT NULL,
+    booking_id UUID, -- 关联的预订（预订扣除、取消退还）
+    delta INTEGER NOT NULL, -- 变动节数，扣除为负
+    balance INTEGER NOT NULL, -- 变动后的剩余节数
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('purchase', 'booking', 'refund', 'adjustment')),
+    note TEXT,
+    created_by VARCHAR(255), -- 操作人的用户ID，系统操作为空
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 订单表：购买会员卡方案，支付成功后开卡
+CREATE TABLE IF NOT EXISTS orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_no VARCHAR(32) NOT NULL UNIQUE, -- 商户订单号（支付网关的out_trade_no）
+    user_id VARCHAR(255) NOT NULL, -- 用户ID（users.id）
+    plan_id UUID NOT NULL REFERENCES membership_plans(id),
+    description VARCHAR(255) NOT NULL, -- 商品描述，取下单时的方案名称
+    amount_cents INTEGER NOT NULL CHECK (amount_cents > 0), -- 应付金额（分）
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'closed', 'refunded')),
+    prepay_id VARCHAR(128),
+    transaction_id VARCHAR(64), -- 支付网关交易号
+    subscription_id UUID REFERENCES subscriptions(id), -- 支付成功后开出的会员卡
+    refund_no VARCHAR(64), -- 商户退款单号
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL, -- 支付截止时间
+    paid_at TIMESTAMP WITH TIME ZONE,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    refunded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 教练表（教练档案，课程通过instructor_id关联）
+CREATE TABLE IF NOT EXISTS instructors (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID UNIQUE REFERENCES users(id) ON DELETE SET NULL, -- 关联的教练账号，教练本人可维护档案
+    name VARCHAR(255) NOT NULL,
+    bio TEXT, -- 个人简介
+    photo_path VARCHAR(512), -- 照片在对象存储中的路径
+    specialties JSONB NOT NULL DEFAULT '[]', -- 擅长的课程类型数组
+    certifications JSONB NOT NULL DEFAULT '[]', -- 资质证书数组
+    active BOOLEAN NOT NULL DEFAULT TRUE, -- 是否在职，离职的教练不能再排课
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 教室与教练排课的排他约束需要btree_gist扩展（在GiST索引中比较room_id、instructor_id）
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- 场馆表（门店）
+CREATE TABLE IF NOT EXISTS locations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE, -- 场馆名称，如静安店
+    address TEXT, -- 地址
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 教室表（场馆内的教室，课程通过room_id关联）
+CREATE TABLE IF NOT EXISTS rooms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    location_id UUID NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    capacity INTEGER NOT NULL CHECK (capacity > 0), -- 最多容纳人数，课程容量不能超过该值
+    equipment JSONB NOT NULL DEFAULT '[]', -- 设备清单数组
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (location_id, name)
+);
+
+-- 课程系列表（每周重复的固定课程，由定时任务滚动生成classes）
+CREATE TABLE IF NOT EXISTS class_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    instructor VARCHAR(255), -- 授课教练姓名，关联了教练档案时与档案一致
+    instructor_id UUID REFERENCES instructors(id) ON DELETE SET NULL, -- 授课教练档案
+    instructor_user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- 授课教练的用户ID
+    room_id UUID REFERENCES rooms(id) ON DELETE SET NULL, -- 上课的教室
+    weekdays JSONB NOT NULL, -- 每周上课的星期数组，0为周日，1-6为周一至周六
+    start_clock VARCHAR(5) NOT NULL, -- 上课时间（当地时间，HH:MM）
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    capacity INTEGER NOT NULL CHECK (capacity > 0),
+    start_date DATE NOT NULL, -- 首节课日期（含）
+    end_date DATE, -- 末节课日期（含），为空表示长期重复
+    exception_dates JSONB NOT NULL DEFAULT '[]', -- 不上课的日期数组（YYYY-MM-DD）
+    timezone VARCHAR(64) NOT NULL, -- 上课时间所在时区
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 已有数据库补充课程系列表新增的列
+ALTER TABLE class_series ADD COLUMN IF NOT EXISTS instructor_id UUID REFERENCES instructors(id) ON DELETE SET NULL;
+ALTER TABLE class_series ADD COLUMN IF NOT EXISTS room_id UUID REFERENCES rooms(id) ON DELETE SET NULL;
 
 -- 课程表（定课业务）
 CREATE TABLE IF NOT EXISTS classes (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     name VARCHAR(255) NOT NULL,
     description TEXT,
-    instructor VARCHAR(255),
+    instructor VARCHAR(255), -- 授课教练姓名，关联了教练档案时与档案一致
+    instructor_id UUID REFERENCES instructors(id) ON DELETE SET NULL, -- 授课教练档案
+    instructor_user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- 授课教练的用户ID
+    room_id UUID REFERENCES rooms(id) ON DELETE SET NULL, -- 上课的教室
     start_time TIMESTAMP WITH TIME ZONE NOT NULL,
     end_time TIMESTAMP WITH TIME ZONE NOT NULL,
     capacity INTEGER NOT NULL DEFAULT 20,
-    booked_count INTEGER DEFAULT 0,
+    booked_count INTEGER DEFAULT 0 CHECK (booked_count >= 0 AND booked_count <= capacity), -- 已确认的预订数，预订时以条件更新占用名额
     status VARCHAR(50) DEFAULT 'scheduled', -- 'scheduled', 'cancelled', 'completed'
+    series_id UUID REFERENCES class_series(id) ON DELETE SET NULL, -- 所属课程系列
+    series_date DATE, -- 在系列中的原定日期（单节调整时间后保持不变）
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- 同一教室未取消的课程时间不能重叠，并发排课时由数据库兜底
+    CONSTRAINT classes_room_no_overlap EXCLUDE USING gist (
+        room_id WITH =, tstzrange(start_time, end_time) WITH &&
+    ) WHERE (status = 'scheduled'),
+    -- 同一教练（档案或教练账号）未取消的课程时间不能重叠
+    CONSTRAINT classes_instructor_no_overlap EXCLUDE USING gist (
+        instructor_id WITH =, tstzrange(start_time, end_time) WITH &&
+    ) WHERE (status = 'scheduled'),
+    CONSTRAINT classes_instructor_user_no_overlap EXCLUDE USING gist (
+        instructor_user_id WITH =, tstzrange(start_time, end_time) WITH &&
+    ) WHERE (status = 'scheduled')
 );
 
+-- 已有数据库补充课程表新增的列与约束
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS instructor_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES class_series(id) ON DELETE SET NULL;
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS series_date DATE;
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS instructor_id UUID REFERENCES instructors(id) ON DELETE SET NULL;
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS room_id UUID REFERENCES rooms(id) ON DELETE SET NULL;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'classes_booked_count_check') THEN
+        ALTER TABLE classes ADD CONSTRAINT classes_booked_count_check CHECK (booked_count >= 0 AND booked_count <= capacity);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'classes_room_no_overlap') THEN
+        ALTER TABLE classes ADD CONSTRAINT classes_room_no_overlap EXCLUDE USING gist (
+            room_id WITH =, tstzrange(start_time, end_time) WITH &&
+        ) WHERE (status = 'scheduled');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'classes_instructor_no_overlap') THEN
+        ALTER TABLE classes ADD CONSTRAINT classes_instructor_no_overlap EXCLUDE USING gist (
+            instructor_id WITH =, tstzrange(start_time, end_time) WITH &&
+        ) WHERE (status = 'scheduled');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'classes_instructor_user_no_overlap') THEN
+        ALTER TABLE classes ADD CONSTRAINT classes_instructor_user_no_overlap EXCLUDE USING gist (
+            instructor_user_id WITH =, tstzrange(start_time, end_time) WITH &&
+        ) WHERE (status = 'scheduled');
+    END IF;
+END $$;
+
 -- 预订表
 CREATE TABLE IF NOT EXISTS bookings (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     class_id UUID NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
-    user_id VARCHAR(255) NOT NULL, -- 微信用户ID
+    user_id VARCHAR(255) NOT NULL, -- 用户ID（users.id）
     user_name VARCHAR(255),
-    status VARCHAR(50) DEFAULT 'confirmed', -- 'confirmed', 'cancelled', 'completed'
+    status VARCHAR(50) DEFAULT 'confirmed', -- 'confirmed', 'waitlisted', 'cancelled', 'completed', 'no_show'
+    check_in_token VARCHAR(64) UNIQUE, -- 签到码（二维码内容）
+    subscription_id UUID REFERENCES subscriptions(id), -- 扣除课时的会员卡
+    waitlist_position INTEGER, -- 候补顺位（从1开始），仅 waitlisted 状态有值
+    cancelled_by VARCHAR(255), -- 取消操作人的用户ID（本人或工作人员）
+    cancel_reason TEXT, -- 取消原因
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    late_cancel BOOLEAN NOT NULL DEFAULT FALSE, -- 会员在免费取消截止时间之后取消
+    credit_forfeited BOOLEAN NOT NULL DEFAULT FALSE, -- 因迟取消或爽约不退还课时
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 已有数据库补充预订表新增的列
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS cancelled_by VARCHAR(255);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS waitlist_position INTEGER;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS late_cancel BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS credit_forfeited BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS check_in_token VARCHAR(64) UNIQUE;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS subscription_id UUID REFERENCES subscriptions(id);
+
+-- 签到记录表
+CREATE TABLE IF NOT EXISTS attendances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_id UUID NOT NULL UNIQUE REFERENCES bookings(id) ON DELETE CASCADE,
+    class_id UUID NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('qr', 'self', 'staff')), -- 扫码、自助、工作人员手动
+    checked_in_by VARCHAR(255) NOT NULL, -- 操作人的用户ID
+    checked_in_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 -- 评价表
 CREATE TABLE IF NOT EXISTS reviews (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     class_id UUID NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
-    user_id VARCHAR(255) NOT NULL, -- 微信用户ID
+    user_id VARCHAR(255) NOT NULL, -- 用户ID（users.id）
     user_name VARCHAR(255),
     rating INTEGER NOT NULL CHECK (rating >= 1 AND rating <= 5), -- 1-5星评分
     content TEXT,
@@ -69,11 +342,44 @@ CREATE TABLE IF NOT EXISTS reviews (
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 通知偏好表（未设置的用户默认接收全部通知）
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id VARCHAR(255) PRIMARY KEY, -- 用户ID（users.id）
+    phone VARCHAR(20) NOT NULL DEFAULT '', -- 接收短信的手机号
+    email VARCHAR(255) NOT NULL DEFAULT '', -- 接收邮件的邮箱
+    reminders BOOLEAN NOT NULL DEFAULT TRUE, -- 上课提醒
+    booking_updates BOOLEAN NOT NULL DEFAULT TRUE, -- 预订成功、候补转正
+    class_changes BOOLEAN NOT NULL DEFAULT TRUE, -- 课程取消、更换教练
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 上课提醒发送记录，按预订与开课时间去重；课程调整时间后会再次提醒
+CREATE TABLE IF NOT EXISTS booking_reminders (
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    class_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (booking_id, class_start)
+);
+
 -- 创建索引
 CREATE INDEX IF NOT EXISTS idx_bookings_class_id ON bookings(class_id);
 CREATE INDEX IF NOT EXISTS idx_bookings_user_id ON bookings(user_id);
 CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings(status);
+CREATE INDEX IF NOT EXISTS idx_bookings_waitlist ON bookings(class_id, waitlist_position) WHERE status = 'waitlisted';
+-- 同一用户在同一课程只能有一个已确认或候补中的预订，兜底并发的重复预订
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookings_active_user ON bookings(class_id, user_id) WHERE status IN ('confirmed', 'waitlisted');
 CREATE INDEX IF NOT EXISTS idx_classes_start_time ON classes(start_time);
+CREATE INDEX IF NOT EXISTS idx_classes_status_end_time ON classes(status, end_time);
+CREATE INDEX IF NOT EXISTS idx_attendances_class_id ON attendances(class_id);
+CREATE INDEX IF NOT EXISTS idx_classes_instructor_user_id ON classes(instructor_user_id);
+CREATE INDEX IF NOT EXISTS idx_classes_instructor_id ON classes(instructor_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_classes_room_id ON classes(room_id, start_time);
+-- 同一系列同一日期只生成一节课，生成任务可重复执行
+CREATE UNIQUE INDEX IF NOT EXISTS idx_classes_series_occurrence ON classes(series_id, series_date);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_credit_ledger_subscription_id ON credit_ledger(subscription_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_pending_expires_at ON orders(expires_at) WHERE status = 'pending';
 CREATE INDEX IF NOT EXISTS idx_reviews_class_id ON reviews(class_id);
 CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews(user_id);
 
@@ -93,6 +399,33 @@ CREATE TRIGGER update_knowledge_bases_updated_at BEFORE UPDATE ON knowledge_base
 CREATE TRIGGER update_knowledge_items_updated_at BEFORE UPDATE ON knowledge_items
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_conversations_updated_at BEFORE UPDATE ON conversations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_membership_plans_updated_at BEFORE UPDATE ON membership_plans
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_subscriptions_updated_at BEFORE UPDATE ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_orders_updated_at BEFORE UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_instructors_updated_at BEFORE UPDATE ON instructors
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_locations_updated_at BEFORE UPDATE ON locations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_rooms_updated_at BEFORE UPDATE ON rooms
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_class_series_updated_at BEFORE UPDATE ON class_series
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 CREATE TRIGGER update_classes_updated_at BEFORE UPDATE ON classes
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -102,3 +435,6 @@ CREATE TRIGGER update_bookings_updated_at BEFORE UPDATE ON bookings
 CREATE TRIGGER update_reviews_updated_at BEFORE UPDATE ON reviews
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_notification_preferences_updated_at BEFORE UPDATE ON notification_preferences
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/yoga/knowledge-base/internal/domain/ai"
+	"github.com/yoga/knowledge-base/internal/domain/user"
 	aiservice "github.com/yoga/knowledge-base/internal/service/ai"
 	"go.uber.org/zap"
 )
@@ -31,6 +36,12 @@ func NewAIHandler(service *aiservice.Service, logger *zap.Logger) *AIHandler {
 
 // Chat 处理聊天请求
 func (h *AIHandler) Chat(c *gin.Context) {
+	// 客户端声明接受SSE时走流式输出
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		h.ChatStream(c)
+		return
+	}
+
 	var req ai.ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -40,25 +51,10 @@ func (h *AIHandler) Chat(c *gin.Context) {
 	response, err := h.service.Chat(c.Request.Context(), req)
 	if err != nil {
 		h.logger.Error("AI聊天失败", zap.Error(err))
-		
-		// 根据错误类型返回不同的状态码和错误信息
-		errorMsg := err.Error()
-		statusCode := http.StatusInternalServerError
-		
-		// 检查是否是余额不足或API密钥问题
-		if contains(errorMsg, "余额不足") || contains(errorMsg, "Insufficient Balance") {
-			statusCode = http.StatusPaymentRequired // 402
-			errorMsg = "AI服务账户余额不足，请联系管理员充值"
-		} else if contains(errorMsg, "API密钥无效") || contains(errorMsg, "401") {
-			statusCode = http.StatusUnauthorized // 401
-			errorMsg = "AI服务配置错误，请联系管理员"
-		} else if contains(errorMsg, "请求频率过高") || contains(errorMsg, "429") {
-			statusCode = http.StatusTooManyRequests // 429
-			errorMsg = "请求过于频繁，请稍后重试"
-		}
-		
+
+		statusCode, errorMsg := chatErrorStatus(err)
 		c.JSON(statusCode, gin.H{
-			"error": errorMsg,
+			"error":  errorMsg,
 			"detail": err.Error(),
 		})
 		return
@@ -67,3 +63,151 @@ func (h *AIHandler) Chat(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ChatStream 以SSE流式返回聊天结果
+//
+// 事件类型：sources（知识库来源）、delta（文本增量）、tool_call、tool_result、done、error。
+// 客户端断开时请求context被取消，上游模型请求随之中断。
+func (h *AIHandler) ChatStream(c *gin.Context) {
+	var req ai.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 流式响应时长不可预知，取消服务器的整体写超时
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("取消写超时失败", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止Nginx缓冲
+	c.Status(http.StatusOK)
+
+	ctx := c.Request.Context()
+	emit := func(event ai.StreamEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.SSEvent(event.Type, event.Data)
+		c.Writer.Flush()
+		return nil
+	}
+
+	if err := h.service.ChatStream(ctx, req, emit); err != nil {
+		if ctx.Err() != nil {
+			h.logger.Info("客户端已断开，停止流式输出")
+			return
+		}
+
+		h.logger.Error("AI流式聊天失败", zap.Error(err))
+		_, errorMsg := chatErrorStatus(err)
+		c.SSEvent(ai.StreamEventError, gin.H{
+			"error":  errorMsg,
+			"detail": err.Error(),
+		})
+		c.Writer.Flush()
+	}
+}
+
+// chatErrorStatus 根据错误类型返回不同的状态码和错误信息
+func chatErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, aiservice.ErrConversationNotFound):
+		return http.StatusNotFound, aiservice.ErrConversationNotFound.Error()
+	}
+
+	errorMsg := err.Error()
+
+	// 检查是否是余额不足或API密钥问题
+	if contains(errorMsg, "余额不足") || contains(errorMsg, "Insufficient Balance") {
+		return http.StatusPaymentRequired, "AI服务账户余额不足，请联系管理员充值" // 402
+	} else if contains(errorMsg, "API密钥无效") || contains(errorMsg, "401") {
+		return http.StatusUnauthorized, "AI服务配置错误，请联系管理员" // 401
+	} else if contains(errorMsg, "请求频率过高") || contains(errorMsg, "429") {
+		return http.StatusTooManyRequests, "请求过于频繁，请稍后重试" // 429
+	}
+
+	return http.StatusInternalServerError, errorMsg
+}
+
+// CreateConversation 为当前用户创建会话
+func (h *AIHandler) CreateConversation(c *gin.Context) {
+	var req struct {
+		Title string `json:"title"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.service.CreateConversation(c.Request.Context(), req.Title)
+	if err != nil {
+		h.logger.Error("创建会话失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conv)
+}
+
+// ListConversations 列出当前用户的会话
+func (h *AIHandler) ListConversations(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	convs, err := h.service.ListConversations(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("查询会话列表失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询会话列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": convs, "limit": limit, "offset": offset})
+}
+
+// GetConversation 获取会话及其消息
+func (h *AIHandler) GetConversation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	conv, messages, err := h.service.GetConversation(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversation": conv, "messages": messages})
+}
+
+// DeleteConversation 删除会话
+func (h *AIHandler) DeleteConversation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	if err := h.service.DeleteConversation(c.Request.Context(), id); err != nil {
+		if errors.Is(err, aiservice.ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+			return
+		}
+		h.logger.Error("删除会话失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除会话失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
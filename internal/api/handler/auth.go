@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/auth"
+	"go.uber.org/zap"
+)
+
+// AuthHandler 认证处理器
+type AuthHandler struct {
+	service *auth.Service
+	logger  *zap.Logger
+}
+
+// NewAuthHandler 创建认证处理器
+func NewAuthHandler(service *auth.Service, logger *zap.Logger) *AuthHandler {
+	return &AuthHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// WeChatLogin 小程序登录：用wx.login获取的code换取会话令牌
+func (h *AuthHandler) WeChatLogin(c *gin.Context) {
+	var req struct {
+		Code      string `json:"code" binding:"required"`
+		Nickname  string `json:"nickname"`
+		AvatarURL string `json:"avatar_url"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.LoginWithWeChat(c.Request.Context(), req.Code, req.Nickname, req.AvatarURL)
+	if err != nil {
+		if errors.Is(err, auth.ErrLoginFailed) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "微信登录失败，请重试"})
+			return
+		}
+		h.logger.Error("微信登录失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Me 获取当前登录用户
+func (h *AuthHandler) Me(c *gin.Context) {
+	u, err := user.Require(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// UpdateProfile 更新当前用户的昵称与头像
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	var req struct {
+		Nickname  string `json:"nickname"`
+		AvatarURL string `json:"avatar_url"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.service.UpdateProfile(c.Request.Context(), req.Nickname, req.AvatarURL)
+	if err != nil {
+		h.logger.Error("更新用户资料失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户资料失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// SetUserRole 设置用户角色（管理员）
+func (h *AuthHandler) SetUserRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req struct {
+		Role user.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.service.SetUserRole(c.Request.Context(), userID, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrInvalidRole):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": auth.ErrUserNotFound.Error()})
+		default:
+			h.logger.Error("设置用户角色失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "设置用户角色失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
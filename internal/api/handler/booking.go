@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	bookingdomain "github.com/yoga/knowledge-base/internal/domain/booking"
+	instructordomain "github.com/yoga/knowledge-base/internal/domain/instructor"
+	locationdomain "github.com/yoga/knowledge-base/internal/domain/location"
+	"github.com/yoga/knowledge-base/internal/domain/user"
 	"github.com/yoga/knowledge-base/internal/service/booking"
 	"go.uber.org/zap"
 )
@@ -25,7 +30,7 @@ func NewBookingHandler(service *booking.Service, logger *zap.Logger) *BookingHan
 	}
 }
 
-// ListClasses 列出课程
+// ListClasses 列出课程，可用 ?location_id= 只查询某个场馆的课程
 func (h *BookingHandler) ListClasses(c *gin.Context) {
 	// 获取查询参数
 	startDate := c.Query("start_date")
@@ -33,41 +38,51 @@ func (h *BookingHandler) ListClasses(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
+	var locationID *uuid.UUID
+	if raw := c.Query("location_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的场馆ID"})
+			return
+		}
+		locationID = &id
+	}
+
 	var startTime, endTime *time.Time
 
+	// 日期按场馆时区解释，结束日期包含当天，范围截至次日零点
+	loc := h.service.Location()
+
 	// 如果没有指定日期，默认查询本周
 	if startDate == "" && endDate == "" {
-		now := time.Now()
+		now := time.Now().In(loc)
 		// 本周一
 		weekday := int(now.Weekday())
 		if weekday == 0 {
 			weekday = 7
 		}
-		monday := now.AddDate(0, 0, -weekday+1)
-		monday = time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, monday.Location())
+		monday := time.Date(now.Year(), now.Month(), now.Day()-weekday+1, 0, 0, 0, 0, loc)
 		startTime = &monday
-		// 本周日
-		sunday := monday.AddDate(0, 0, 6)
-		sunday = time.Date(sunday.Year(), sunday.Month(), sunday.Day(), 23, 59, 59, 0, sunday.Location())
-		endTime = &sunday
+		// 截至下周一零点，包含本周日全天
+		nextMonday := monday.AddDate(0, 0, 7)
+		endTime = &nextMonday
 	} else {
 		if startDate != "" {
-			t, err := time.Parse("2006-01-02", startDate)
+			t, err := time.ParseInLocation(bookingdomain.DateLayout, startDate, loc)
 			if err == nil {
-				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 				startTime = &t
 			}
 		}
 		if endDate != "" {
-			t, err := time.Parse("2006-01-02", endDate)
+			t, err := time.ParseInLocation(bookingdomain.DateLayout, endDate, loc)
 			if err == nil {
-				t = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+				t = t.AddDate(0, 0, 1)
 				endTime = &t
 			}
 		}
 	}
 
-	classes, err := h.service.ListClasses(c.Request.Context(), startTime, endTime, limit, offset)
+	classes, err := h.service.ListClasses(c.Request.Context(), startTime, endTime, locationID, limit, offset)
 	if err != nil {
 		h.logger.Error("查询课程列表失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询课程列表失败"})
@@ -75,8 +90,8 @@ func (h *BookingHandler) ListClasses(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items": classes,
-		"limit": limit,
+		"items":  classes,
+		"limit":  limit,
 		"offset": offset,
 	})
 }
@@ -99,35 +114,320 @@ func (h *BookingHandler) GetClass(c *gin.Context) {
 	c.JSON(http.StatusOK, class)
 }
 
-// BookClass 预订课程
-func (h *BookingHandler) BookClass(c *gin.Context) {
+// classRequest 创建或编辑课程的请求体
+type classRequest struct {
+	Name             string     `json:"name" binding:"required"`
+	Description      string     `json:"description"`
+	Instructor       string     `json:"instructor"`
+	InstructorID     *uuid.UUID `json:"instructor_id"`      // 教练档案，指定时按档案填写教练姓名；教练只能指定本人的档案
+	InstructorUserID *uuid.UUID `json:"instructor_user_id"` // 仅管理员可指定，教练操作时为本人
+	RoomID           *uuid.UUID `json:"room_id"`            // 上课的教室，课程容量不能超过教室容量
+	StartTime        time.Time  `json:"start_time" binding:"required"`
+	EndTime          time.Time  `json:"end_time" binding:"required"`
+	Capacity         int        `json:"capacity" binding:"required,min=1"`
+}
+
+func (r *classRequest) input() booking.ClassInput {
+	return booking.ClassInput{
+		Name:             r.Name,
+		Description:      r.Description,
+		Instructor:       r.Instructor,
+		InstructorID:     r.InstructorID,
+		InstructorUserID: r.InstructorUserID,
+		RoomID:           r.RoomID,
+		StartTime:        r.StartTime,
+		EndTime:          r.EndTime,
+		Capacity:         r.Capacity,
+	}
+}
+
+// CreateClass 创建课程（教练、管理员），同一教练的课程时间不能重叠
+func (h *BookingHandler) CreateClass(c *gin.Context) {
+	var req classRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	class, err := h.service.CreateClass(c.Request.Context(), req.input())
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "创建课程失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("创建课程失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusCreated, class)
+}
+
+// UpdateClass 编辑课程（管理员，或该课程的授课教练）
+func (h *BookingHandler) UpdateClass(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	var req classRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	class, err := h.service.UpdateClass(c.Request.Context(), id, req.input())
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "更新课程失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("更新课程失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, class)
+}
+
+// RescheduleClass 调整课程时间
+func (h *BookingHandler) RescheduleClass(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
 	var req struct {
-		UserID   string `json:"user_id" binding:"required"`
-		UserName string `json:"user_name"`
+		StartTime time.Time `json:"start_time" binding:"required"`
+		EndTime   time.Time `json:"end_time" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
+	class, err := h.service.RescheduleClass(c.Request.Context(), id, req.StartTime, req.EndTime)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "调整课程时间失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("调整课程时间失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, class)
+}
+
+// SubstituteInstructor 安排代课教练（管理员），并通知已预订的会员
+func (h *BookingHandler) SubstituteInstructor(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	var req struct {
+		InstructorID uuid.UUID `json:"instructor_id" binding:"required"`
+		Reason       string    `json:"reason" binding:"max=500"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	class, err := h.service.SubstituteInstructor(c.Request.Context(), id, req.InstructorID, req.Reason)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "更换教练失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("更换教练失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, class)
+}
+
+// CancelClass 取消课程，同时取消其下所有已确认的预订；请求体可选携带取消原因 {"reason": "..."}
+func (h *BookingHandler) CancelClass(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	cancelled, err := h.service.CancelClass(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "取消课程失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("取消课程失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "取消课程成功",
+		"cancelled_bookings": cancelled,
+	})
+}
+
+// CompleteClass 将课程标记为已完成
+func (h *BookingHandler) CompleteClass(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	if err := h.service.CompleteClass(c.Request.Context(), id); err != nil {
+		status, msg := bookingErrorStatus(err, "更新课程状态失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("更新课程状态失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "课程已完成"})
+}
+
+// bookingErrorStatus 将定课服务的错误映射为HTTP状态码与错误信息，未识别的错误返回500及fallback
+func bookingErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, user.ErrForbidden):
+		return http.StatusForbidden, user.ErrForbidden.Error()
+	case errors.Is(err, booking.ErrClassNotFound):
+		return http.StatusNotFound, booking.ErrClassNotFound.Error()
+	case errors.Is(err, booking.ErrSeriesNotFound):
+		return http.StatusNotFound, booking.ErrSeriesNotFound.Error()
+	case errors.Is(err, instructordomain.ErrInstructorNotFound):
+		return http.StatusNotFound, instructordomain.ErrInstructorNotFound.Error()
+	case errors.Is(err, locationdomain.ErrRoomNotFound):
+		return http.StatusNotFound, locationdomain.ErrRoomNotFound.Error()
+	case errors.Is(err, bookingdomain.ErrInvalidCheckInToken):
+		return http.StatusNotFound, bookingdomain.ErrInvalidCheckInToken.Error()
+	case errors.Is(err, bookingdomain.ErrBookingNotFound):
+		return http.StatusNotFound, bookingdomain.ErrBookingNotFound.Error()
+	case errors.Is(err, bookingdomain.ErrBookingNotCancellable):
+		return http.StatusConflict, bookingdomain.ErrBookingNotCancellable.Error()
+	case errors.Is(err, bookingdomain.ErrBookingBanned):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, bookingdomain.ErrNoCredits):
+		return http.StatusPaymentRequired, bookingdomain.ErrNoCredits.Error()
+	case errors.Is(err, bookingdomain.ErrInvalidClassTime),
+		errors.Is(err, bookingdomain.ErrInvalidCapacity),
+		errors.Is(err, bookingdomain.ErrInvalidSeries),
+		errors.Is(err, bookingdomain.ErrRoomCapacityExceeded):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, bookingdomain.ErrCapacityBelowBooked),
+		errors.Is(err, bookingdomain.ErrInstructorConflict),
+		errors.Is(err, bookingdomain.ErrRoomConflict),
+		errors.Is(err, instructordomain.ErrInstructorInactive),
+		errors.Is(err, bookingdomain.ErrClassNotEditable),
+		errors.Is(err, bookingdomain.ErrClassNotStarted),
+		errors.Is(err, bookingdomain.ErrClassNotBookable),
+		errors.Is(err, bookingdomain.ErrClassFull),
+		errors.Is(err, bookingdomain.ErrClassAvailable),
+		errors.Is(err, bookingdomain.ErrAlreadyBooked),
+		errors.Is(err, bookingdomain.ErrClassStarted),
+		errors.Is(err, bookingdomain.ErrCancelDeadlinePassed),
+		errors.Is(err, bookingdomain.ErrBookingNotConfirmed),
+		errors.Is(err, bookingdomain.ErrBookingCheckedIn),
+		errors.Is(err, bookingdomain.ErrCheckInNotOpen),
+		errors.Is(err, bookingdomain.ErrAlreadyCheckedIn),
+		errors.Is(err, bookingdomain.ErrBookingNotCheckable):
+		return http.StatusConflict, err.Error()
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// bookingErrorResponse 错误响应体，预订业务错误附带错误码（code）便于客户端区分处理
+func bookingErrorResponse(err error, msg string) gin.H {
+	body := gin.H{"error": msg}
+	if code := bookingdomain.ErrorCode(err); code != "" {
+		body["code"] = code
+	}
+	return body
+}
+
+// BookClass 以当前登录用户身份预订课程
+func (h *BookingHandler) BookClass(c *gin.Context) {
+	classID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	booking, err := h.service.BookClass(c.Request.Context(), classID)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "预订课程失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("预订课程失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusCreated, booking)
+}
+
+// JoinWaitlist 以当前登录用户身份加入已满课程的候补名单
+func (h *BookingHandler) JoinWaitlist(c *gin.Context) {
 	classID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
 		return
 	}
 
-	booking, err := h.service.BookClass(c.Request.Context(), classID, req.UserID, req.UserName)
+	booking, err := h.service.JoinWaitlist(c.Request.Context(), classID)
 	if err != nil {
-		h.logger.Error("预订课程失败", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "预订课程失败"})
+		status, msg := bookingErrorStatus(err, "加入候补名单失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("加入候补名单失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
 		return
 	}
 
 	c.JSON(http.StatusCreated, booking)
 }
 
-// CancelBooking 取消预订
+// ListWaitlist 查看课程的候补名单（管理员，或该课程的授课教练）
+func (h *BookingHandler) ListWaitlist(c *gin.Context) {
+	classID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	bookings, err := h.service.ListWaitlist(c.Request.Context(), classID)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "查询候补名单失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("查询候补名单失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": bookings})
+}
+
+// CancelBooking 取消预订，请求体可选携带取消原因 {"reason": "..."}
 func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	bookingID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -135,27 +435,56 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CancelBooking(c.Request.Context(), bookingID); err != nil {
-		h.logger.Error("取消预订失败", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消预订失败"})
+	var req struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	booking, err := h.service.CancelBooking(c.Request.Context(), bookingID, req.Reason)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "取消预订失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("取消预订失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "取消预订成功"})
+	c.JSON(http.StatusOK, gin.H{"message": "取消预订成功", "booking": booking})
 }
 
-// ListUserBookings 列出用户预订
-func (h *BookingHandler) ListUserBookings(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id参数必需"})
+// MarkNoShow 将已确认的预订标记为爽约（管理员，或该课程的授课教练）
+func (h *BookingHandler) MarkNoShow(c *gin.Context) {
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的预订ID"})
 		return
 	}
 
+	booking, err := h.service.MarkNoShow(c.Request.Context(), bookingID)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "标记爽约失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("标记爽约失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// ListUserBookings 列出当前登录用户的预订
+func (h *BookingHandler) ListUserBookings(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	bookings, err := h.service.ListUserBookings(c.Request.Context(), userID, limit, offset)
+	bookings, err := h.service.ListUserBookings(c.Request.Context(), limit, offset)
 	if err != nil {
 		h.logger.Error("查询用户预订失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户预订失败"})
@@ -163,20 +492,18 @@ func (h *BookingHandler) ListUserBookings(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items": bookings,
-		"limit": limit,
+		"items":  bookings,
+		"limit":  limit,
 		"offset": offset,
 	})
 }
 
-// CreateReview 创建评价
+// CreateReview 以当前登录用户身份创建评价
 func (h *BookingHandler) CreateReview(c *gin.Context) {
 	var req struct {
-		UserID   string   `json:"user_id" binding:"required"`
-		UserName string   `json:"user_name"`
-		Rating   int      `json:"rating" binding:"required,min=1,max=5"`
-		Content  string   `json:"content"`
-		Images   []string `json:"images"`
+		Rating  int      `json:"rating" binding:"required,min=1,max=5"`
+		Content string   `json:"content"`
+		Images  []string `json:"images"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -190,7 +517,7 @@ func (h *BookingHandler) CreateReview(c *gin.Context) {
 		return
 	}
 
-	review, err := h.service.CreateReview(c.Request.Context(), classID, req.UserID, req.UserName, req.Rating, req.Content, req.Images)
+	review, err := h.service.CreateReview(c.Request.Context(), classID, req.Rating, req.Content, req.Images)
 	if err != nil {
 		h.logger.Error("创建评价失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建评价失败"})
@@ -219,9 +546,73 @@ func (h *BookingHandler) ListClassReviews(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items": reviews,
-		"limit": limit,
+		"items":  reviews,
+		"limit":  limit,
 		"offset": offset,
 	})
 }
 
+// CheckInByToken 工作人员扫描会员出示的签到码签到
+func (h *BookingHandler) CheckInByToken(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attendance, err := h.service.CheckInByToken(c.Request.Context(), req.Token)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "签到失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("签到失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusCreated, attendance)
+}
+
+// CheckIn 按预订签到（会员自助签到，或工作人员手动签到）
+func (h *BookingHandler) CheckIn(c *gin.Context) {
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的预订ID"})
+		return
+	}
+
+	attendance, err := h.service.CheckIn(c.Request.Context(), bookingID)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "签到失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("签到失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusCreated, attendance)
+}
+
+// ClassAttendance 查看课程点名表（管理员，或该课程的授课教练）
+func (h *BookingHandler) ClassAttendance(c *gin.Context) {
+	classID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return
+	}
+
+	roster, err := h.service.ClassAttendance(c.Request.Context(), classID)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "查询点名表失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("查询点名表失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": roster})
+}
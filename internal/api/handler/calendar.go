@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	bookingdomain "github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/auth"
+	"github.com/yoga/knowledge-base/internal/service/booking"
+	"github.com/yoga/knowledge-base/pkg/ical"
+	"go.uber.org/zap"
+)
+
+// 课程表订阅源默认的时间范围：过去7天至未来60天
+const (
+	classFeedPast   = 7 * 24 * time.Hour
+	classFeedFuture = 60 * 24 * time.Hour
+)
+
+// CalendarHandler 日历订阅处理器
+type CalendarHandler struct {
+	authService    *auth.Service
+	bookingService *booking.Service
+	logger         *zap.Logger
+}
+
+// NewCalendarHandler 创建日历订阅处理器
+func NewCalendarHandler(authService *auth.Service, bookingService *booking.Service, logger *zap.Logger) *CalendarHandler {
+	return &CalendarHandler{
+		authService:    authService,
+		bookingService: bookingService,
+		logger:         logger,
+	}
+}
+
+// ClassFeed 场馆课程表的日历订阅源（公开），可选 start_date、end_date（YYYY-MM-DD）指定时间范围
+func (h *CalendarHandler) ClassFeed(c *gin.Context) {
+	// 日期按场馆时区解释，结束日期包含当天，范围截至次日零点
+	loc := h.bookingService.Location()
+	now := time.Now()
+	start, end := now.Add(-classFeedPast), now.Add(classFeedFuture)
+	if v := c.Query("start_date"); v != "" {
+		t, err := time.ParseInLocation(bookingdomain.DateLayout, v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期格式，应为 YYYY-MM-DD"})
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end_date"); v != "" {
+		t, err := time.ParseInLocation(bookingdomain.DateLayout, v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式，应为 YYYY-MM-DD"})
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+
+	cal, err := h.bookingService.ClassCalendar(c.Request.Context(), start, end)
+	if err != nil {
+		h.logger.Error("生成课程表日历失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成课程表日历失败"})
+		return
+	}
+
+	h.writeCalendar(c, cal, "classes.ics")
+}
+
+// UserBookingFeed 当前用户预订的日历订阅源。日历客户端无法携带会话令牌，
+// 以私有订阅地址中的 ?token= 识别用户；已登录的请求也可直接访问。
+func (h *CalendarHandler) UserBookingFeed(c *gin.Context) {
+	ctx := c.Request.Context()
+	if token := c.Query("token"); token != "" {
+		u, err := h.authService.AuthenticateCalendarToken(ctx, token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": auth.ErrInvalidCalendarToken.Error()})
+			return
+		}
+		ctx = user.WithUser(ctx, u)
+	}
+
+	cal, err := h.bookingService.UserCalendar(ctx)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "生成预订日历失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("生成预订日历失败", zap.Error(err))
+		}
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	h.writeCalendar(c, cal, "bookings.ics")
+}
+
+// GetFeedURL 获取当前用户的私有日历订阅地址，首次访问时生成
+func (h *CalendarHandler) GetFeedURL(c *gin.Context) {
+	h.feedURL(c, false)
+}
+
+// ResetFeedURL 重新生成私有日历订阅地址，原地址随即失效（地址泄露时使用）
+func (h *CalendarHandler) ResetFeedURL(c *gin.Context) {
+	h.feedURL(c, true)
+}
+
+func (h *CalendarHandler) feedURL(c *gin.Context, reset bool) {
+	token, err := h.authService.CalendarToken(c.Request.Context(), reset)
+	if err != nil {
+		if errors.Is(err, user.ErrUnauthenticated) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("获取日历订阅地址失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取日历订阅地址失败"})
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	feed := url.URL{
+		Scheme:   scheme,
+		Host:     c.Request.Host,
+		Path:     "/api/v1/users/me/bookings.ics",
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	webcal := feed
+	webcal.Scheme = "webcal"
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        feed.String(),
+		"webcal_url": webcal.String(),
+	})
+}
+
+// writeCalendar 输出iCalendar文本，订阅源内容随预订变化，不允许中间缓存
+func (h *CalendarHandler) writeCalendar(c *gin.Context, cal *ical.Calendar, filename string) {
+	c.Header("Cache-Control", "private, max-age=0, must-revalidate")
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Data(http.StatusOK, ical.ContentType, cal.Encode())
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	bookingdomain "github.com/yoga/knowledge-base/internal/domain/booking"
+	instructordomain "github.com/yoga/knowledge-base/internal/domain/instructor"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/instructor"
+	"go.uber.org/zap"
+)
+
+// instructorScheduleDays 教练课程表默认查询今天起的天数
+const instructorScheduleDays = 14
+
+// InstructorHandler 教练档案处理器
+type InstructorHandler struct {
+	service *instructor.Service
+	logger  *zap.Logger
+}
+
+// NewInstructorHandler 创建教练档案处理器
+func NewInstructorHandler(service *instructor.Service, logger *zap.Logger) *InstructorHandler {
+	return &InstructorHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// instructorRequest 创建或修改教练档案的请求体
+type instructorRequest struct {
+	UserID         *uuid.UUID `json:"user_id"` // 关联的教练账号，仅管理员可修改
+	Name           string     `json:"name" binding:"required"`
+	Bio            string     `json:"bio"`
+	Specialties    []string   `json:"specialties"`
+	Certifications []string   `json:"certifications"`
+	Active         *bool      `json:"active"` // 默认在职，仅管理员可修改
+}
+
+func (r *instructorRequest) input() instructor.Input {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return instructor.Input{
+		UserID:         r.UserID,
+		Name:           r.Name,
+		Bio:            r.Bio,
+		Specialties:    r.Specialties,
+		Certifications: r.Certifications,
+		Active:         active,
+	}
+}
+
+// instructorErrorStatus 将教练档案服务的错误映射为HTTP状态码与错误信息，未识别的错误返回500及fallback
+func instructorErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, user.ErrForbidden):
+		return http.StatusForbidden, user.ErrForbidden.Error()
+	case errors.Is(err, instructordomain.ErrInstructorNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, instructordomain.ErrInvalidInstructor),
+		errors.Is(err, instructordomain.ErrInvalidPhoto):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, bookingdomain.ErrInstructorConflict):
+		return http.StatusConflict, err.Error()
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// respondError 输出教练档案服务错误，500时记录日志
+func (h *InstructorHandler) respondError(c *gin.Context, err error, fallback string) {
+	status, msg := instructorErrorStatus(err, fallback)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(fallback, zap.Error(err))
+	}
+	c.JSON(status, gin.H{"error": msg})
+}
+
+// ListInstructors 列出在职教练，管理员可用 ?all=true 包含已离职的教练
+func (h *InstructorHandler) ListInstructors(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	list, err := h.service.ListInstructors(c.Request.Context(), c.Query("all") == "true", limit, offset)
+	if err != nil {
+		h.respondError(c, err, "查询教练列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": list, "limit": limit, "offset": offset})
+}
+
+// GetInstructor 教练主页：简介、专长、证书、照片与平均评分
+func (h *InstructorHandler) GetInstructor(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教练ID"})
+		return
+	}
+
+	ins, err := h.service.GetInstructor(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "查询教练失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, ins)
+}
+
+// CreateInstructor 创建教练档案（管理员）
+func (h *InstructorHandler) CreateInstructor(c *gin.Context) {
+	var req instructorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ins, err := h.service.CreateInstructor(c.Request.Context(), req.input())
+	if err != nil {
+		h.respondError(c, err, "创建教练档案失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ins)
+}
+
+// UpdateInstructor 修改教练档案（管理员，或教练本人）
+func (h *InstructorHandler) UpdateInstructor(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教练ID"})
+		return
+	}
+
+	var req instructorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ins, err := h.service.UpdateInstructor(c.Request.Context(), id, req.input())
+	if err != nil {
+		h.respondError(c, err, "修改教练档案失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, ins)
+}
+
+// UploadPhoto 上传教练照片（multipart表单字段file），替换原照片
+func (h *InstructorHandler) UploadPhoto(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教练ID"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件上传失败"})
+		return
+	}
+	defer file.Close()
+
+	ins, err := h.service.UploadPhoto(c.Request.Context(), id, file, header.Size, header.Header.Get("Content-Type"), header.Filename)
+	if err != nil {
+		h.respondError(c, err, "上传教练照片失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, ins)
+}
+
+// GetSchedule 教练的课程表，可选 start_date、end_date（YYYY-MM-DD），默认为今天起两周
+func (h *InstructorHandler) GetSchedule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教练ID"})
+		return
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 0, instructorScheduleDays)
+	if v := c.Query("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期格式，应为 YYYY-MM-DD"})
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期格式，应为 YYYY-MM-DD"})
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	classes, err := h.service.Schedule(c.Request.Context(), id, start, end, limit, offset)
+	if err != nil {
+		h.respondError(c, err, "查询教练课程表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": classes, "limit": limit, "offset": offset})
+}
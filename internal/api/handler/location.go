@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	locationdomain "github.com/yoga/knowledge-base/internal/domain/location"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/location"
+	"go.uber.org/zap"
+)
+
+// LocationHandler 场馆与教室处理器
+type LocationHandler struct {
+	service *location.Service
+	logger  *zap.Logger
+}
+
+// NewLocationHandler 创建场馆与教室处理器
+func NewLocationHandler(service *location.Service, logger *zap.Logger) *LocationHandler {
+	return &LocationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// locationRequest 创建或修改场馆的请求体
+type locationRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Address string `json:"address"`
+}
+
+// roomRequest 创建或修改教室的请求体
+type roomRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Capacity  int      `json:"capacity" binding:"required,min=1"`
+	Equipment []string `json:"equipment"` // 设备清单，如瑜伽垫、瑜伽砖、空中吊床
+}
+
+// locationErrorStatus 将场馆服务的错误映射为HTTP状态码与错误信息，未识别的错误返回500及fallback
+func locationErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, user.ErrForbidden):
+		return http.StatusForbidden, user.ErrForbidden.Error()
+	case errors.Is(err, locationdomain.ErrLocationNotFound),
+		errors.Is(err, locationdomain.ErrRoomNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, locationdomain.ErrInvalidLocation),
+		errors.Is(err, locationdomain.ErrInvalidRoom):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, locationdomain.ErrLocationExists),
+		errors.Is(err, locationdomain.ErrRoomExists):
+		return http.StatusConflict, err.Error()
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// respondError 输出场馆服务错误，500时记录日志
+func (h *LocationHandler) respondError(c *gin.Context, err error, fallback string) {
+	status, msg := locationErrorStatus(err, fallback)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(fallback, zap.Error(err))
+	}
+	c.JSON(status, gin.H{"error": msg})
+}
+
+// ListLocations 列出全部场馆及其教室
+func (h *LocationHandler) ListLocations(c *gin.Context) {
+	list, err := h.service.ListLocations(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "查询场馆列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": list})
+}
+
+// GetLocation 获取场馆及其教室
+func (h *LocationHandler) GetLocation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的场馆ID"})
+		return
+	}
+
+	loc, err := h.service.GetLocation(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "查询场馆失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, loc)
+}
+
+// CreateLocation 创建场馆（管理员）
+func (h *LocationHandler) CreateLocation(c *gin.Context) {
+	var req locationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := h.service.CreateLocation(c.Request.Context(), location.LocationInput{
+		Name:    req.Name,
+		Address: req.Address,
+	})
+	if err != nil {
+		h.respondError(c, err, "创建场馆失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, loc)
+}
+
+// UpdateLocation 修改场馆（管理员）
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的场馆ID"})
+		return
+	}
+
+	var req locationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, err := h.service.UpdateLocation(c.Request.Context(), id, location.LocationInput{
+		Name:    req.Name,
+		Address: req.Address,
+	})
+	if err != nil {
+		h.respondError(c, err, "修改场馆失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, loc)
+}
+
+// CreateRoom 在场馆内创建教室（管理员）
+func (h *LocationHandler) CreateRoom(c *gin.Context) {
+	locationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的场馆ID"})
+		return
+	}
+
+	var req roomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	room, err := h.service.CreateRoom(c.Request.Context(), locationID, location.RoomInput{
+		Name:      req.Name,
+		Capacity:  req.Capacity,
+		Equipment: req.Equipment,
+	})
+	if err != nil {
+		h.respondError(c, err, "创建教室失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, room)
+}
+
+// UpdateRoom 修改教室（管理员）
+func (h *LocationHandler) UpdateRoom(c *gin.Context) {
+	locationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的场馆ID"})
+		return
+	}
+	roomID, err := uuid.Parse(c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教室ID"})
+		return
+	}
+
+	var req roomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	room, err := h.service.UpdateRoom(c.Request.Context(), locationID, roomID, location.RoomInput{
+		Name:      req.Name,
+		Capacity:  req.Capacity,
+		Equipment: req.Equipment,
+	})
+	if err != nil {
+		h.respondError(c, err, "修改教室失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, room)
+}
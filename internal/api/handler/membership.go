@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	membershipdomain "github.com/yoga/knowledge-base/internal/domain/membership"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/membership"
+	"go.uber.org/zap"
+)
+
+// MembershipHandler 会员卡处理器
+type MembershipHandler struct {
+	service *membership.Service
+	logger  *zap.Logger
+}
+
+// NewMembershipHandler 创建会员卡处理器
+func NewMembershipHandler(service *membership.Service, logger *zap.Logger) *MembershipHandler {
+	return &MembershipHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// planRequest 创建或修改会员卡方案的请求体
+type planRequest struct {
+	Name         string                    `json:"name" binding:"required"`
+	Description  string                    `json:"description"`
+	Kind         membershipdomain.PlanKind `json:"kind" binding:"required"`
+	Credits      *int                      `json:"credits"`
+	ValidityDays int                       `json:"validity_days" binding:"required,min=1"`
+	PriceCents   int                       `json:"price_cents" binding:"min=0"`
+	Active       *bool                     `json:"active"` // 默认在售
+}
+
+func (r *planRequest) input() membership.PlanInput {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return membership.PlanInput{
+		Name:         r.Name,
+		Description:  r.Description,
+		Kind:         r.Kind,
+		Credits:      r.Credits,
+		ValidityDays: r.ValidityDays,
+		PriceCents:   r.PriceCents,
+		Active:       active,
+	}
+}
+
+// membershipErrorStatus 将会员卡服务的错误映射为HTTP状态码与错误信息，未识别的错误返回500及fallback
+func membershipErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, user.ErrForbidden):
+		return http.StatusForbidden, user.ErrForbidden.Error()
+	case errors.Is(err, membershipdomain.ErrPlanNotFound),
+		errors.Is(err, membershipdomain.ErrSubscriptionNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, membershipdomain.ErrInvalidPlan),
+		errors.Is(err, membershipdomain.ErrInvalidAdjustment):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, membershipdomain.ErrPlanInactive),
+		errors.Is(err, membershipdomain.ErrNotFreezable),
+		errors.Is(err, membershipdomain.ErrNotFrozen):
+		return http.StatusConflict, err.Error()
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// respondError 输出会员卡服务错误，500时记录日志
+func (h *MembershipHandler) respondError(c *gin.Context, err error, fallback string) {
+	status, msg := membershipErrorStatus(err, fallback)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(fallback, zap.Error(err))
+	}
+	c.JSON(status, gin.H{"error": msg})
+}
+
+// CreatePlan 创建会员卡方案（管理员）
+func (h *MembershipHandler) CreatePlan(c *gin.Context) {
+	var req planRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.service.CreatePlan(c.Request.Context(), req.input())
+	if err != nil {
+		h.respondError(c, err, "创建会员卡方案失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// UpdatePlan 修改会员卡方案（管理员）
+func (h *MembershipHandler) UpdatePlan(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的方案ID"})
+		return
+	}
+
+	var req planRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.service.UpdatePlan(c.Request.Context(), id, req.input())
+	if err != nil {
+		h.respondError(c, err, "修改会员卡方案失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// ListPlans 列出在售的会员卡方案，管理员带 ?all=true 时包含已停售的方案
+func (h *MembershipHandler) ListPlans(c *gin.Context) {
+	plans, err := h.service.ListPlans(c.Request.Context(), c.Query("all") == "true")
+	if err != nil {
+		h.respondError(c, err, "查询会员卡方案失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": plans})
+}
+
+// GrantSubscription 管理员为用户开卡
+func (h *MembershipHandler) GrantSubscription(c *gin.Context) {
+	var req struct {
+		UserID   uuid.UUID  `json:"user_id" binding:"required"`
+		PlanID   uuid.UUID  `json:"plan_id" binding:"required"`
+		StartsAt *time.Time `json:"starts_at"` // 默认立即生效
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.GrantSubscription(c.Request.Context(), req.UserID, req.PlanID, req.StartsAt)
+	if err != nil {
+		h.respondError(c, err, "开卡失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// ListMySubscriptions 列出当前登录用户的会员卡
+func (h *MembershipHandler) ListMySubscriptions(c *gin.Context) {
+	subs, err := h.service.ListMySubscriptions(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "查询会员卡失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": subs})
+}
+
+// ListUserSubscriptions 列出指定用户的会员卡（管理员）
+func (h *MembershipHandler) ListUserSubscriptions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	subs, err := h.service.ListUserSubscriptions(c.Request.Context(), userID)
+	if err != nil {
+		h.respondError(c, err, "查询会员卡失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": subs})
+}
+
+// FreezeSubscription 冻结会员卡（持卡人或管理员）
+func (h *MembershipHandler) FreezeSubscription(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会员卡ID"})
+		return
+	}
+
+	sub, err := h.service.FreezeSubscription(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "冻结会员卡失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// UnfreezeSubscription 解冻会员卡（持卡人或管理员）
+func (h *MembershipHandler) UnfreezeSubscription(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会员卡ID"})
+		return
+	}
+
+	sub, err := h.service.UnfreezeSubscription(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "解冻会员卡失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// AdjustCredits 调整次卡剩余节数（管理员）
+func (h *MembershipHandler) AdjustCredits(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会员卡ID"})
+		return
+	}
+
+	var req struct {
+		Delta int    `json:"delta" binding:"required"`
+		Note  string `json:"note" binding:"max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.service.AdjustCredits(c.Request.Context(), id, req.Delta, req.Note)
+	if err != nil {
+		h.respondError(c, err, "调整课时失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// ListLedger 查看会员卡的课时流水（持卡人或管理员）
+func (h *MembershipHandler) ListLedger(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会员卡ID"})
+		return
+	}
+
+	entries, err := h.service.ListLedger(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "查询课时流水失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": entries})
+}
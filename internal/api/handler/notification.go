@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	notificationdomain "github.com/yoga/knowledge-base/internal/domain/notification"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/notification"
+	"go.uber.org/zap"
+)
+
+// NotificationHandler 通知偏好处理器
+type NotificationHandler struct {
+	service *notification.Service
+	logger  *zap.Logger
+}
+
+// NewNotificationHandler 创建通知偏好处理器
+func NewNotificationHandler(service *notification.Service, logger *zap.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// notificationErrorStatus 将通知服务的错误映射为HTTP状态码与错误信息，未识别的错误返回500及fallback
+func notificationErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, notificationdomain.ErrInvalidContact):
+		return http.StatusBadRequest, err.Error()
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// respondError 输出通知服务错误，500时记录日志
+func (h *NotificationHandler) respondError(c *gin.Context, err error, fallback string) {
+	status, msg := notificationErrorStatus(err, fallback)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(fallback, zap.Error(err))
+	}
+	c.JSON(status, gin.H{"error": msg})
+}
+
+// GetPreference 获取当前用户的通知偏好，未设置时返回默认值（全部开启）
+func (h *NotificationHandler) GetPreference(c *gin.Context) {
+	p, err := h.service.GetMyPreference(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "查询通知偏好失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// UpdatePreference 修改当前用户的通知偏好，只修改请求体中出现的字段：
+// {"phone": "...", "email": "...", "reminders": false, "booking_updates": true, "class_changes": true}
+func (h *NotificationHandler) UpdatePreference(c *gin.Context) {
+	var req struct {
+		Phone          *string `json:"phone"`
+		Email          *string `json:"email"`
+		Reminders      *bool   `json:"reminders"`
+		BookingUpdates *bool   `json:"booking_updates"`
+		ClassChanges   *bool   `json:"class_changes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := h.service.UpdateMyPreference(c.Request.Context(), notification.PreferenceInput{
+		Phone:          req.Phone,
+		Email:          req.Email,
+		Reminders:      req.Reminders,
+		BookingUpdates: req.BookingUpdates,
+		ClassChanges:   req.ClassChanges,
+	})
+	if err != nil {
+		h.respondError(c, err, "修改通知偏好失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
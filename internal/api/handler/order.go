@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	membershipdomain "github.com/yoga/knowledge-base/internal/domain/membership"
+	orderdomain "github.com/yoga/knowledge-base/internal/domain/order"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/order"
+	"github.com/yoga/knowledge-base/pkg/payment"
+	"go.uber.org/zap"
+)
+
+// OrderHandler 订单与支付处理器
+type OrderHandler struct {
+	service *order.Service
+	logger  *zap.Logger
+}
+
+// NewOrderHandler 创建订单处理器
+func NewOrderHandler(service *order.Service, logger *zap.Logger) *OrderHandler {
+	return &OrderHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// orderErrorStatus 将订单服务的错误映射为HTTP状态码与错误信息，未识别的错误返回500及fallback
+func orderErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, user.ErrUnauthenticated):
+		return http.StatusUnauthorized, user.ErrUnauthenticated.Error()
+	case errors.Is(err, user.ErrForbidden):
+		return http.StatusForbidden, user.ErrForbidden.Error()
+	case errors.Is(err, orderdomain.ErrOrderNotFound),
+		errors.Is(err, orderdomain.ErrSimulationUnavailable),
+		errors.Is(err, membershipdomain.ErrPlanNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, orderdomain.ErrInvalidTransition),
+		errors.Is(err, orderdomain.ErrFreePlan),
+		errors.Is(err, membershipdomain.ErrPlanInactive):
+		return http.StatusConflict, err.Error()
+	}
+	return http.StatusInternalServerError, fallback
+}
+
+// respondError 输出订单服务错误，500时记录日志
+func (h *OrderHandler) respondError(c *gin.Context, err error, fallback string) {
+	status, msg := orderErrorStatus(err, fallback)
+	if status == http.StatusInternalServerError {
+		h.logger.Error(fallback, zap.Error(err))
+	}
+	c.JSON(status, gin.H{"error": msg})
+}
+
+// CreateOrder 购买会员卡方案，返回订单与小程序 wx.requestPayment 的参数
+func (h *OrderHandler) CreateOrder(c *gin.Context) {
+	var req struct {
+		PlanID uuid.UUID `json:"plan_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	o, params, err := h.service.CreateOrder(c.Request.Context(), req.PlanID)
+	if err != nil {
+		h.respondError(c, err, "下单失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"order":      o,
+		"pay_params": params,
+	})
+}
+
+// ListMyOrders 列出当前登录用户的订单
+func (h *OrderHandler) ListMyOrders(c *gin.Context) {
+	orders, err := h.service.ListMyOrders(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "查询订单失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": orders})
+}
+
+// GetOrder 获取订单（下单人或管理员）
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订单ID"})
+		return
+	}
+
+	o, err := h.service.GetOrder(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "查询订单失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, o)
+}
+
+// SyncOrder 向支付网关查单并更新订单，小程序支付完成后调用以确认结果
+func (h *OrderHandler) SyncOrder(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订单ID"})
+		return
+	}
+
+	o, err := h.service.SyncOrder(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "查询支付结果失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, o)
+}
+
+// RefundOrder 全额退款（管理员）
+func (h *OrderHandler) RefundOrder(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订单ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=80"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	o, err := h.service.RefundOrder(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		h.respondError(c, err, "退款失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, o)
+}
+
+// SimulatePayment 模拟付款（仅本地假网关）
+func (h *OrderHandler) SimulatePayment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的订单ID"})
+		return
+	}
+
+	o, err := h.service.SimulatePayment(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "模拟付款失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, o)
+}
+
+// PaymentNotify 支付网关的支付结果通知（无需登录，按网关签名校验）。
+// 处理成功返回200，失败时按微信支付约定返回FAIL，网关会稍后重试。
+func (h *OrderHandler) PaymentNotify(c *gin.Context) {
+	if err := h.service.HandleNotify(c.Request.Context(), c.Request); err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			h.logger.Warn("支付通知签名无效", zap.String("client_ip", c.ClientIP()))
+			c.JSON(http.StatusUnauthorized, gin.H{"code": "FAIL", "message": err.Error()})
+			return
+		}
+		h.logger.Error("处理支付通知失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "FAIL", "message": "处理失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": "SUCCESS"})
+}
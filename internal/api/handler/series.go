@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	bookingdomain "github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/service/booking"
+	"go.uber.org/zap"
+)
+
+// seriesRequest 创建或修改课程系列的请求体，日期格式为YYYY-MM-DD
+type seriesRequest struct {
+	Name             string     `json:"name" binding:"required"`
+	Description      string     `json:"description"`
+	Instructor       string     `json:"instructor"`
+	InstructorID     *uuid.UUID `json:"instructor_id"`      // 教练档案，指定时按档案填写教练姓名；教练只能指定本人的档案
+	InstructorUserID *uuid.UUID `json:"instructor_user_id"` // 仅管理员可指定，教练操作时为本人
+	RoomID           *uuid.UUID `json:"room_id"`            // 上课的教室，课程容量不能超过教室容量
+	Weekdays         []int      `json:"weekdays" binding:"required,min=1,dive,min=0,max=6"`
+	StartClock       string     `json:"start_clock" binding:"required"`
+	DurationMinutes  int        `json:"duration_minutes" binding:"required,min=1"`
+	Capacity         int        `json:"capacity" binding:"required,min=1"`
+	StartDate        string     `json:"start_date" binding:"required"`
+	EndDate          string     `json:"end_date"`
+	ExceptionDates   []string   `json:"exception_dates"`
+}
+
+func (r *seriesRequest) input() (booking.SeriesInput, error) {
+	in := booking.SeriesInput{
+		Name:             r.Name,
+		Description:      r.Description,
+		Instructor:       r.Instructor,
+		InstructorID:     r.InstructorID,
+		InstructorUserID: r.InstructorUserID,
+		RoomID:           r.RoomID,
+		Weekdays:         r.Weekdays,
+		StartClock:       r.StartClock,
+		DurationMinutes:  r.DurationMinutes,
+		Capacity:         r.Capacity,
+		ExceptionDates:   r.ExceptionDates,
+	}
+
+	startDate, err := time.Parse(bookingdomain.DateLayout, r.StartDate)
+	if err != nil {
+		return in, err
+	}
+	in.StartDate = startDate
+
+	if r.EndDate != "" {
+		endDate, err := time.Parse(bookingdomain.DateLayout, r.EndDate)
+		if err != nil {
+			return in, err
+		}
+		in.EndDate = &endDate
+	}
+	return in, nil
+}
+
+// CreateSeries 创建课程系列（教练、管理员），并立即生成时间窗口内的课程
+func (h *BookingHandler) CreateSeries(c *gin.Context) {
+	var req seriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	in, err := req.input()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式应为YYYY-MM-DD"})
+		return
+	}
+
+	series, generated, err := h.service.CreateSeries(c.Request.Context(), in)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "创建课程系列失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("创建课程系列失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"series":            series,
+		"rrule":             series.RRule(),
+		"generated_classes": generated,
+	})
+}
+
+// ListSeries 列出进行中的课程系列
+func (h *BookingHandler) ListSeries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	series, err := h.service.ListSeries(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("查询课程系列列表失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询课程系列列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  series,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetSeries 获取课程系列
+func (h *BookingHandler) GetSeries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程系列ID"})
+		return
+	}
+
+	series, err := h.service.GetSeries(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": booking.ErrSeriesNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series": series,
+		"rrule":  series.RRule(),
+	})
+}
+
+// UpdateSeries 修改课程系列。
+// 查询参数 effective_from=YYYY-MM-DD 表示“此节及以后”，从该日期起拆分为新系列；不传则修改整个系列。
+func (h *BookingHandler) UpdateSeries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程系列ID"})
+		return
+	}
+
+	var req seriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	in, err := req.input()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式应为YYYY-MM-DD"})
+		return
+	}
+
+	var effectiveFrom *time.Time
+	if v := c.Query("effective_from"); v != "" {
+		t, err := time.Parse(bookingdomain.DateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "effective_from格式应为YYYY-MM-DD"})
+			return
+		}
+		effectiveFrom = &t
+	}
+
+	revision, err := h.service.UpdateSeries(c.Request.Context(), id, in, effectiveFrom)
+	if err != nil {
+		status, msg := bookingErrorStatus(err, "修改课程系列失败")
+		if status == http.StatusInternalServerError {
+			h.logger.Error("修改课程系列失败", zap.Error(err))
+		}
+		c.JSON(status, bookingErrorResponse(err, msg))
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/service/auth"
+	"github.com/yoga/knowledge-base/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// AuthMiddleware 认证中间件：请求携带有效的 Authorization: Bearer <token> 时，
+// 将对应用户注入请求context（见user.FromContext）。无令牌或令牌无效时按匿名请求继续，
+// 需要登录的路由再叠加RequireAuth。
+func AuthMiddleware(authSvc *auth.Service, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.Next()
+			return
+		}
+
+		u, err := authSvc.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			logger.Debug("令牌校验失败", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Request = c.Request.WithContext(user.WithUser(c.Request.Context(), u))
+		c.Next()
+	}
+}
+
+// RequireAuth 要求请求已登录，否则返回401
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := user.FromContext(c.Request.Context()); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": user.ErrUnauthenticated.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole 要求已登录用户具有任一指定角色（管理员视为具有所有角色），
+// 未登录返回401，角色不符返回403
+func RequireRole(roles ...user.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := user.FromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": user.ErrUnauthenticated.Error()})
+			return
+		}
+		if !u.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": user.ErrForbidden.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// MCPAdminFromRole 已登录的场馆管理员通过/mcp或站内AI助手调用工具时可使用管理员工具
+func MCPAdminFromRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if u, ok := user.FromContext(c.Request.Context()); ok && u.IsAdmin() {
+			c.Request = c.Request.WithContext(mcp.WithAdmin(c.Request.Context(), true))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,126 @@
+// Package app 组装API服务器与MCP stdio服务器共用的仓储、客户端与服务
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yoga/knowledge-base/internal/config"
+	"github.com/yoga/knowledge-base/internal/mcp/tools"
+	"github.com/yoga/knowledge-base/internal/repository/postgres"
+	aiservice "github.com/yoga/knowledge-base/internal/service/ai"
+	"github.com/yoga/knowledge-base/internal/service/booking"
+	"github.com/yoga/knowledge-base/internal/service/knowledge"
+	"github.com/yoga/knowledge-base/internal/service/location"
+	"github.com/yoga/knowledge-base/internal/service/membership"
+	"github.com/yoga/knowledge-base/internal/service/notification"
+	"github.com/yoga/knowledge-base/pkg/chunker"
+	"github.com/yoga/knowledge-base/pkg/embedding"
+	"github.com/yoga/knowledge-base/pkg/mcp"
+	"github.com/yoga/knowledge-base/pkg/notify"
+	"github.com/yoga/knowledge-base/pkg/storage"
+	"github.com/yoga/knowledge-base/pkg/vector"
+	"github.com/yoga/knowledge-base/pkg/wechat"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Services 共用的服务
+type Services struct {
+	DB            *gorm.DB
+	Storage       *storage.MinIOStorage
+	WeChat        *wechat.Client
+	KnowledgeRepo *postgres.KnowledgeRepository
+	Knowledge     *knowledge.Service
+	Retriever     aiservice.Retriever
+	Notification  *notification.Service
+	Booking       *booking.Service
+	Location      *location.Service
+	Membership    *membership.Service
+	logger        *zap.Logger
+}
+
+// NewServices 按配置初始化共用的服务
+func NewServices(cfg *config.Config, logger *zap.Logger) (*Services, error) {
+	// 初始化存储
+	storageClient, err := storage.NewMinIOStorage(
+		cfg.MinIO.Endpoint,
+		cfg.MinIO.AccessKeyID,
+		cfg.MinIO.SecretAccessKey,
+		cfg.MinIO.UseSSL,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("初始化存储失败: %w", err)
+	}
+
+	// 初始化数据库连接（共享连接）
+	db, err := postgres.GetDB(cfg.Database.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("初始化数据库失败: %w", err)
+	}
+
+	// 初始化知识库服务
+	kbRepo, err := postgres.NewKnowledgeRepository(cfg.Database.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("初始化知识库仓储失败: %w", err)
+	}
+	embeddingClient := embedding.NewClient(cfg.Embedding.URL)
+	vectorClient := vector.NewClient(cfg.Vector.URL)
+	splitter := chunker.NewSplitter(cfg.Chunking.ChunkSize, cfg.Chunking.Overlap)
+	kbService := knowledge.NewService(kbRepo, storageClient, embeddingClient, vectorClient, splitter, cfg.MinIO.BucketName, logger)
+
+	// 初始化通知服务：预订事件通知与上课提醒
+	studioLocation, err := cfg.Booking.Location()
+	if err != nil {
+		return nil, fmt.Errorf("加载场馆时区失败: %w", err)
+	}
+	wechatAPI := wechat.NewClient(cfg.WeChat.AppID, cfg.WeChat.AppSecret)
+	notifyChannels, err := cfg.Notify.Channels(wechatAPI, logger)
+	if err != nil {
+		return nil, fmt.Errorf("初始化通知渠道失败: %w", err)
+	}
+	bookingRepo := postgres.NewBookingRepository(db)
+	notificationService, err := notification.NewService(postgres.NewNotificationRepository(db), postgres.NewUserRepository(db), bookingRepo,
+		notify.NewDispatcher(logger, notifyChannels...), notification.Config{
+			ReminderLead: cfg.Notify.ReminderLead,
+			Location:     studioLocation,
+		}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("初始化通知服务失败: %w", err)
+	}
+
+	// 初始化定课服务
+	bookingService := booking.NewService(bookingRepo, booking.Config{
+		Location:          studioLocation,
+		SeriesHorizon:     time.Duration(cfg.Booking.SeriesHorizonDays) * 24 * time.Hour,
+		WaitlistCutoff:    cfg.Booking.WaitlistCutoff,
+		Policy:            cfg.Booking.CancellationPolicy(),
+		CheckIn:           cfg.Booking.CheckInWindow(),
+		RequireMembership: cfg.Booking.RequireMembership,
+	}, notificationService, logger)
+
+	return &Services{
+		DB:            db,
+		Storage:       storageClient,
+		WeChat:        wechatAPI,
+		KnowledgeRepo: kbRepo,
+		Knowledge:     kbService,
+		Retriever:     aiservice.NewRetriever(vectorClient, kbRepo, cfg.Retrieval.MaxContentLength, logger),
+		Notification:  notificationService,
+		Booking:       bookingService,
+		Location:      location.NewService(postgres.NewLocationRepository(db), logger),
+		Membership:    membership.NewService(postgres.NewMembershipRepository(db), logger),
+		logger:        logger,
+	}, nil
+}
+
+// RegisterMCPTools 注册MCP工具。memberTools为false时不注册以当前登录用户身份操作的工具
+// （预订、会员卡等），用于没有用户身份的接入（如stdio）。
+func (s *Services) RegisterMCPTools(server mcp.Server, memberTools bool) {
+	tools.RegisterScheduleTools(server, s.Booking, s.Location, s.logger)
+	tools.RegisterKnowledgeTools(server, s.Knowledge, s.Retriever, s.logger)
+	if memberTools {
+		tools.RegisterBookingTools(server, s.Booking, s.logger)
+		tools.RegisterMembershipTools(server, s.Membership, s.logger)
+	}
+}
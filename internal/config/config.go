@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/pkg/notify"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +21,15 @@ type Config struct {
 	OpenAI    OpenAIConfig
 	Vector    VectorServiceConfig
 	Embedding EmbeddingServiceConfig
+	Worker    EmbeddingWorkerConfig
+	Chunking  ChunkingConfig
+	Retrieval RetrievalConfig
+	MCP       MCPConfig
+	Auth      AuthConfig
+	Booking   BookingConfig
+	WeChat    WeChatConfig
+	Payment   PaymentConfig
+	Notify    NotifyConfig
 	Jaeger    JaegerConfig
 	Log       LogConfig
 }
@@ -61,9 +72,13 @@ type QdrantConfig struct {
 
 // OpenAIConfig OpenAI配置
 type OpenAIConfig struct {
-	APIKey  string
-	BaseURL string
-	Model   string
+	APIKey        string
+	BaseURL       string
+	Model         string
+	MaxIterations int // 工具调用循环中最多调用模型的次数
+	MaxToolCalls  int // 单轮对话最多执行的工具调用次数
+	// MaxHistoryTokens 会话历史的token上限，超出后较早的消息会被摘要
+	MaxHistoryTokens int
 }
 
 // VectorServiceConfig 向量服务配置
@@ -76,6 +91,161 @@ type EmbeddingServiceConfig struct {
 	URL string
 }
 
+// EmbeddingWorkerConfig 后台向量化worker配置
+type EmbeddingWorkerConfig struct {
+	Concurrency  int
+	PollInterval time.Duration
+	LeaseTimeout time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	DrainTimeout time.Duration
+}
+
+// ChunkingConfig 文档分块配置
+type ChunkingConfig struct {
+	ChunkSize int // 每块最大字符数
+	Overlap   int // 相邻分块重叠字符数
+}
+
+// RetrievalConfig 知识检索配置
+type RetrievalConfig struct {
+	MaxContentLength int // 每条检索来源返回给模型的最大字符数
+}
+
+// MCPConfig MCP服务器配置
+type MCPConfig struct {
+	ToolTimeout time.Duration // 工具调用的默认超时时间
+	AdminToken  string        // HTTP端点的管理员令牌，为空时不开放管理员工具
+	StdioAdmin  bool          // stdio服务器是否以管理员身份运行
+}
+
+// AuthConfig 登录认证配置
+type AuthConfig struct {
+	TokenSecret string        // 会话令牌签名密钥，为空时启动时随机生成（重启后已签发的令牌失效）
+	TokenTTL    time.Duration // 会话令牌有效期
+}
+
+// WeChatConfig 微信小程序配置
+type WeChatConfig struct {
+	AppID     string
+	AppSecret string
+	FakeLogin bool // 使用本地假code2session（仅限开发测试，任意code都能登录）
+}
+
+// PaymentConfig 支付配置（微信支付APIv3商户参数，appid取WeChat.AppID）
+type PaymentConfig struct {
+	Fake                  bool          // 使用本地假支付网关（仅限开发测试，可通过接口模拟付款）
+	FakeSecret            string        // 假网关支付通知的签名密钥
+	MchID                 string        // 商户号
+	MchSerialNo           string        // 商户API证书序列号
+	PrivateKeyPath        string        // 商户API私钥文件
+	APIv3Key              string        // APIv3密钥，用于解密支付通知
+	PlatformPublicKeyPath string        // 微信支付平台公钥文件
+	NotifyURL             string        // 支付结果通知地址，需公网可访问
+	OrderTTL              time.Duration // 下单后的支付时限
+	ExpireInterval        time.Duration // 关闭超时订单任务的执行间隔
+}
+
+// NotifyConfig 消息通知配置，各渠道按配置启用，均未启用时写入日志
+type NotifyConfig struct {
+	Log                 bool          // 写入日志（本地开发）
+	FilePath            string        // 以JSON行追加写入的文件（本地开发），为空不启用
+	WeChatTemplatesPath string        // 订阅消息模板配置文件，为空不启用订阅消息
+	SMSURL              string        // 短信网关地址，为空不启用短信
+	SMSAPIKey           string        // 短信网关密钥
+	SMSSign             string        // 短信签名
+	SMTPHost            string        // SMTP服务器，为空不启用邮件
+	SMTPPort            int           // SMTP端口
+	SMTPUsername        string        // SMTP用户名
+	SMTPPassword        string        // SMTP密码
+	SMTPFrom            string        // 发件人地址
+	ReminderLead        time.Duration // 开课前多久发送上课提醒
+	ReminderInterval    time.Duration // 上课提醒任务的执行间隔
+}
+
+// Channels 按配置创建通知渠道，均未启用时使用日志渠道
+func (c NotifyConfig) Channels(subscribe notify.SubscribeSender, logger *zap.Logger) ([]notify.Channel, error) {
+	var channels []notify.Channel
+	if c.WeChatTemplatesPath != "" {
+		templates, err := notify.LoadSubscribeTemplates(c.WeChatTemplatesPath)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, notify.NewWeChatChannel(subscribe, templates))
+	}
+	if c.SMSURL != "" {
+		channels = append(channels, notify.NewSMSChannel(notify.SMSConfig{
+			URL:    c.SMSURL,
+			APIKey: c.SMSAPIKey,
+			Sign:   c.SMSSign,
+		}))
+	}
+	if c.SMTPHost != "" {
+		channels = append(channels, notify.NewEmailChannel(notify.EmailConfig{
+			Host:     c.SMTPHost,
+			Port:     c.SMTPPort,
+			Username: c.SMTPUsername,
+			Password: c.SMTPPassword,
+			From:     c.SMTPFrom,
+		}))
+	}
+	if c.FilePath != "" {
+		channels = append(channels, notify.NewFileChannel(c.FilePath))
+	}
+	if c.Log || len(channels) == 0 {
+		channels = append(channels, notify.NewLogChannel(logger))
+	}
+	return channels, nil
+}
+
+// BookingConfig 定课业务配置
+type BookingConfig struct {
+	Timezone               string        // 场馆所在时区（IANA名称），课程系列的上课时间按此时区解释
+	SeriesHorizonDays      int           // 课程系列提前生成课程的天数
+	SeriesGenerateInterval time.Duration // 课程系列生成任务的执行间隔
+	WaitlistCutoff         time.Duration // 开课前多久停止候补自动转正
+	CancelDeadline         time.Duration // 开课前多久截止免费取消
+	AllowLateCancel        bool          // 截止后会员是否仍可取消（记为迟取消）
+	LateCancelForfeit      bool          // 迟取消与爽约不退还课时
+	NoShowBanThreshold     int           // 统计窗口内爽约达到该次数后暂停预订，0表示不限制
+	NoShowWindow           time.Duration // 爽约次数的统计窗口
+	NoShowBanDuration      time.Duration // 暂停预订的时长
+	CheckInOpensBefore     time.Duration // 开课前多久开放签到
+	SelfCheckInClosesAfter time.Duration // 开课后多久关闭会员自助签到
+	CompleteInterval       time.Duration // 结束已下课课程任务的执行间隔
+	RequireMembership      bool          // 预订需从会员卡扣除课时
+}
+
+// Location 加载场馆时区
+func (c BookingConfig) Location() (*time.Location, error) {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("无效的STUDIO_TIMEZONE: %w", err)
+	}
+	return loc, nil
+}
+
+// CancellationPolicy 转换为领域层的取消与爽约政策
+func (c BookingConfig) CancellationPolicy() booking.CancellationPolicy {
+	return booking.CancellationPolicy{
+		Deadline:           c.CancelDeadline,
+		AllowLateCancel:    c.AllowLateCancel,
+		ForfeitCredit:      c.LateCancelForfeit,
+		NoShowBanThreshold: c.NoShowBanThreshold,
+		NoShowWindow:       c.NoShowWindow,
+		BanDuration:        c.NoShowBanDuration,
+	}
+}
+
+// CheckInWindow 转换为领域层的签到时间窗口
+func (c BookingConfig) CheckInWindow() booking.CheckInWindow {
+	return booking.CheckInWindow{
+		OpensBefore:     c.CheckInOpensBefore,
+		SelfClosesAfter: c.SelfCheckInClosesAfter,
+	}
+}
+
 // JaegerConfig Jaeger配置
 type JaegerConfig struct {
 	Endpoint string
@@ -87,12 +257,28 @@ type LogConfig struct {
 	Format string
 }
 
-// Load 加载配置
+// Load 加载API服务器配置
 func Load() (*Config, error) {
+	cfg := load()
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadMCP 加载MCP服务器配置，MCP服务器只执行业务工具，不要求OpenAI配置
+func LoadMCP() *Config {
+	return load()
+}
+
+// load 从环境变量（及.env文件）读取配置
+func load() *Config {
 	// 尝试加载.env文件，但不强制要求
 	_ = godotenv.Load()
 
-	cfg := &Config{
+	return &Config{
 		Server: ServerConfig{
 			Host:         getEnv("SERVER_HOST", "0.0.0.0"),
 			Port:         getEnvAsInt("SERVER_PORT", 8080),
@@ -122,9 +308,12 @@ func Load() (*Config, error) {
 			Port: getEnvAsInt("QDRANT_PORT", 6333),
 		},
 		OpenAI: OpenAIConfig{
-			APIKey:  getEnv("OPENAI_API_KEY", ""),
-			BaseURL: getEnv("OPENAI_BASE_URL", "https://api.deepseek.com/v1"),
-			Model:   getEnv("OPENAI_MODEL", "deepseek-chat"),
+			APIKey:           getEnv("OPENAI_API_KEY", ""),
+			BaseURL:          getEnv("OPENAI_BASE_URL", "https://api.deepseek.com/v1"),
+			Model:            getEnv("OPENAI_MODEL", "deepseek-chat"),
+			MaxIterations:    getEnvAsInt("AI_MAX_ITERATIONS", 5),
+			MaxToolCalls:     getEnvAsInt("AI_MAX_TOOL_CALLS", 8),
+			MaxHistoryTokens: getEnvAsInt("AI_MAX_HISTORY_TOKENS", 3000),
 		},
 		Vector: VectorServiceConfig{
 			URL: getEnv("VECTOR_SERVICE_URL", "http://localhost:8003"),
@@ -132,6 +321,79 @@ func Load() (*Config, error) {
 		Embedding: EmbeddingServiceConfig{
 			URL: getEnv("EMBEDDING_SERVICE_URL", "http://localhost:8002"),
 		},
+		Worker: EmbeddingWorkerConfig{
+			Concurrency:  getEnvAsInt("EMBEDDING_WORKER_CONCURRENCY", 2),
+			PollInterval: getEnvAsDuration("EMBEDDING_WORKER_POLL_INTERVAL", 5*time.Second),
+			LeaseTimeout: getEnvAsDuration("EMBEDDING_WORKER_LEASE_TIMEOUT", 5*time.Minute),
+			MaxAttempts:  getEnvAsInt("EMBEDDING_WORKER_MAX_ATTEMPTS", 5),
+			BaseBackoff:  getEnvAsDuration("EMBEDDING_WORKER_BASE_BACKOFF", 10*time.Second),
+			MaxBackoff:   getEnvAsDuration("EMBEDDING_WORKER_MAX_BACKOFF", 30*time.Minute),
+			DrainTimeout: getEnvAsDuration("EMBEDDING_WORKER_DRAIN_TIMEOUT", 30*time.Second),
+		},
+		Chunking: ChunkingConfig{
+			ChunkSize: getEnvAsInt("CHUNK_SIZE", 500),
+			Overlap:   getEnvAsInt("CHUNK_OVERLAP", 80),
+		},
+		Retrieval: RetrievalConfig{
+			MaxContentLength: getEnvAsInt("RETRIEVAL_MAX_CONTENT_LENGTH", 800),
+		},
+		MCP: MCPConfig{
+			ToolTimeout: getEnvAsDuration("MCP_TOOL_TIMEOUT", 15*time.Second),
+			AdminToken:  getEnv("MCP_ADMIN_TOKEN", ""),
+			StdioAdmin:  getEnvAsBool("MCP_STDIO_ADMIN", true),
+		},
+		Auth: AuthConfig{
+			TokenSecret: getEnv("AUTH_TOKEN_SECRET", ""),
+			TokenTTL:    getEnvAsDuration("AUTH_TOKEN_TTL", 7*24*time.Hour),
+		},
+		WeChat: WeChatConfig{
+			AppID:     getEnv("WECHAT_APP_ID", ""),
+			AppSecret: getEnv("WECHAT_APP_SECRET", ""),
+			FakeLogin: getEnvAsBool("WECHAT_FAKE_LOGIN", false),
+		},
+		Payment: PaymentConfig{
+			Fake:                  getEnvAsBool("PAYMENT_FAKE", false),
+			FakeSecret:            getEnv("PAYMENT_FAKE_SECRET", "fake-payment-secret"),
+			MchID:                 getEnv("WECHAT_PAY_MCH_ID", ""),
+			MchSerialNo:           getEnv("WECHAT_PAY_SERIAL_NO", ""),
+			PrivateKeyPath:        getEnv("WECHAT_PAY_PRIVATE_KEY_PATH", ""),
+			APIv3Key:              getEnv("WECHAT_PAY_APIV3_KEY", ""),
+			PlatformPublicKeyPath: getEnv("WECHAT_PAY_PLATFORM_PUBLIC_KEY_PATH", ""),
+			NotifyURL:             getEnv("WECHAT_PAY_NOTIFY_URL", ""),
+			OrderTTL:              getEnvAsDuration("ORDER_TTL", 30*time.Minute),
+			ExpireInterval:        getEnvAsDuration("ORDER_EXPIRE_INTERVAL", 5*time.Minute),
+		},
+		Notify: NotifyConfig{
+			Log:                 getEnvAsBool("NOTIFY_LOG", false),
+			FilePath:            getEnv("NOTIFY_FILE_PATH", ""),
+			WeChatTemplatesPath: getEnv("WECHAT_SUBSCRIBE_TEMPLATES", ""),
+			SMSURL:              getEnv("SMS_GATEWAY_URL", ""),
+			SMSAPIKey:           getEnv("SMS_GATEWAY_API_KEY", ""),
+			SMSSign:             getEnv("SMS_SIGN", ""),
+			SMTPHost:            getEnv("SMTP_HOST", ""),
+			SMTPPort:            getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername:        getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:            getEnv("SMTP_FROM", ""),
+			ReminderLead:        getEnvAsDuration("CLASS_REMINDER_LEAD", 2*time.Hour),
+			ReminderInterval:    getEnvAsDuration("CLASS_REMINDER_INTERVAL", 5*time.Minute),
+		},
+		Booking: BookingConfig{
+			Timezone:               getEnv("STUDIO_TIMEZONE", "Asia/Shanghai"),
+			SeriesHorizonDays:      getEnvAsInt("CLASS_SERIES_HORIZON_DAYS", 28),
+			SeriesGenerateInterval: getEnvAsDuration("CLASS_SERIES_GENERATE_INTERVAL", time.Hour),
+			WaitlistCutoff:         getEnvAsDuration("WAITLIST_PROMOTION_CUTOFF", 2*time.Hour),
+			CancelDeadline:         getEnvAsDuration("CANCEL_DEADLINE", 12*time.Hour),
+			AllowLateCancel:        getEnvAsBool("LATE_CANCEL_ALLOWED", true),
+			LateCancelForfeit:      getEnvAsBool("LATE_CANCEL_FORFEIT_CREDIT", true),
+			NoShowBanThreshold:     getEnvAsInt("NO_SHOW_BAN_THRESHOLD", 3),
+			NoShowWindow:           getEnvAsDuration("NO_SHOW_WINDOW", 30*24*time.Hour),
+			NoShowBanDuration:      getEnvAsDuration("NO_SHOW_BAN_DURATION", 7*24*time.Hour),
+			CheckInOpensBefore:     getEnvAsDuration("CHECK_IN_OPENS_BEFORE", 30*time.Minute),
+			SelfCheckInClosesAfter: getEnvAsDuration("SELF_CHECK_IN_CLOSES_AFTER", 15*time.Minute),
+			CompleteInterval:       getEnvAsDuration("CLASS_COMPLETE_INTERVAL", 5*time.Minute),
+			RequireMembership:      getEnvAsBool("BOOKING_REQUIRES_MEMBERSHIP", true),
+		},
 		Jaeger: JaegerConfig{
 			Endpoint: getEnv("JAEGER_ENDPOINT", "http://localhost:14268/api/traces"),
 		},
@@ -140,12 +402,6 @@ func Load() (*Config, error) {
 			Format: getEnv("LOG_FORMAT", "json"),
 		},
 	}
-
-	if err := cfg.validate(); err != nil {
-		return nil, fmt.Errorf("配置验证失败: %w", err)
-	}
-
-	return cfg, nil
 }
 
 // validate 验证配置
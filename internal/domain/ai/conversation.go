@@ -0,0 +1,27 @@
+package ai
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Conversation 会话实体
+type Conversation struct {
+	ID              uuid.UUID `json:"id"`
+	UserID          string    `json:"user_id"`
+	Title           string    `json:"title"`
+	Summary         string    `json:"summary,omitempty"` // 早期消息的摘要
+	SummarizedCount int       `json:"-"`                 // 已被摘要覆盖的最早消息条数
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Message 会话消息实体
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Role           string    `json:"role"` // "user", "assistant"
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
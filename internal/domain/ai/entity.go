@@ -2,36 +2,56 @@ package ai
 
 // ChatMessage 聊天消息
 type ChatMessage struct {
-	Role    string `json:"role"`    // "user", "assistant", "system"
-	Content string `json:"content"`
+	Role       string     `json:"role"` // "user", "assistant", "system", "tool"
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant消息发起的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool消息对应的工具调用ID
 }
 
 // ChatRequest 聊天请求
 type ChatRequest struct {
-	Message    string   `json:"message"`
-	History    []ChatMessage `json:"history,omitempty"`
-	BaseID     string   `json:"base_id,omitempty"` // 指定知识库ID
+	Message        string        `json:"message"`
+	History        []ChatMessage `json:"history,omitempty"`
+	BaseID         string        `json:"base_id,omitempty"`         // 指定知识库ID
+	ConversationID string        `json:"conversation_id,omitempty"` // 指定后由服务端加载历史消息，忽略History
 }
 
 // ChatResponse 聊天响应
 type ChatResponse struct {
-	Message     string   `json:"message"`
-	Sources     []Source `json:"sources,omitempty"` // 引用的知识库内容
-	ToolCalls   []ToolCall `json:"tool_calls,omitempty"` // MCP工具调用
+	ConversationID string     `json:"conversation_id,omitempty"`
+	Message        string     `json:"message"`
+	Sources        []Source   `json:"sources,omitempty"`    // 引用的知识库内容
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"` // MCP工具调用
 }
 
 // Source 知识库来源
 type Source struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	ID      string  `json:"id"`
+	Title   string  `json:"title"`
+	Content string  `json:"content"`
 	Score   float64 `json:"score"`
 }
 
 // ToolCall MCP工具调用
 type ToolCall struct {
+	ID        string                 `json:"id,omitempty"`
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
 	Result    interface{}            `json:"result,omitempty"`
 }
 
+// 流式事件类型
+const (
+	StreamEventSources    = "sources"     // 检索到的知识库来源
+	StreamEventDelta      = "delta"       // 模型输出的文本增量
+	StreamEventToolCall   = "tool_call"   // 模型发起的工具调用
+	StreamEventToolResult = "tool_result" // 工具执行结果
+	StreamEventDone       = "done"        // 回答结束
+	StreamEventError      = "error"       // 处理出错
+)
+
+// StreamEvent 流式聊天事件
+type StreamEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
@@ -0,0 +1,71 @@
+package booking
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 签到方式
+const (
+	CheckInByQRCode = "qr"    // 工作人员扫描会员的签到码
+	CheckInBySelf   = "self"  // 会员在签到时间内自助签到
+	CheckInByStaff  = "staff" // 工作人员按预订手动签到
+)
+
+// Attendance 签到记录，每个预订至多一条
+type Attendance struct {
+	ID          uuid.UUID `json:"id"`
+	BookingID   uuid.UUID `json:"booking_id"`
+	ClassID     uuid.UUID `json:"class_id"`
+	UserID      string    `json:"user_id"`
+	Method      string    `json:"method"`        // 'qr', 'self', 'staff'
+	CheckedInBy string    `json:"checked_in_by"` // 操作人的用户ID，自助签到时为会员本人
+	CheckedInAt time.Time `json:"checked_in_at"`
+}
+
+// TableName 指定表名
+func (Attendance) TableName() string {
+	return "attendances"
+}
+
+// RosterEntry 课程点名表中的一行：预订及其签到情况
+type RosterEntry struct {
+	BookingID   uuid.UUID  `json:"booking_id"`
+	UserID      string     `json:"user_id"`
+	UserName    string     `json:"user_name"`
+	Status      string     `json:"status"` // 预订状态：'confirmed', 'completed', 'no_show'
+	CheckedIn   bool       `json:"checked_in"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	Method      string     `json:"method,omitempty"`
+}
+
+// CheckInWindow 签到时间窗口
+type CheckInWindow struct {
+	// OpensBefore 开课前多久开放签到
+	OpensBefore time.Duration
+	// SelfClosesAfter 开课后多久关闭会员自助签到；工作人员可签到至下课
+	SelfClosesAfter time.Duration
+}
+
+// SelfOpen 会员在now是否可以自助签到
+func (w CheckInWindow) SelfOpen(c *Class, now time.Time) bool {
+	return !now.Before(c.StartTime.Add(-w.OpensBefore)) && !now.After(c.StartTime.Add(w.SelfClosesAfter))
+}
+
+// StaffOpen 工作人员在now是否可以为会员签到
+func (w CheckInWindow) StaffOpen(c *Class, now time.Time) bool {
+	return !now.Before(c.StartTime.Add(-w.OpensBefore)) && !now.After(c.EndTime)
+}
+
+// NewCheckInToken 生成预订的签到码（32位十六进制随机串），用于生成二维码
+func NewCheckInToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand读取失败时退化为UUID，仍不可预测
+		return uuid.NewString()
+	}
+	return hex.EncodeToString(buf)
+}
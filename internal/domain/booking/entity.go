@@ -4,30 +4,55 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/location"
+	"github.com/yoga/knowledge-base/internal/domain/user"
 )
 
 // Class 课程实体
 type Class struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Instructor  string    `json:"instructor"`
-	StartTime   time.Time `json:"start_time"`
-	EndTime     time.Time `json:"end_time"`
-	Capacity    int       `json:"capacity"`
-	BookedCount int       `json:"booked_count"`
-	Status      string    `json:"status"` // 'scheduled', 'cancelled', 'completed'
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Description      string     `json:"description"`
+	Instructor       string     `json:"instructor"`                   // 授课教练姓名，关联了教练档案时与档案一致
+	InstructorID     *uuid.UUID `json:"instructor_id,omitempty"`      // 授课教练档案（instructors.id）
+	InstructorUserID *uuid.UUID `json:"instructor_user_id,omitempty"` // 授课教练的用户ID（users.id）
+	RoomID           *uuid.UUID `json:"room_id,omitempty"`            // 上课的教室
+	StartTime        time.Time  `json:"start_time"`
+	EndTime          time.Time  `json:"end_time"`
+	Capacity         int        `json:"capacity"`
+	BookedCount      int        `json:"booked_count"`
+	Status           string     `json:"status"`                // 'scheduled', 'cancelled', 'completed'
+	SeriesID         *uuid.UUID `json:"series_id,omitempty"`   // 所属课程系列
+	SeriesDate       *time.Time `json:"series_date,omitempty"` // 在系列中的原定日期，单节调整时间后保持不变
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Room 教室及所属场馆，查询课程时填写
+	Room *location.Room `json:"room,omitempty" gorm:"-"`
 }
 
 // Booking 预订实体
 type Booking struct {
-	ID        uuid.UUID `json:"id"`
-	ClassID   uuid.UUID `json:"class_id"`
-	UserID    string    `json:"user_id"`    // 微信用户ID
-	UserName  string    `json:"user_name"`
-	Status    string    `json:"status"`     // 'confirmed', 'cancelled', 'completed'
+	ID       uuid.UUID `json:"id"`
+	ClassID  uuid.UUID `json:"class_id"`
+	UserID   string    `json:"user_id"` // 用户ID（users.id）
+	UserName string    `json:"user_name"`
+	Status   string    `json:"status"` // 'confirmed', 'waitlisted', 'cancelled', 'completed', 'no_show'
+	// 签到码，会员出示其二维码由工作人员扫码签到
+	CheckInToken string `json:"check_in_token,omitempty"`
+	// 扣除课时的会员卡，不要求会员卡时为空
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+	// 候补名单中的位置（从1开始），仅status为waitlisted时有值
+	WaitlistPosition *int `json:"waitlist_position,omitempty"`
+	// 取消信息，仅status为cancelled时有值
+	CancelledBy  string     `json:"cancelled_by,omitempty"` // 取消操作人的用户ID
+	CancelReason string     `json:"cancel_reason,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	// LateCancel 会员在免费取消截止时间之后取消
+	LateCancel bool `json:"late_cancel,omitempty"`
+	// CreditForfeited 因迟取消或爽约不退还课时
+	CreditForfeited bool `json:"credit_forfeited,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -37,8 +62,46 @@ func (c *Class) IsAvailable() bool {
 	return c.Status == "scheduled" && c.BookedCount < c.Capacity
 }
 
+// ManageableBy 用户是否可以管理该课程：管理员可管理全部课程，教练只能管理自己授课的课程
+func (c *Class) ManageableBy(u *user.User) bool {
+	if u.IsAdmin() {
+		return true
+	}
+	return u.Role == user.RoleInstructor && c.InstructorUserID != nil && *c.InstructorUserID == u.ID
+}
+
+// Cancellation 取消预订的操作信息
+type Cancellation struct {
+	By     string // 操作人的用户ID
+	Reason string
+	// PromoteBefore 在此时间之前释放的名额会自动转给候补名单中的第一位，零值表示不自动转正
+	PromoteBefore time.Time
+	// Policy 非空时按取消政策处理已确认的预订（会员自行取消），在锁定预订后按当时的状态判定
+	Policy *CancellationPolicy
+	// RequireCredit 候补转正时需扣除课时
+	RequireCredit bool
+}
+
+// Validate 校验课程时间与容量
+func (c *Class) Validate() error {
+	if !c.EndTime.After(c.StartTime) {
+		return ErrInvalidClassTime
+	}
+	if c.Capacity <= 0 {
+		return ErrInvalidCapacity
+	}
+	if c.Capacity < c.BookedCount {
+		return ErrCapacityBelowBooked
+	}
+	return nil
+}
+
+// IsFull 课程名额是否已满
+func (c *Class) IsFull() bool {
+	return c.BookedCount >= c.Capacity
+}
+
 // CanBook 检查是否可以预订
 func (c *Class) CanBook() bool {
 	return c.IsAvailable() && time.Now().Before(c.StartTime)
 }
-
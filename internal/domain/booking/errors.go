@@ -0,0 +1,106 @@
+package booking
+
+import "errors"
+
+var (
+	// ErrInvalidClassTime 课程结束时间必须晚于开始时间
+	ErrInvalidClassTime = errors.New("课程结束时间必须晚于开始时间")
+	// ErrInvalidCapacity 课程容量必须大于0
+	ErrInvalidCapacity = errors.New("课程容量必须大于0")
+	// ErrCapacityBelowBooked 课程容量不能低于已预订人数
+	ErrCapacityBelowBooked = errors.New("课程容量不能低于已预订人数")
+	// ErrInstructorConflict 授课教练在该时段已有其他课程
+	ErrInstructorConflict = errors.New("授课教练在该时段已有其他课程")
+	// ErrRoomConflict 教室在该时段已有其他课程
+	ErrRoomConflict = errors.New("教室在该时段已有其他课程")
+	// ErrRoomCapacityExceeded 课程容量超过教室容量
+	ErrRoomCapacityExceeded = errors.New("课程容量不能超过教室容量")
+	// ErrClassNotEditable 课程已取消或已完成，不能再修改
+	ErrClassNotEditable = errors.New("课程已取消或已完成")
+	// ErrClassNotStarted 课程尚未开始，不能标记为已完成
+	ErrClassNotStarted = errors.New("课程尚未开始")
+	// ErrBookingNotFound 预订不存在
+	ErrBookingNotFound = errors.New("预订不存在")
+	// ErrBookingNotCancellable 预订当前状态不允许取消（已取消或已完成）
+	ErrBookingNotCancellable = errors.New("只能取消已确认或候补中的预订")
+	// ErrClassNotBookable 课程已取消、已完成或已开始，不能预订
+	ErrClassNotBookable = errors.New("课程不可预订")
+	// ErrClassFull 课程已满，可加入候补名单
+	ErrClassFull = errors.New("课程已满，可加入候补名单")
+	// ErrClassAvailable 课程还有名额，无需候补
+	ErrClassAvailable = errors.New("课程还有名额，请直接预订")
+	// ErrAlreadyBooked 已预订该课程或已在候补名单中
+	ErrAlreadyBooked = errors.New("您已预订该课程或已在候补名单中")
+	// ErrClassStarted 课程已开始，会员不能再取消预订
+	ErrClassStarted = errors.New("课程已开始，不能取消预订")
+	// ErrCancelDeadlinePassed 已过免费取消截止时间，且场馆不允许迟取消
+	ErrCancelDeadlinePassed = errors.New("已过取消截止时间，不能取消预订")
+	// ErrBookingBanned 爽约次数过多，暂停预订
+	ErrBookingBanned = errors.New("爽约次数过多，暂时不能预订")
+	// ErrBookingNotConfirmed 只有已确认的预订可以标记为爽约
+	ErrBookingNotConfirmed = errors.New("只能将已确认的预订标记为爽约")
+	// ErrBookingCheckedIn 已签到的预订不能标记为爽约
+	ErrBookingCheckedIn = errors.New("该预订已签到，不能标记为爽约")
+	// ErrNoCredits 没有可用于该课程的会员卡或剩余课时
+	ErrNoCredits = errors.New("没有可用的会员卡或剩余课时")
+	// ErrInvalidCheckInToken 签到码无效
+	ErrInvalidCheckInToken = errors.New("签到码无效")
+	// ErrCheckInNotOpen 不在签到时间内
+	ErrCheckInNotOpen = errors.New("不在签到时间内")
+	// ErrAlreadyCheckedIn 该预订已签到
+	ErrAlreadyCheckedIn = errors.New("该预订已签到")
+	// ErrBookingNotCheckable 只有已确认的预订可以签到
+	ErrBookingNotCheckable = errors.New("只有已确认的预订可以签到")
+)
+
+// 错误码，随HTTP响应和MCP工具结果返回，便于客户端区分处理
+const (
+	CodeBookingNotFound       = "booking_not_found"
+	CodeBookingNotCancellable = "booking_not_cancellable"
+	CodeBookingNotConfirmed   = "booking_not_confirmed"
+	CodeBookingCheckedIn      = "booking_checked_in"
+	CodeClassStarted          = "class_started"
+	CodeCancelDeadlinePassed  = "cancel_deadline_passed"
+	CodeBookingBanned         = "booking_banned"
+	CodeClassNotBookable      = "class_not_bookable"
+	CodeClassFull             = "class_full"
+	CodeClassAvailable        = "class_available"
+	CodeAlreadyBooked         = "already_booked"
+	CodeNoCredits             = "no_credits"
+	CodeInvalidCheckInToken   = "invalid_check_in_token"
+	CodeCheckInNotOpen        = "check_in_not_open"
+	CodeAlreadyCheckedIn      = "already_checked_in"
+	CodeBookingNotCheckable   = "booking_not_checkable"
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrBookingNotFound, CodeBookingNotFound},
+	{ErrBookingNotCancellable, CodeBookingNotCancellable},
+	{ErrBookingNotConfirmed, CodeBookingNotConfirmed},
+	{ErrBookingCheckedIn, CodeBookingCheckedIn},
+	{ErrClassStarted, CodeClassStarted},
+	{ErrCancelDeadlinePassed, CodeCancelDeadlinePassed},
+	{ErrBookingBanned, CodeBookingBanned},
+	{ErrClassNotBookable, CodeClassNotBookable},
+	{ErrClassFull, CodeClassFull},
+	{ErrClassAvailable, CodeClassAvailable},
+	{ErrAlreadyBooked, CodeAlreadyBooked},
+	{ErrNoCredits, CodeNoCredits},
+	{ErrInvalidCheckInToken, CodeInvalidCheckInToken},
+	{ErrCheckInNotOpen, CodeCheckInNotOpen},
+	{ErrAlreadyCheckedIn, CodeAlreadyCheckedIn},
+	{ErrBookingNotCheckable, CodeBookingNotCheckable},
+}
+
+// ErrorCode 返回预订错误对应的错误码，非预订业务错误返回空字符串
+func ErrorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
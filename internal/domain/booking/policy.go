@@ -0,0 +1,63 @@
+package booking
+
+import (
+	"sort"
+	"time"
+)
+
+// CancellationPolicy 场馆的取消与爽约政策
+type CancellationPolicy struct {
+	// Deadline 开课前多久截止免费取消，之后取消记为迟取消；0表示开课前均可免费取消
+	Deadline time.Duration
+	// AllowLateCancel 截止后会员是否仍可自行取消（记为迟取消），否则返回ErrCancelDeadlinePassed
+	AllowLateCancel bool
+	// ForfeitCredit 迟取消和爽约不退还课时
+	ForfeitCredit bool
+	// NoShowBanThreshold 统计窗口内爽约达到该次数后暂停预订，0表示不限制
+	NoShowBanThreshold int
+	// NoShowWindow 爽约次数的统计窗口
+	NoShowWindow time.Duration
+	// BanDuration 暂停预订的时长，从触发暂停的那次爽约的上课时间起算
+	BanDuration time.Duration
+}
+
+// IsLate 在now取消开课时间为start的预订是否已过免费取消截止时间
+func (p CancellationPolicy) IsLate(start, now time.Time) bool {
+	return !now.Before(start.Add(-p.Deadline))
+}
+
+// CheckCancel 会员在now自行取消开课时间为start的已确认预订是否允许，允许时返回是否为迟取消。
+// 课程已开始返回ErrClassStarted，已过截止时间且不允许迟取消返回ErrCancelDeadlinePassed。
+func (p CancellationPolicy) CheckCancel(start, now time.Time) (late bool, err error) {
+	if !now.Before(start) {
+		return false, ErrClassStarted
+	}
+	if !p.IsLate(start, now) {
+		return false, nil
+	}
+	if !p.AllowLateCancel {
+		return false, ErrCancelDeadlinePassed
+	}
+	return true, nil
+}
+
+// NoShowSince 统计爽约次数的起始时间
+func (p CancellationPolicy) NoShowSince(now time.Time) time.Time {
+	return now.Add(-p.NoShowWindow)
+}
+
+// BannedUntil 根据统计窗口内爽约课程的上课时间计算暂停预订的截止时间，未被暂停时返回false
+func (p CancellationPolicy) BannedUntil(noShows []time.Time, now time.Time) (time.Time, bool) {
+	if p.NoShowBanThreshold <= 0 || len(noShows) < p.NoShowBanThreshold {
+		return time.Time{}, false
+	}
+
+	sorted := append([]time.Time(nil), noShows...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	until := sorted[len(sorted)-1].Add(p.BanDuration)
+	if !now.Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
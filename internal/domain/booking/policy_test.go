@@ -0,0 +1,149 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCancellationPolicyCheckCancel(t *testing.T) {
+	start := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		policy   CancellationPolicy
+		now      time.Time
+		wantLate bool
+		wantErr  error
+	}{
+		{
+			name:   "截止时间前免费取消",
+			policy: CancellationPolicy{Deadline: 12 * time.Hour, AllowLateCancel: true},
+			now:    start.Add(-13 * time.Hour),
+		},
+		{
+			name:     "恰好到截止时间记为迟取消",
+			policy:   CancellationPolicy{Deadline: 12 * time.Hour, AllowLateCancel: true},
+			now:      start.Add(-12 * time.Hour),
+			wantLate: true,
+		},
+		{
+			name:     "截止后允许迟取消",
+			policy:   CancellationPolicy{Deadline: 12 * time.Hour, AllowLateCancel: true},
+			now:      start.Add(-time.Hour),
+			wantLate: true,
+		},
+		{
+			name:    "截止后不允许迟取消",
+			policy:  CancellationPolicy{Deadline: 12 * time.Hour},
+			now:     start.Add(-time.Hour),
+			wantErr: ErrCancelDeadlinePassed,
+		},
+		{
+			name:   "不设截止时间时开课前均可免费取消",
+			policy: CancellationPolicy{},
+			now:    start.Add(-time.Minute),
+		},
+		{
+			name:    "课程已开始",
+			policy:  CancellationPolicy{Deadline: 12 * time.Hour, AllowLateCancel: true},
+			now:     start,
+			wantErr: ErrClassStarted,
+		},
+		{
+			name:    "不设截止时间时课程已开始",
+			policy:  CancellationPolicy{},
+			now:     start.Add(time.Minute),
+			wantErr: ErrClassStarted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			late, err := tt.policy.CheckCancel(start, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckCancel返回错误%v，期望%v", err, tt.wantErr)
+			}
+			if late != tt.wantLate {
+				t.Errorf("CheckCancel返回late=%v，期望%v", late, tt.wantLate)
+			}
+		})
+	}
+}
+
+func TestCancellationPolicyBannedUntil(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	policy := CancellationPolicy{NoShowBanThreshold: 3, NoShowWindow: 30 * 24 * time.Hour, BanDuration: 7 * 24 * time.Hour}
+
+	tests := []struct {
+		name       string
+		policy     CancellationPolicy
+		noShows    []time.Time
+		wantBanned bool
+		wantUntil  time.Time
+	}{
+		{
+			name:    "未达到次数",
+			policy:  policy,
+			noShows: []time.Time{days(1), days(2)},
+		},
+		{
+			name:       "达到次数从最近一次爽约起暂停",
+			policy:     policy,
+			noShows:    []time.Time{days(10), days(2), days(5)},
+			wantBanned: true,
+			wantUntil:  days(2).AddDate(0, 0, 7),
+		},
+		{
+			name:       "超过次数",
+			policy:     policy,
+			noShows:    []time.Time{days(1), days(3), days(4), days(6)},
+			wantBanned: true,
+			wantUntil:  days(1).AddDate(0, 0, 7),
+		},
+		{
+			name:    "暂停已到期",
+			policy:  policy,
+			noShows: []time.Time{days(20), days(15), days(8)},
+		},
+		{
+			name:    "恰好到期",
+			policy:  policy,
+			noShows: []time.Time{days(20), days(15), days(7)},
+		},
+		{
+			name:    "不限制爽约",
+			policy:  CancellationPolicy{BanDuration: 7 * 24 * time.Hour},
+			noShows: []time.Time{days(1), days(2), days(3)},
+		},
+		{
+			name:   "没有爽约",
+			policy: policy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, banned := tt.policy.BannedUntil(tt.noShows, now)
+			if banned != tt.wantBanned {
+				t.Fatalf("BannedUntil返回banned=%v，期望%v", banned, tt.wantBanned)
+			}
+			if !until.Equal(tt.wantUntil) {
+				t.Errorf("BannedUntil返回%v，期望%v", until, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestCancellationPolicyBannedUntilDoesNotReorderInput(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	noShows := []time.Time{now.Add(-time.Hour), now.Add(-3 * time.Hour), now.Add(-2 * time.Hour)}
+	want := append([]time.Time(nil), noShows...)
+
+	policy := CancellationPolicy{NoShowBanThreshold: 2, BanDuration: time.Hour}
+	policy.BannedUntil(noShows, now)
+	for i := range noShows {
+		if !noShows[i].Equal(want[i]) {
+			t.Fatalf("BannedUntil修改了传入的爽约时间顺序: %v", noShows)
+		}
+	}
+}
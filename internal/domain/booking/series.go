@@ -0,0 +1,216 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+)
+
+// DateLayout 日期格式（开始、结束日期与例外日期）
+const DateLayout = "2006-01-02"
+
+// ErrInvalidSeries 课程系列的重复规则无效
+var ErrInvalidSeries = errors.New("无效的课程系列")
+
+// weekdayCodes RRULE中BYDAY的星期代码，下标与time.Weekday一致
+var weekdayCodes = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ClassSeries 课程系列：按周重复的固定课程（类似RRULE FREQ=WEEKLY;BYDAY=...），
+// 由生成任务在滚动时间窗口内生成具体的课程（classes）
+type ClassSeries struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Description      string     `json:"description"`
+	Instructor       string     `json:"instructor"`
+	InstructorID     *uuid.UUID `json:"instructor_id,omitempty"`
+	InstructorUserID *uuid.UUID `json:"instructor_user_id,omitempty"`
+	RoomID           *uuid.UUID `json:"room_id,omitempty"`
+	Weekdays         []int      `json:"weekdays" gorm:"serializer:json"`        // 每周上课的星期，0为周日，1-6为周一至周六
+	StartClock       string     `json:"start_clock"`                            // 上课时间（当地时间，HH:MM）
+	DurationMinutes  int        `json:"duration_minutes"`                       // 课程时长（分钟）
+	Capacity         int        `json:"capacity"`                               // 每节课容量
+	StartDate        time.Time  `json:"start_date"`                             // 首节课日期（含）
+	EndDate          *time.Time `json:"end_date,omitempty"`                     // 末节课日期（含），为空表示长期重复
+	ExceptionDates   []string   `json:"exception_dates" gorm:"serializer:json"` // 不上课的日期（YYYY-MM-DD）
+	Timezone         string     `json:"timezone"`                               // 上课时间所在时区，如Asia/Shanghai
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName 表名
+func (ClassSeries) TableName() string {
+	return "class_series"
+}
+
+// Validate 校验重复规则
+func (s *ClassSeries) Validate() error {
+	if len(s.Weekdays) == 0 {
+		return fmt.Errorf("%w: 至少需要选择一个上课星期", ErrInvalidSeries)
+	}
+	for _, d := range s.Weekdays {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("%w: 星期取值为0-6", ErrInvalidSeries)
+		}
+	}
+	if _, err := time.Parse("15:04", s.StartClock); err != nil {
+		return fmt.Errorf("%w: 上课时间格式应为HH:MM", ErrInvalidSeries)
+	}
+	if s.DurationMinutes <= 0 {
+		return fmt.Errorf("%w: 课程时长必须大于0", ErrInvalidSeries)
+	}
+	if s.Capacity <= 0 {
+		return ErrInvalidCapacity
+	}
+	if s.EndDate != nil && s.EndDate.Before(s.StartDate) {
+		return fmt.Errorf("%w: 结束日期不能早于开始日期", ErrInvalidSeries)
+	}
+	for _, d := range s.ExceptionDates {
+		if _, err := time.Parse(DateLayout, d); err != nil {
+			return fmt.Errorf("%w: 例外日期格式应为YYYY-MM-DD", ErrInvalidSeries)
+		}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("%w: 未知时区%s", ErrInvalidSeries, s.Timezone)
+	}
+	return nil
+}
+
+// RRule 以iCalendar RRULE格式描述重复规则
+func (s *ClassSeries) RRule() string {
+	days := append([]int(nil), s.Weekdays...)
+	sort.Ints(days)
+	codes := make([]string, 0, len(days))
+	for _, d := range days {
+		codes = append(codes, weekdayCodes[d])
+	}
+
+	rule := "FREQ=WEEKLY;BYDAY=" + strings.Join(codes, ",")
+	if s.EndDate != nil {
+		rule += ";UNTIL=" + s.EndDate.Format("20060102")
+	}
+	return rule
+}
+
+// OccurrenceOn 返回系列在指定日历日的上课时间，该日期不上课时ok为false。
+// date只取年月日，上课时间按系列时区解释。
+func (s *ClassSeries) OccurrenceOn(date time.Time) (start, end time.Time, ok bool) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	day := civilDate(date)
+	if day.Before(civilDate(s.StartDate)) || (s.EndDate != nil && day.After(civilDate(*s.EndDate))) {
+		return time.Time{}, time.Time{}, false
+	}
+	if !s.onWeekday(day.Weekday()) || s.isException(day) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	clock, err := time.Parse("15:04", s.StartClock)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	end = start.Add(time.Duration(s.DurationMinutes) * time.Minute)
+	return start, end, true
+}
+
+// Occurrences 返回[from, to)时间范围内的所有上课日期（按系列时区的日历日）
+func (s *ClassSeries) Occurrences(from, to time.Time) []time.Time {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil
+	}
+
+	var dates []time.Time
+	for day := civilDate(from.In(loc)); day.Before(civilDate(to.In(loc))); day = day.AddDate(0, 0, 1) {
+		if _, _, ok := s.OccurrenceOn(day); ok {
+			dates = append(dates, day)
+		}
+	}
+	return dates
+}
+
+// NewClass 生成指定日期的课程，该日期不上课时返回nil
+func (s *ClassSeries) NewClass(date time.Time) *Class {
+	start, end, ok := s.OccurrenceOn(date)
+	if !ok {
+		return nil
+	}
+
+	seriesDate := civilDate(date)
+	return &Class{
+		Name:             s.Name,
+		Description:      s.Description,
+		Instructor:       s.Instructor,
+		InstructorID:     s.InstructorID,
+		InstructorUserID: s.InstructorUserID,
+		RoomID:           s.RoomID,
+		StartTime:        start,
+		EndTime:          end,
+		Capacity:         s.Capacity,
+		Status:           "scheduled",
+		SeriesID:         &s.ID,
+		SeriesDate:       &seriesDate,
+	}
+}
+
+// ManageableBy 用户是否可以管理该系列：管理员可管理全部系列，教练只能管理自己授课的系列
+func (s *ClassSeries) ManageableBy(u *user.User) bool {
+	if u.IsAdmin() {
+		return true
+	}
+	return u.Role == user.RoleInstructor && s.InstructorUserID != nil && *s.InstructorUserID == u.ID
+}
+
+// onWeekday 是否在该星期上课
+func (s *ClassSeries) onWeekday(weekday time.Weekday) bool {
+	for _, d := range s.Weekdays {
+		if time.Weekday(d) == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// isException 是否为例外日期
+func (s *ClassSeries) isException(day time.Time) bool {
+	key := day.Format(DateLayout)
+	for _, d := range s.ExceptionDates {
+		if d == key {
+			return true
+		}
+	}
+	return false
+}
+
+// civilDate 截取日历日（UTC零点表示），用于跨时区比较日期
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SeriesChanges 修改课程系列时对已生成课程的调整，事务提交后据此通知相关会员
+type SeriesChanges struct {
+	Updated           int                 // 按新规则调整的课程数
+	Cancelled         []ClassCancellation // 按新规则不再上课而取消的课程
+	InstructorChanges []InstructorChange  // 更换了授课教练的课程
+}
+
+// ClassCancellation 被取消的课程及其被取消的预订（取消前的状态）
+type ClassCancellation struct {
+	Class    *Class
+	Bookings []*Booking
+}
+
+// InstructorChange 更换了授课教练的课程及原教练姓名
+type InstructorChange struct {
+	Class    *Class
+	Previous string
+}
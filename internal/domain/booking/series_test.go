@@ -0,0 +1,153 @@
+package booking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mustDate 解析YYYY-MM-DD日期
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(DateLayout, s)
+	if err != nil {
+		t.Fatalf("解析日期%s失败: %v", s, err)
+	}
+	return d
+}
+
+func TestClassSeriesOccurrences(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	local := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02 15:04", s, shanghai)
+		return d
+	}
+
+	end := mustDate(t, "2026-10-31")
+	// 2026年10月每周一、周三上课，10月14日（周三）停课
+	series := ClassSeries{
+		Weekdays:        []int{1, 3},
+		StartClock:      "09:00",
+		DurationMinutes: 60,
+		StartDate:       mustDate(t, "2026-10-01"),
+		EndDate:         &end,
+		ExceptionDates:  []string{"2026-10-14"},
+		Timezone:        "Asia/Shanghai",
+	}
+	openEnded := series
+	openEnded.EndDate = nil
+	badZone := series
+	badZone.Timezone = "Mars/Olympus"
+
+	tests := []struct {
+		name     string
+		series   ClassSeries
+		from, to time.Time
+		want     []string
+	}{
+		{
+			name:   "整月跳过例外日期",
+			series: series,
+			from:   local("2026-10-01 00:00"), to: local("2026-11-01 00:00"),
+			want: []string{"2026-10-05", "2026-10-07", "2026-10-12", "2026-10-19", "2026-10-21", "2026-10-26", "2026-10-28"},
+		},
+		{
+			name:   "开始日期之前不上课",
+			series: series,
+			from:   local("2026-09-20 00:00"), to: local("2026-10-06 00:00"),
+			want: []string{"2026-10-05"},
+		},
+		{
+			name:   "结束日期之后不上课",
+			series: series,
+			from:   local("2026-10-26 00:00"), to: local("2026-11-10 00:00"),
+			want: []string{"2026-10-26", "2026-10-28"},
+		},
+		{
+			name:   "长期重复",
+			series: openEnded,
+			from:   local("2026-10-26 00:00"), to: local("2026-11-10 00:00"),
+			want: []string{"2026-10-26", "2026-10-28", "2026-11-02", "2026-11-04", "2026-11-09"},
+		},
+		{
+			name:   "结束时间不含当天",
+			series: series,
+			from:   local("2026-10-05 00:00"), to: local("2026-10-07 00:00"),
+			want: []string{"2026-10-05"},
+		},
+		{
+			name:   "按系列时区取日历日",
+			series: series,
+			from:   time.Date(2026, 10, 6, 16, 30, 0, 0, time.UTC), // 上海时间10月7日00:30
+			to:     local("2026-10-08 00:00"),
+			want:   []string{"2026-10-07"},
+		},
+		{
+			name:   "时间范围为空",
+			series: series,
+			from:   local("2026-10-10 00:00"), to: local("2026-10-10 00:00"),
+			want: nil,
+		},
+		{
+			name:   "未知时区",
+			series: badZone,
+			from:   local("2026-10-01 00:00"), to: local("2026-11-01 00:00"),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range tt.series.Occurrences(tt.from, tt.to) {
+				got = append(got, d.Format(DateLayout))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences = %v，期望%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassSeriesOccurrenceOn(t *testing.T) {
+	// 美东时间2026年11月1日结束夏令时，上课的当地时间保持不变
+	series := ClassSeries{
+		Weekdays:        []int{0},
+		StartClock:      "09:30",
+		DurationMinutes: 75,
+		StartDate:       mustDate(t, "2026-10-01"),
+		Timezone:        "America/New_York",
+	}
+	if _, err := time.LoadLocation(series.Timezone); err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+
+	tests := []struct {
+		date      string
+		wantOK    bool
+		wantStart time.Time
+	}{
+		{"2026-10-25", true, time.Date(2026, 10, 25, 13, 30, 0, 0, time.UTC)},
+		{"2026-11-01", true, time.Date(2026, 11, 1, 14, 30, 0, 0, time.UTC)},
+		{"2026-10-26", false, time.Time{}},
+		{"2026-09-27", false, time.Time{}},
+	}
+	for _, tt := range tests {
+		start, end, ok := series.OccurrenceOn(mustDate(t, tt.date))
+		if ok != tt.wantOK {
+			t.Errorf("OccurrenceOn(%s) ok = %v，期望%v", tt.date, ok, tt.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !start.Equal(tt.wantStart) {
+			t.Errorf("OccurrenceOn(%s) start = %v，期望%v", tt.date, start.UTC(), tt.wantStart)
+		}
+		if d := end.Sub(start); d != 75*time.Minute {
+			t.Errorf("OccurrenceOn(%s) 时长为%v，期望75分钟", tt.date, d)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package instructor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+)
+
+// Instructor 教练档案，课程通过instructor_id关联
+type Instructor struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         *uuid.UUID `json:"user_id,omitempty"` // 关联的教练账号（users.id），教练本人可维护自己的档案
+	Name           string     `json:"name"`
+	Bio            string     `json:"bio"`                                   // 个人简介
+	PhotoPath      string     `json:"-"`                                     // 照片在对象存储中的路径
+	PhotoURL       string     `json:"photo_url,omitempty" gorm:"-"`          // 照片访问地址
+	Specialties    []string   `json:"specialties" gorm:"serializer:json"`    // 擅长的课程类型，如阴瑜伽、流瑜伽
+	Certifications []string   `json:"certifications" gorm:"serializer:json"` // 资质证书，如RYT-200
+	Active         bool       `json:"active"`                                // 是否在职，离职的教练不能再排课
+	Rating         *Rating    `json:"rating,omitempty" gorm:"-"`             // 所授课程的评价汇总
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Rating 评价汇总
+type Rating struct {
+	Average float64 `json:"average"` // 平均评分，保留一位小数，没有评价时为0
+	Count   int     `json:"count"`   // 评价数
+}
+
+// TableName 表名
+func (Instructor) TableName() string {
+	return "instructors"
+}
+
+// Validate 校验教练档案
+func (i *Instructor) Validate() error {
+	i.Name = strings.TrimSpace(i.Name)
+	if i.Name == "" {
+		return ErrInvalidInstructor
+	}
+	if i.Specialties == nil {
+		i.Specialties = []string{}
+	}
+	if i.Certifications == nil {
+		i.Certifications = []string{}
+	}
+	return nil
+}
+
+// ManageableBy 用户是否可以维护该档案：管理员可维护全部档案，教练只能维护关联到本人账号的档案
+func (i *Instructor) ManageableBy(u *user.User) bool {
+	if u.IsAdmin() {
+		return true
+	}
+	return u.Role == user.RoleInstructor && i.UserID != nil && *i.UserID == u.ID
+}
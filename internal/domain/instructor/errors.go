@@ -0,0 +1,14 @@
+package instructor
+
+import "errors"
+
+var (
+	// ErrInvalidInstructor 教练档案无效
+	ErrInvalidInstructor = errors.New("无效的教练档案：姓名不能为空")
+	// ErrInstructorNotFound 教练不存在
+	ErrInstructorNotFound = errors.New("教练不存在")
+	// ErrInstructorInactive 教练已离职，不能再排课
+	ErrInstructorInactive = errors.New("教练已离职，不能安排课程")
+	// ErrInvalidPhoto 照片格式或大小不符合要求
+	ErrInvalidPhoto = errors.New("照片须为不超过5MB的图片")
+)
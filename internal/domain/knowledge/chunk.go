@@ -0,0 +1,21 @@
+package knowledge
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KnowledgeChunk 知识项分块实体，每个分块对应向量库中的一个向量
+type KnowledgeChunk struct {
+	ID              uuid.UUID `json:"id"`
+	KnowledgeItemID uuid.UUID `json:"knowledge_item_id"`
+	KnowledgeBaseID uuid.UUID `json:"knowledge_base_id"`
+	ChunkIndex      int       `json:"chunk_index"`
+	Heading         string    `json:"heading,omitempty"` // 所属Markdown标题路径
+	Content         string    `json:"content"`
+	StartOffset     int       `json:"start_offset"` // 在知识项内容中的起始字符偏移
+	EndOffset       int       `json:"end_offset"`
+	VectorID        string    `json:"vector_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
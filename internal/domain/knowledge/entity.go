@@ -18,23 +18,32 @@ type KnowledgeBase struct {
 
 // KnowledgeItem 知识项实体
 type KnowledgeItem struct {
-	ID              uuid.UUID              `json:"id"`
-	KnowledgeBaseID uuid.UUID              `json:"knowledge_base_id"`
-	Title           string                 `json:"title"`
-	Content         string                 `json:"content,omitempty"`
-	ContentType     string                 `json:"content_type"` // 'text', 'image', 'video'
-	FilePath        string                 `json:"file_path,omitempty"`
-	FileSize        int64                  `json:"file_size,omitempty"`
-	MimeType        string                 `json:"mime_type,omitempty"`
-	Metadata        map[string]interface{} `json:"metadata,omitempty"`
-	VectorID        string                 `json:"vector_id,omitempty"`
-	EmbeddingStatus string                 `json:"embedding_status"` // 'pending', 'processing', 'completed', 'failed'
-	CreatedAt       time.Time              `json:"created_at"`
-	UpdatedAt       time.Time              `json:"updated_at"`
+	ID                uuid.UUID              `json:"id"`
+	KnowledgeBaseID   uuid.UUID              `json:"knowledge_base_id"`
+	Title             string                 `json:"title"`
+	Content           string                 `json:"content,omitempty"`
+	ContentType       string                 `json:"content_type"` // 'text', 'image', 'video'
+	FilePath          string                 `json:"file_path,omitempty"`
+	FileSize          int64                  `json:"file_size,omitempty"`
+	MimeType          string                 `json:"mime_type,omitempty"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+	VectorID          string                 `json:"vector_id,omitempty"`
+	EmbeddingStatus   string                 `json:"embedding_status"` // 'pending', 'processing', 'completed', 'failed'
+	EmbeddingAttempts int                    `json:"embedding_attempts"`
+	EmbeddingError    string                 `json:"embedding_error,omitempty"`
+	NextAttemptAt     *time.Time             `json:"next_attempt_at,omitempty"` // 下次重试时间
+	LeaseExpiresAt    *time.Time             `json:"-"`                         // 处理租约到期时间，过期后可被其他worker回收
+	LeaseToken        *uuid.UUID             `json:"-"`                         // 处理租约令牌，每次领取重新生成，只有持有者可以续租和更新状态
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// IsDeadLettered 检查是否已超过最大重试次数（死信）
+func (ki *KnowledgeItem) IsDeadLettered() bool {
+	return ki.EmbeddingStatus == "failed"
 }
 
 // IsEmbedded 检查是否已完成向量化
 func (ki *KnowledgeItem) IsEmbedded() bool {
 	return ki.EmbeddingStatus == "completed" && ki.VectorID != ""
 }
-
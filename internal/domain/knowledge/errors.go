@@ -0,0 +1,9 @@
+package knowledge
+
+import "errors"
+
+// ErrItemNotFound 知识项不存在
+var ErrItemNotFound = errors.New("知识项不存在")
+
+// ErrLeaseLost 向量化任务的租约已过期并被其他worker领取，或任务已不存在
+var ErrLeaseLost = errors.New("向量化任务租约已失效")
@@ -0,0 +1,69 @@
+package location
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Location 场馆（门店）
+type Location struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`    // 场馆名称，如静安店
+	Address   string    `json:"address"` // 地址
+	Rooms     []*Room   `json:"rooms,omitempty" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (Location) TableName() string {
+	return "locations"
+}
+
+// Validate 校验场馆信息
+func (l *Location) Validate() error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return ErrInvalidLocation
+	}
+	return nil
+}
+
+// Room 场馆内的教室
+type Room struct {
+	ID         uuid.UUID `json:"id"`
+	LocationID uuid.UUID `json:"location_id"`
+	Name       string    `json:"name"`
+	Capacity   int       `json:"capacity"`                         // 最多容纳人数，课程容量不能超过该值
+	Equipment  []string  `json:"equipment" gorm:"serializer:json"` // 设备清单，如瑜伽垫、瑜伽砖、空中吊床
+	Location   *Location `json:"location,omitempty" gorm:"-"`      // 所属场馆，随课程返回时填写
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (Room) TableName() string {
+	return "rooms"
+}
+
+// Validate 校验教室信息
+func (r *Room) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || r.Capacity <= 0 {
+		return ErrInvalidRoom
+	}
+	if r.Equipment == nil {
+		r.Equipment = []string{}
+	}
+	return nil
+}
+
+// Label 教室的完整名称，如“静安店 大教室”，未填写场馆时只返回教室名称
+func (r *Room) Label() string {
+	if r.Location == nil {
+		return r.Name
+	}
+	return r.Location.Name + " " + r.Name
+}
@@ -0,0 +1,18 @@
+package location
+
+import "errors"
+
+var (
+	// ErrInvalidLocation 场馆信息无效
+	ErrInvalidLocation = errors.New("无效的场馆：名称不能为空")
+	// ErrInvalidRoom 教室信息无效
+	ErrInvalidRoom = errors.New("无效的教室：名称不能为空，容量须大于0")
+	// ErrLocationNotFound 场馆不存在
+	ErrLocationNotFound = errors.New("场馆不存在")
+	// ErrRoomNotFound 教室不存在
+	ErrRoomNotFound = errors.New("教室不存在")
+	// ErrLocationExists 场馆名称已存在
+	ErrLocationExists = errors.New("场馆名称已存在")
+	// ErrRoomExists 场馆内已有同名教室
+	ErrRoomExists = errors.New("该场馆已有同名教室")
+)
@@ -0,0 +1,156 @@
+package membership
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PlanKind 会员卡类型
+type PlanKind string
+
+const (
+	// KindUnlimited 期限卡：有效期内不限次数
+	KindUnlimited PlanKind = "unlimited"
+	// KindClassPack 次卡：有效期内可上固定节数
+	KindClassPack PlanKind = "class_pack"
+	// KindDropIn 单次卡：一节课
+	KindDropIn PlanKind = "drop_in"
+)
+
+// Valid 是否为已定义的类型
+func (k PlanKind) Valid() bool {
+	switch k {
+	case KindUnlimited, KindClassPack, KindDropIn:
+		return true
+	}
+	return false
+}
+
+// Plan 会员卡方案（在售的卡种）
+type Plan struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Kind         PlanKind  `json:"kind"`
+	Credits      *int      `json:"credits,omitempty"` // 可用节数，期限卡为空
+	ValidityDays int       `json:"validity_days"`     // 开卡后的有效天数
+	PriceCents   int       `json:"price_cents"`       // 售价（分）
+	Active       bool      `json:"active"`            // 是否在售
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Plan) TableName() string {
+	return "membership_plans"
+}
+
+// Validate 校验方案：期限卡不设节数，次卡与单次卡节数必须大于0（单次卡为1）
+func (p *Plan) Validate() error {
+	if !p.Kind.Valid() || p.ValidityDays <= 0 || p.PriceCents < 0 {
+		return ErrInvalidPlan
+	}
+	switch p.Kind {
+	case KindUnlimited:
+		if p.Credits != nil {
+			return ErrInvalidPlan
+		}
+	case KindDropIn:
+		if p.Credits == nil || *p.Credits != 1 {
+			return ErrInvalidPlan
+		}
+	default:
+		if p.Credits == nil || *p.Credits <= 0 {
+			return ErrInvalidPlan
+		}
+	}
+	return nil
+}
+
+// 会员卡状态
+const (
+	StatusActive    = "active"
+	StatusFrozen    = "frozen"
+	StatusCancelled = "cancelled"
+)
+
+// Subscription 会员持有的会员卡
+type Subscription struct {
+	ID               uuid.UUID  `json:"id"`
+	UserID           string     `json:"user_id"` // 用户ID（users.id）
+	PlanID           uuid.UUID  `json:"plan_id"`
+	PlanName         string     `json:"plan_name"`
+	Kind             PlanKind   `json:"kind"`
+	Status           string     `json:"status"` // 'active', 'frozen', 'cancelled'
+	StartsAt         time.Time  `json:"starts_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RemainingCredits *int       `json:"remaining_credits,omitempty"` // 剩余节数，期限卡为空
+	FrozenAt         *time.Time `json:"frozen_at,omitempty"`         // 冻结开始时间，解冻时按冻结时长顺延有效期
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// NewSubscription 按方案为用户开卡，有效期从startsAt起算
+func NewSubscription(plan *Plan, userID string, startsAt time.Time) *Subscription {
+	s := &Subscription{
+		ID:        uuid.New(),
+		UserID:    userID,
+		PlanID:    plan.ID,
+		PlanName:  plan.Name,
+		Kind:      plan.Kind,
+		Status:    StatusActive,
+		StartsAt:  startsAt,
+		ExpiresAt: startsAt.AddDate(0, 0, plan.ValidityDays),
+	}
+	if plan.Credits != nil {
+		credits := *plan.Credits
+		s.RemainingCredits = &credits
+	}
+	return s
+}
+
+// Unlimited 是否为不限次数的期限卡
+func (s *Subscription) Unlimited() bool {
+	return s.RemainingCredits == nil
+}
+
+// CoversAt 会员卡是否可用于在t上课的课程：未冻结、在有效期内且有剩余节数
+func (s *Subscription) CoversAt(t time.Time) bool {
+	if s.Status != StatusActive || t.Before(s.StartsAt) || !t.Before(s.ExpiresAt) {
+		return false
+	}
+	return s.Unlimited() || *s.RemainingCredits > 0
+}
+
+// 课时变动原因
+const (
+	ReasonPurchase   = "purchase"   // 开卡
+	ReasonBooking    = "booking"    // 预订扣除
+	ReasonRefund     = "refund"     // 取消预订退还
+	ReasonAdjustment = "adjustment" // 管理员调整
+)
+
+// LedgerEntry 课时流水，每次课时变动记录一条
+type LedgerEntry struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	UserID         string     `json:"user_id"`
+	BookingID      *uuid.UUID `json:"booking_id,omitempty"`
+	Delta          int        `json:"delta"`   // 变动节数，扣除为负
+	Balance        int        `json:"balance"` // 变动后的剩余节数
+	Reason         string     `json:"reason"`  // 'purchase', 'booking', 'refund', 'adjustment'
+	Note           string     `json:"note,omitempty"`
+	CreatedBy      string     `json:"created_by"` // 操作人的用户ID，系统操作为空
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (LedgerEntry) TableName() string {
+	return "credit_ledger"
+}
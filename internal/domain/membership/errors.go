@@ -0,0 +1,20 @@
+package membership
+
+import "errors"
+
+var (
+	// ErrInvalidPlan 会员卡方案无效
+	ErrInvalidPlan = errors.New("无效的会员卡方案：期限卡不设节数，次卡节数须大于0，单次卡为1节，有效天数须大于0")
+	// ErrPlanNotFound 会员卡方案不存在
+	ErrPlanNotFound = errors.New("会员卡方案不存在")
+	// ErrPlanInactive 会员卡方案已停售
+	ErrPlanInactive = errors.New("会员卡方案已停售")
+	// ErrSubscriptionNotFound 会员卡不存在
+	ErrSubscriptionNotFound = errors.New("会员卡不存在")
+	// ErrNotFreezable 只有使用中的会员卡可以冻结
+	ErrNotFreezable = errors.New("只有使用中的会员卡可以冻结")
+	// ErrNotFrozen 会员卡未冻结
+	ErrNotFrozen = errors.New("会员卡未冻结")
+	// ErrInvalidAdjustment 调整后剩余节数不能为负，期限卡不能调整节数
+	ErrInvalidAdjustment = errors.New("无效的课时调整")
+)
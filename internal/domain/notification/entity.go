@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"time"
+)
+
+// Category 通知类别，会员可按类别关闭通知
+type Category string
+
+const (
+	// CategoryReminder 上课提醒
+	CategoryReminder Category = "reminder"
+	// CategoryBooking 预订结果：预订成功、候补转正
+	CategoryBooking Category = "booking"
+	// CategoryClassChange 课程变动：课程取消、更换教练
+	CategoryClassChange Category = "class_change"
+)
+
+// 消息模板键
+const (
+	TemplateBookingConfirmed  = "booking_confirmed"
+	TemplateWaitlistPromoted  = "waitlist_promoted"
+	TemplateClassCancelled    = "class_cancelled"
+	TemplateInstructorChanged = "instructor_changed"
+	TemplateClassReminder     = "class_reminder"
+)
+
+// ErrInvalidContact 手机号或邮箱格式无效
+var ErrInvalidContact = errors.New("无效的手机号或邮箱")
+
+var phonePattern = regexp.MustCompile(`^1\d{10}$`)
+
+// Preference 会员的通知偏好与联系方式，未设置时全部类别默认开启
+type Preference struct {
+	UserID         string    `json:"user_id" gorm:"primaryKey"` // 用户ID（users.id）
+	Phone          string    `json:"phone"`                     // 接收短信的手机号，为空不发短信
+	Email          string    `json:"email"`                     // 接收邮件的邮箱，为空不发邮件
+	Reminders      bool      `json:"reminders"`                 // 上课提醒
+	BookingUpdates bool      `json:"booking_updates"`           // 预订成功、候补转正
+	ClassChanges   bool      `json:"class_changes"`             // 课程取消、更换教练
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Preference) TableName() string {
+	return "notification_preferences"
+}
+
+// DefaultPreference 用户未设置时的默认偏好：全部类别开启
+func DefaultPreference(userID string) *Preference {
+	return &Preference{
+		UserID:         userID,
+		Reminders:      true,
+		BookingUpdates: true,
+		ClassChanges:   true,
+	}
+}
+
+// Allows 是否接收该类别的通知
+func (p *Preference) Allows(c Category) bool {
+	switch c {
+	case CategoryReminder:
+		return p.Reminders
+	case CategoryBooking:
+		return p.BookingUpdates
+	case CategoryClassChange:
+		return p.ClassChanges
+	}
+	return false
+}
+
+// Validate 校验联系方式：手机号为11位中国大陆手机号，邮箱为合法地址，均可为空
+func (p *Preference) Validate() error {
+	if p.Phone != "" && !phonePattern.MatchString(p.Phone) {
+		return ErrInvalidContact
+	}
+	if p.Email != "" {
+		addr, err := mail.ParseAddress(p.Email)
+		if err != nil || addr.Address != p.Email {
+			return ErrInvalidContact
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package order
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 订单状态
+const (
+	StatusPending  = "pending"  // 待支付
+	StatusPaid     = "paid"     // 已支付并已开卡
+	StatusClosed   = "closed"   // 超时未支付，已关闭
+	StatusRefunded = "refunded" // 已退款，对应会员卡已作废
+)
+
+// transitions 订单状态机：允许的状态流转。
+// 关闭后仍可能收到支付成功通知（用户在关闭前一刻付款），此时按已支付处理。
+var transitions = map[string][]string{
+	StatusPending: {StatusPaid, StatusClosed},
+	StatusClosed:  {StatusPaid},
+	StatusPaid:    {StatusRefunded},
+}
+
+// CanTransition 是否允许从from流转到to
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order 购买会员卡方案的订单（次卡、期限卡、单次卡）
+type Order struct {
+	ID             uuid.UUID  `json:"id"`
+	OrderNo        string     `json:"order_no"` // 商户订单号（支付网关的out_trade_no）
+	UserID         string     `json:"user_id"`  // 用户ID（users.id）
+	PlanID         uuid.UUID  `json:"plan_id"`
+	Description    string     `json:"description"`  // 商品描述，取下单时的方案名称
+	AmountCents    int        `json:"amount_cents"` // 应付金额（分），取下单时的方案售价
+	Status         string     `json:"status"`       // 'pending', 'paid', 'closed', 'refunded'
+	PrepayID       string     `json:"-"`
+	TransactionID  string     `json:"transaction_id,omitempty"`  // 支付网关交易号
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"` // 支付成功后开出的会员卡
+	RefundNo       string     `json:"refund_no,omitempty"`       // 商户退款单号
+	ExpiresAt      time.Time  `json:"expires_at"`                // 支付截止时间
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+	RefundedAt     *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Order) TableName() string {
+	return "orders"
+}
+
+// NewOrderNo 生成商户订单号：下单时间加随机串，不超过微信支付要求的32位
+func NewOrderNo(now time.Time) string {
+	buf := make([]byte, 6)
+	_, _ = rand.Read(buf)
+	return "YG" + now.Format("20060102150405") + hex.EncodeToString(buf)
+}
+
+// RefundNoFor 订单的商户退款单号。每个订单只全额退款一次，
+// 退款单号由订单号派生，重复提交时支付网关不会重复退款。
+func RefundNoFor(orderNo string) string {
+	return "R" + orderNo
+}
@@ -0,0 +1,16 @@
+package order
+
+import "errors"
+
+var (
+	// ErrOrderNotFound 订单不存在
+	ErrOrderNotFound = errors.New("订单不存在")
+	// ErrInvalidTransition 订单当前状态不允许该操作
+	ErrInvalidTransition = errors.New("订单当前状态不允许该操作")
+	// ErrAmountMismatch 支付金额与订单金额不一致
+	ErrAmountMismatch = errors.New("支付金额与订单金额不一致")
+	// ErrFreePlan 免费方案无需支付，由管理员直接开卡
+	ErrFreePlan = errors.New("该方案无需支付")
+	// ErrSimulationUnavailable 当前支付网关不支持模拟付款（仅本地假网关支持）
+	ErrSimulationUnavailable = errors.New("当前支付网关不支持模拟付款")
+)
@@ -0,0 +1,36 @@
+package user
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrUnauthenticated 当前请求没有已登录用户
+	ErrUnauthenticated = errors.New("未登录或登录已过期")
+	// ErrForbidden 当前用户的角色无权执行该操作
+	ErrForbidden = errors.New("无权执行该操作")
+)
+
+// contextKey 用户身份在context中的键
+type contextKey struct{}
+
+// WithUser 将已认证用户注入context，供handler、service与MCP工具读取
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// FromContext 读取context中的已认证用户
+func FromContext(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(contextKey{}).(*User)
+	return u, ok && u != nil
+}
+
+// Require 读取已认证用户，未登录时返回ErrUnauthenticated
+func Require(ctx context.Context) (*User, error) {
+	u, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	return u, nil
+}
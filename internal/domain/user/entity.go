@@ -0,0 +1,29 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User 用户实体（微信小程序用户）
+type User struct {
+	ID            uuid.UUID  `json:"id"`
+	OpenID        string     `json:"-"` // 小程序openid
+	UnionID       string     `json:"-"` // 开放平台unionid（绑定开放平台后才有）
+	Nickname      string     `json:"nickname"`
+	AvatarURL     string     `json:"avatar_url"`
+	Role          Role       `json:"role"`
+	CalendarToken *string    `json:"-"` // 私有日历订阅地址中的令牌，未生成时为空
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// DisplayName 展示用名称，未设置昵称时使用默认值
+func (u *User) DisplayName() string {
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+	return "微信用户"
+}
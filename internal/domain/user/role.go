@@ -0,0 +1,45 @@
+package user
+
+// Role 用户角色
+type Role string
+
+const (
+	// RoleMember 会员：浏览知识库，管理自己的预订与评价
+	RoleMember Role = "member"
+	// RoleInstructor 教练：在会员权限之外管理自己授课的课程
+	RoleInstructor Role = "instructor"
+	// RoleAdmin 场馆管理员：管理知识库、全部课程与用户角色
+	RoleAdmin Role = "admin"
+)
+
+// Valid 是否为已定义的角色
+func (r Role) Valid() bool {
+	switch r {
+	case RoleMember, RoleInstructor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// HasRole 用户是否具有任一指定角色，管理员视为具有所有角色
+func (u *User) HasRole(roles ...Role) bool {
+	if u.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin 是否为场馆管理员
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsStaff 是否为工作人员（教练或管理员）
+func (u *User) IsStaff() bool {
+	return u.HasRole(RoleInstructor)
+}
@@ -2,7 +2,7 @@ package mcp
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
 	aiservice "github.com/yoga/knowledge-base/internal/service/ai"
 	"github.com/yoga/knowledge-base/pkg/mcp"
@@ -25,21 +25,21 @@ func NewService(server mcp.Server, logger *zap.Logger) *Service {
 
 // CallTool 调用工具
 func (s *Service) CallTool(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
-	result, err := s.server.CallTool(name, args)
+	result, err := s.server.CallTool(ctx, name, args)
 	if err != nil {
 		return nil, err
 	}
 
 	if !result.Success {
-		return nil, fmt.Errorf(result.Error)
+		return nil, errors.New(result.Error)
 	}
 
 	return result.Data, nil
 }
 
-// ListTools 列出所有工具
+// ListTools 列出调用方可用的工具
 func (s *Service) ListTools(ctx context.Context) ([]aiservice.Tool, error) {
-	mcpTools := s.server.ListTools()
+	mcpTools := mcp.VisibleTools(ctx, s.server.ListTools())
 
 	// 转换为AI服务的Tool格式
 	tools := make([]aiservice.Tool, len(mcpTools))
@@ -56,4 +56,3 @@ func (s *Service) ListTools(ctx context.Context) ([]aiservice.Tool, error) {
 
 	return tools, nil
 }
-
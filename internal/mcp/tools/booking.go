@@ -1,39 +1,51 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	bookingdomain "github.com/yoga/knowledge-base/internal/domain/booking"
 	"github.com/yoga/knowledge-base/internal/service/booking"
+	"github.com/yoga/knowledge-base/internal/service/location"
 	"github.com/yoga/knowledge-base/pkg/mcp"
 	"go.uber.org/zap"
 )
 
-// RegisterBookingTools 注册定课相关工具
-func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger *zap.Logger) {
+// RegisterScheduleTools 注册查询课程表工具，无需登录
+func RegisterScheduleTools(server mcp.Server, bookingSvc *booking.Service, locationSvc *location.Service, logger *zap.Logger) {
 	// 查询课程表工具
 	server.RegisterTool(mcp.Tool{
 		Name:        "query_schedule",
-		Description: "查询课程表，可以按日期范围查询",
+		Description: "查询课程表，可以按日期范围和场馆查询，结果包含上课的场馆、教室与地址",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"start_date": map[string]interface{}{
 					"type":        "string",
+					"format":      "date",
 					"description": "开始日期，格式：YYYY-MM-DD",
 				},
 				"end_date": map[string]interface{}{
 					"type":        "string",
+					"format":      "date",
 					"description": "结束日期，格式：YYYY-MM-DD",
 				},
+				"location": map[string]interface{}{
+					"type":        "string",
+					"description": "场馆名称，可以只写一部分，如“静安”；不填查询全部场馆",
+				},
 			},
 		},
-	}, func(args map[string]interface{}) (interface{}, error) {
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 		var startTime, endTime *time.Time
+		var locationID *uuid.UUID
 
+		// 日期按场馆时区解释
+		studioLoc := bookingSvc.Location()
 		if startDateStr, ok := args["start_date"].(string); ok && startDateStr != "" {
-			t, err := time.Parse("2006-01-02", startDateStr)
+			t, err := time.ParseInLocation(bookingdomain.DateLayout, startDateStr, studioLoc)
 			if err != nil {
 				return nil, fmt.Errorf("无效的开始日期格式: %w", err)
 			}
@@ -41,16 +53,24 @@ func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger
 		}
 
 		if endDateStr, ok := args["end_date"].(string); ok && endDateStr != "" {
-			t, err := time.Parse("2006-01-02", endDateStr)
+			t, err := time.ParseInLocation(bookingdomain.DateLayout, endDateStr, studioLoc)
 			if err != nil {
 				return nil, fmt.Errorf("无效的结束日期格式: %w", err)
 			}
-			// 设置为当天的23:59:59
-			t = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+			// 包含结束日期当天，截至次日零点
+			t = t.AddDate(0, 0, 1)
 			endTime = &t
 		}
 
-		classes, err := bookingSvc.ListClasses(nil, startTime, endTime, 50, 0)
+		if name, ok := args["location"].(string); ok && name != "" {
+			loc, err := locationSvc.MatchLocation(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("查找场馆失败: %w", err)
+			}
+			locationID = &loc.ID
+		}
+
+		classes, err := bookingSvc.ListClasses(ctx, startTime, endTime, locationID, 50, 0)
 		if err != nil {
 			return nil, fmt.Errorf("查询课程表失败: %w", err)
 		}
@@ -59,44 +79,48 @@ func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger
 		result := make([]map[string]interface{}, len(classes))
 		for i, class := range classes {
 			result[i] = map[string]interface{}{
-				"id":          class.ID.String(),
-				"name":        class.Name,
-				"description": class.Description,
-				"instructor":  class.Instructor,
-				"start_time":  class.StartTime.Format(time.RFC3339),
-				"end_time":    class.EndTime.Format(time.RFC3339),
-				"capacity":    class.Capacity,
+				"id":           class.ID.String(),
+				"name":         class.Name,
+				"description":  class.Description,
+				"instructor":   class.Instructor,
+				"start_time":   class.StartTime.Format(time.RFC3339),
+				"end_time":     class.EndTime.Format(time.RFC3339),
+				"capacity":     class.Capacity,
 				"booked_count": class.BookedCount,
-				"available":   class.IsAvailable(),
+				"available":    class.IsAvailable(),
+			}
+			if class.Room != nil {
+				result[i]["room"] = class.Room.Name
+				if class.Room.Location != nil {
+					result[i]["location"] = class.Room.Location.Name
+					result[i]["address"] = class.Room.Location.Address
+				}
 			}
 		}
 
 		return result, nil
 	})
+}
 
+// RegisterBookingTools 注册会员预订相关工具。
+// 工具从ctx中读取当前登录用户（user.FromContext），不接受模型传入的用户ID，没有用户身份的接入不应注册。
+func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger *zap.Logger) {
 	// 预订课程工具
 	server.RegisterTool(mcp.Tool{
 		Name:        "book_class",
-		Description: "预订课程",
+		Description: "为当前登录用户预订课程",
 		Parameters: map[string]interface{}{
-			"type": "object",
-			"required": []string{"class_id", "user_id"},
+			"type":     "object",
+			"required": []string{"class_id"},
 			"properties": map[string]interface{}{
 				"class_id": map[string]interface{}{
 					"type":        "string",
+					"format":      "uuid",
 					"description": "课程ID",
 				},
-				"user_id": map[string]interface{}{
-					"type":        "string",
-					"description": "用户ID（微信用户ID）",
-				},
-				"user_name": map[string]interface{}{
-					"type":        "string",
-					"description": "用户名称",
-				},
 			},
 		},
-	}, func(args map[string]interface{}) (interface{}, error) {
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 		classIDStr, ok := args["class_id"].(string)
 		if !ok {
 			return nil, fmt.Errorf("class_id 是必需的")
@@ -107,16 +131,9 @@ func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger
 			return nil, fmt.Errorf("无效的课程ID: %w", err)
 		}
 
-		userID, ok := args["user_id"].(string)
-		if !ok {
-			return nil, fmt.Errorf("user_id 是必需的")
-		}
-
-		userName, _ := args["user_name"].(string)
-
-		booking, err := bookingSvc.BookClass(nil, classID, userID, userName)
+		booking, err := bookingSvc.BookClass(ctx, classID)
 		if err != nil {
-			return nil, fmt.Errorf("预订课程失败: %w", err)
+			return nil, bookingToolError(fmt.Errorf("预订课程失败: %w", err))
 		}
 
 		return map[string]interface{}{
@@ -130,18 +147,25 @@ func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger
 	// 取消预订工具
 	server.RegisterTool(mcp.Tool{
 		Name:        "cancel_booking",
-		Description: "取消预订",
+		Description: "取消预订或退出候补。课程开始后不能取消；超过免费取消截止时间的取消记为迟取消，可能不退还课时",
 		Parameters: map[string]interface{}{
-			"type": "object",
+			"type":     "object",
 			"required": []string{"booking_id"},
 			"properties": map[string]interface{}{
 				"booking_id": map[string]interface{}{
 					"type":        "string",
+					"format":      "uuid",
 					"description": "预订ID",
 				},
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "取消原因",
+					"maxLength":   500,
+				},
 			},
 		},
-	}, func(args map[string]interface{}) (interface{}, error) {
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		reason, _ := args["reason"].(string)
 		bookingIDStr, ok := args["booking_id"].(string)
 		if !ok {
 			return nil, fmt.Errorf("booking_id 是必需的")
@@ -152,37 +176,76 @@ func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger
 			return nil, fmt.Errorf("无效的预订ID: %w", err)
 		}
 
-		if err := bookingSvc.CancelBooking(nil, bookingID); err != nil {
-			return nil, fmt.Errorf("取消预订失败: %w", err)
+		b, err := bookingSvc.CancelBooking(ctx, bookingID, reason)
+		if err != nil {
+			return nil, bookingToolError(fmt.Errorf("取消预订失败: %w", err))
 		}
 
+		message := "取消预订成功"
+		if b.LateCancel {
+			message = "取消预订成功（已过免费取消截止时间，记为迟取消）"
+		}
 		return map[string]interface{}{
-			"booking_id": bookingIDStr,
-			"message":    "取消预订成功",
+			"booking_id":       bookingIDStr,
+			"late_cancel":      b.LateCancel,
+			"credit_forfeited": b.CreditForfeited,
+			"message":          message,
 		}, nil
 	})
 
-	// 查询用户预订
+	// 加入候补名单
 	server.RegisterTool(mcp.Tool{
-		Name:        "query_user_bookings",
-		Description: "查询用户的预订列表",
+		Name:        "join_waitlist",
+		Description: "课程已满时以当前登录用户身份加入候补名单，有人取消后按顺序自动转为已预订",
 		Parameters: map[string]interface{}{
-			"type": "object",
-			"required": []string{"user_id"},
+			"type":     "object",
+			"required": []string{"class_id"},
 			"properties": map[string]interface{}{
-				"user_id": map[string]interface{}{
+				"class_id": map[string]interface{}{
 					"type":        "string",
-					"description": "用户ID（微信用户ID）",
+					"format":      "uuid",
+					"description": "课程ID",
 				},
 			},
 		},
-	}, func(args map[string]interface{}) (interface{}, error) {
-		userID, ok := args["user_id"].(string)
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		classIDStr, ok := args["class_id"].(string)
 		if !ok {
-			return nil, fmt.Errorf("user_id 是必需的")
+			return nil, fmt.Errorf("class_id 是必需的")
 		}
 
-		bookings, err := bookingSvc.ListUserBookings(nil, userID, 50, 0)
+		classID, err := uuid.Parse(classIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的课程ID: %w", err)
+		}
+
+		b, err := bookingSvc.JoinWaitlist(ctx, classID)
+		if err != nil {
+			return nil, bookingToolError(err)
+		}
+
+		result := map[string]interface{}{
+			"booking_id": b.ID.String(),
+			"status":     b.Status,
+			"message":    "已加入候补名单",
+		}
+		if b.WaitlistPosition != nil {
+			result["waitlist_position"] = *b.WaitlistPosition
+			result["message"] = fmt.Sprintf("已加入候补名单，当前排在第%d位", *b.WaitlistPosition)
+		}
+		return result, nil
+	})
+
+	// 查询用户预订
+	server.RegisterTool(mcp.Tool{
+		Name:        "query_user_bookings",
+		Description: "查询当前登录用户的预订列表",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		bookings, err := bookingSvc.ListUserBookings(ctx, 50, 0)
 		if err != nil {
 			return nil, fmt.Errorf("查询用户预订失败: %w", err)
 		}
@@ -201,3 +264,10 @@ func RegisterBookingTools(server mcp.Server, bookingSvc *booking.Service, logger
 	})
 }
 
+// bookingToolError 预订业务错误附带错误码返回，便于调用方区分处理
+func bookingToolError(err error) error {
+	if code := bookingdomain.ErrorCode(err); code != "" {
+		return &mcp.ToolError{Code: code, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	aiservice "github.com/yoga/knowledge-base/internal/service/ai"
+	"github.com/yoga/knowledge-base/internal/service/knowledge"
+	"github.com/yoga/knowledge-base/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// maxItemContentLength get_knowledge_item返回正文的最大字符数
+const maxItemContentLength = 4000
+
+// RegisterKnowledgeTools 注册知识库相关工具
+func RegisterKnowledgeTools(server mcp.Server, kbSvc *knowledge.Service, retriever aiservice.Retriever, logger *zap.Logger) {
+	// 检索知识库
+	server.RegisterTool(mcp.Tool{
+		Name:        "search_knowledge",
+		Description: "在瑜伽知识库中检索与问题相关的内容，可限定知识库",
+		Parameters: map[string]interface{}{
+			"type":     "object",
+			"required": []string{"query"},
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "检索问题或关键词",
+				},
+				"knowledge_base_id": map[string]interface{}{
+					"type":        "string",
+					"format":      "uuid",
+					"description": "知识库ID，不传则检索全部知识库",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "返回结果数量",
+					"minimum":     1,
+					"maximum":     10,
+					"default":     5,
+				},
+			},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		query, _ := args["query"].(string)
+		baseID, _ := args["knowledge_base_id"].(string)
+		limit, _ := args["limit"].(int)
+
+		sources, err := retriever.Search(ctx, query, limit, baseID)
+		if err != nil {
+			return nil, fmt.Errorf("检索知识库失败: %w", err)
+		}
+
+		result := make([]map[string]interface{}, len(sources))
+		for i, source := range sources {
+			result[i] = map[string]interface{}{
+				"item_id": source.ID,
+				"title":   source.Title,
+				"content": source.Content,
+				"score":   source.Score,
+			}
+		}
+
+		return result, nil
+	})
+
+	// 获取知识项详情
+	server.RegisterTool(mcp.Tool{
+		Name:        "get_knowledge_item",
+		Description: "获取知识项的完整内容",
+		Parameters: map[string]interface{}{
+			"type":     "object",
+			"required": []string{"item_id"},
+			"properties": map[string]interface{}{
+				"item_id": map[string]interface{}{
+					"type":        "string",
+					"format":      "uuid",
+					"description": "知识项ID（search_knowledge结果中的item_id）",
+				},
+			},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		itemID, err := uuid.Parse(args["item_id"].(string))
+		if err != nil {
+			return nil, fmt.Errorf("无效的知识项ID: %w", err)
+		}
+
+		item, err := kbSvc.GetItem(ctx, itemID)
+		if err != nil {
+			return nil, fmt.Errorf("获取知识项失败: %w", err)
+		}
+
+		content := []rune(item.Content)
+		truncated := len(content) > maxItemContentLength
+		if truncated {
+			content = content[:maxItemContentLength]
+		}
+
+		return map[string]interface{}{
+			"item_id":           item.ID.String(),
+			"knowledge_base_id": item.KnowledgeBaseID.String(),
+			"title":             item.Title,
+			"content_type":      item.ContentType,
+			"content":           string(content),
+			"truncated":         truncated,
+			"embedding_status":  item.EmbeddingStatus,
+			"created_at":        item.CreatedAt.Format(time.RFC3339),
+		}, nil
+	})
+
+	// 列出知识库
+	server.RegisterTool(mcp.Tool{
+		Name:        "list_knowledge_bases",
+		Description: "列出所有知识库",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "返回数量",
+					"minimum":     1,
+					"maximum":     100,
+					"default":     20,
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "偏移量",
+					"minimum":     0,
+					"default":     0,
+				},
+			},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		limit, _ := args["limit"].(int)
+		offset, _ := args["offset"].(int)
+
+		bases, err := kbSvc.ListBases(ctx, limit, offset)
+		if err != nil {
+			return nil, fmt.Errorf("查询知识库列表失败: %w", err)
+		}
+
+		result := make([]map[string]interface{}, len(bases))
+		for i, base := range bases {
+			result[i] = map[string]interface{}{
+				"knowledge_base_id": base.ID.String(),
+				"name":              base.Name,
+				"description":       base.Description,
+				"type":              base.Type,
+			}
+		}
+
+		return result, nil
+	})
+
+	// 添加文本知识项（仅管理员）
+	server.RegisterTool(mcp.Tool{
+		Name:        "add_text_item",
+		Description: "向知识库添加一条文本知识，添加后会在后台完成向量化",
+		AdminOnly:   true,
+		Parameters: map[string]interface{}{
+			"type":     "object",
+			"required": []string{"knowledge_base_id", "title", "content"},
+			"properties": map[string]interface{}{
+				"knowledge_base_id": map[string]interface{}{
+					"type":        "string",
+					"format":      "uuid",
+					"description": "知识库ID",
+				},
+				"title": map[string]interface{}{
+					"type":        "string",
+					"description": "标题",
+					"maxLength":   200,
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "正文内容",
+				},
+			},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		baseID, err := uuid.Parse(args["knowledge_base_id"].(string))
+		if err != nil {
+			return nil, fmt.Errorf("无效的知识库ID: %w", err)
+		}
+
+		if _, err := kbSvc.GetBase(ctx, baseID); err != nil {
+			return nil, fmt.Errorf("知识库不存在: %w", err)
+		}
+
+		item, err := kbSvc.CreateTextItem(ctx, baseID, args["title"].(string), args["content"].(string))
+		if err != nil {
+			return nil, fmt.Errorf("添加知识项失败: %w", err)
+		}
+
+		logger.Info("通过MCP添加知识项", zap.String("item_id", item.ID.String()), zap.String("knowledge_base_id", baseID.String()))
+
+		return map[string]interface{}{
+			"item_id":          item.ID.String(),
+			"embedding_status": item.EmbeddingStatus,
+			"message":          "添加成功，向量化完成后即可被检索",
+		}, nil
+	})
+}
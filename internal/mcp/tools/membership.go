@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yoga/knowledge-base/internal/service/membership"
+	"github.com/yoga/knowledge-base/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// RegisterMembershipTools 注册会员卡相关工具，以当前登录用户身份查询
+func RegisterMembershipTools(server mcp.Server, membershipSvc *membership.Service, logger *zap.Logger) {
+	// 查询用户会员卡
+	server.RegisterTool(mcp.Tool{
+		Name:        "query_user_memberships",
+		Description: "查询当前登录用户的会员卡：类型、状态、有效期和剩余课时",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		subs, err := membershipSvc.ListMySubscriptions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("查询会员卡失败: %w", err)
+		}
+
+		result := make([]map[string]interface{}, len(subs))
+		for i, sub := range subs {
+			item := map[string]interface{}{
+				"subscription_id": sub.ID.String(),
+				"plan_name":       sub.PlanName,
+				"kind":            sub.Kind,
+				"status":          sub.Status,
+				"starts_at":       sub.StartsAt.Format(time.RFC3339),
+				"expires_at":      sub.ExpiresAt.Format(time.RFC3339),
+			}
+			if sub.RemainingCredits != nil {
+				item["remaining_credits"] = *sub.RemainingCredits
+			}
+			result[i] = item
+		}
+
+		return result, nil
+	})
+}
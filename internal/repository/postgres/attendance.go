@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBookingByCheckInToken 根据签到码获取预订
+func (r *BookingRepository) GetBookingByCheckInToken(ctx context.Context, token string) (*booking.Booking, error) {
+	var b booking.Booking
+	if err := r.db.WithContext(ctx).Where("check_in_token = ?", token).First(&b).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, booking.ErrInvalidCheckInToken
+		}
+		return nil, fmt.Errorf("查询预订失败: %w", err)
+	}
+	return &b, nil
+}
+
+// CheckIn 为预订签到。锁定预订行后校验状态，同一预订只记录一次签到。
+func (r *BookingRepository) CheckIn(ctx context.Context, a *booking.Attendance) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b booking.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", a.BookingID).First(&b).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return booking.ErrBookingNotFound
+			}
+			return fmt.Errorf("查询预订失败: %w", err)
+		}
+		if b.Status != "confirmed" {
+			return booking.ErrBookingNotCheckable
+		}
+
+		var count int64
+		if err := tx.Model(&booking.Attendance{}).Where("booking_id = ?", a.BookingID).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询签到记录失败: %w", err)
+		}
+		if count > 0 {
+			return booking.ErrAlreadyCheckedIn
+		}
+
+		a.ClassID = b.ClassID
+		a.UserID = b.UserID
+		if err := tx.Create(a).Error; err != nil {
+			return fmt.Errorf("签到失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListAttendance 列出课程的点名表：已确认、已完成和爽约的预订及其签到情况
+func (r *BookingRepository) ListAttendance(ctx context.Context, classID uuid.UUID) ([]*booking.RosterEntry, error) {
+	var entries []*booking.RosterEntry
+	if err := r.db.WithContext(ctx).
+		Table("bookings").
+		Select(`bookings.id AS booking_id, bookings.user_id, bookings.user_name, bookings.status,
+			attendances.id IS NOT NULL AS checked_in, attendances.checked_in_at, COALESCE(attendances.method, '') AS method`).
+		Joins("LEFT JOIN attendances ON attendances.booking_id = bookings.id").
+		Where("bookings.class_id = ? AND bookings.status IN ?", classID, []string{"confirmed", "completed", "no_show"}).
+		Order("bookings.created_at ASC").
+		Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("查询点名表失败: %w", err)
+	}
+	return entries, nil
+}
@@ -2,12 +2,15 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/location"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // BookingRepository 预订仓储接口实现
@@ -30,6 +33,9 @@ func (r *BookingRepository) CreateClass(ctx context.Context, class *booking.Clas
 	class.UpdatedAt = now
 
 	if err := r.db.WithContext(ctx).Create(class).Error; err != nil {
+		if conflictErr := classOverlapError(err); conflictErr != nil {
+			return conflictErr
+		}
 		return fmt.Errorf("创建课程失败: %w", err)
 	}
 	return nil
@@ -47,8 +53,8 @@ func (r *BookingRepository) GetClass(ctx context.Context, id uuid.UUID) (*bookin
 	return &class, nil
 }
 
-// ListClasses 列出课程
-func (r *BookingRepository) ListClasses(ctx context.Context, startTime, endTime *time.Time, limit, offset int) ([]*booking.Class, error) {
+// ListClasses 列出课程，locationID非空时只列出该场馆教室内的课程
+func (r *BookingRepository) ListClasses(ctx context.Context, startTime, endTime *time.Time, locationID *uuid.UUID, limit, offset int) ([]*booking.Class, error) {
 	var classes []*booking.Class
 	query := r.db.WithContext(ctx)
 	
@@ -58,6 +64,9 @@ func (r *BookingRepository) ListClasses(ctx context.Context, startTime, endTime
 	if endTime != nil {
 		query = query.Where("end_time <= ?", *endTime)
 	}
+	if locationID != nil {
+		query = query.Where("room_id IN (?)", r.db.Model(&location.Room{}).Select("id").Where("location_id = ?", *locationID))
+	}
 	
 	if err := query.Order("start_time ASC").Limit(limit).Offset(offset).Find(&classes).Error; err != nil {
 		return nil, fmt.Errorf("查询课程列表失败: %w", err)
@@ -65,62 +74,290 @@ func (r *BookingRepository) ListClasses(ctx context.Context, startTime, endTime
 	return classes, nil
 }
 
-// UpdateClass 更新课程
+// UpdateClass 更新课程信息。在事务内锁定课程行，以最新的已预订人数校验容量，
+// 并保留预订数量与状态，避免覆盖并发预订带来的变化。
 func (r *BookingRepository) UpdateClass(ctx context.Context, class *booking.Class) error {
-	class.UpdatedAt = time.Now()
-	if err := r.db.WithContext(ctx).Save(class).Error; err != nil {
-		return fmt.Errorf("更新课程失败: %w", err)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current booking.Class
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", class.ID).First(&current).Error; err != nil {
+			return fmt.Errorf("查询课程失败: %w", err)
+		}
+
+		if current.Status != "scheduled" {
+			return booking.ErrClassNotEditable
+		}
+		if class.Capacity < current.BookedCount {
+			return booking.ErrCapacityBelowBooked
+		}
+
+		class.BookedCount = current.BookedCount
+		class.Status = current.Status
+		class.UpdatedAt = time.Now()
+		if err := tx.Omit("booked_count", "status", "created_at").Save(class).Error; err != nil {
+			if conflictErr := classOverlapError(err); conflictErr != nil {
+				return conflictErr
+			}
+			return fmt.Errorf("更新课程失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// CancelClass 取消课程，并在同一事务内取消该课程下所有已确认和候补中的预订，返回被取消的预订
+func (r *BookingRepository) CancelClass(ctx context.Context, id uuid.UUID, cancelledBy, reason string) ([]*booking.Booking, error) {
+	var cancelled []*booking.Booking
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var class booking.Class
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&class).Error; err != nil {
+			return fmt.Errorf("查询课程失败: %w", err)
+		}
+		if class.Status != "scheduled" {
+			return booking.ErrClassNotEditable
+		}
+
+		var err error
+		cancelled, err = cancelClass(tx, &class, cancelledBy, reason)
+		return err
+	})
+	return cancelled, err
+}
+
+// cancelClass 在事务内取消已锁定的课程及其已确认和候补中的预订，已确认预订扣除的课时全部退还。
+// 返回被取消的预订（取消前的状态），供调用方通知相关会员。
+func cancelClass(tx *gorm.DB, class *booking.Class, cancelledBy, reason string) ([]*booking.Booking, error) {
+	var active []*booking.Booking
+	if err := tx.Where("class_id = ? AND status IN ?", class.ID, []string{"confirmed", "waitlisted"}).
+		Find(&active).Error; err != nil {
+		return nil, fmt.Errorf("查询课程预订失败: %w", err)
 	}
-	return nil
+	for _, b := range active {
+		if b.Status != "confirmed" {
+			continue
+		}
+		if err := refundCredit(tx, b, "课程取消", cancelledBy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Model(&booking.Booking{}).
+		Where("class_id = ? AND status IN ?", class.ID, []string{"confirmed", "waitlisted"}).
+		Updates(map[string]interface{}{
+			"status":            "cancelled",
+			"waitlist_position": nil,
+			"cancelled_by":      cancelledBy,
+			"cancel_reason":     reason,
+			"cancelled_at":      time.Now(),
+		}).Error; err != nil {
+		return nil, fmt.Errorf("取消课程预订失败: %w", err)
+	}
+
+	if err := tx.Model(class).Updates(map[string]interface{}{
+		"status":       "cancelled",
+		"booked_count": 0,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("取消课程失败: %w", err)
+	}
+	return active, nil
+}
+
+// CompleteClass 将课程标记为已完成，并在同一事务内结算预订：已签到的预订标记为已完成，
+// 未签到的已确认预订标记为爽约。返回已完成与爽约的预订数。
+func (r *BookingRepository) CompleteClass(ctx context.Context, id uuid.UUID, forfeitCredit bool) (attended, noShows int, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&booking.Class{}).
+			Where("id = ? AND status = ?", id, "scheduled").
+			Update("status", "completed")
+		if result.Error != nil {
+			return fmt.Errorf("更新课程状态失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return booking.ErrClassNotEditable
+		}
+
+		checkedIn := tx.Model(&booking.Attendance{}).Select("booking_id").Where("class_id = ?", id)
+		result = tx.Model(&booking.Booking{}).
+			Where("class_id = ? AND status = ? AND id IN (?)", id, "confirmed", checkedIn).
+			Update("status", "completed")
+		if result.Error != nil {
+			return fmt.Errorf("更新预订状态失败: %w", result.Error)
+		}
+		attended = int(result.RowsAffected)
+
+		// 场馆不没收爽约课时时退还
+		if !forfeitCredit {
+			var absent []*booking.Booking
+			if err := tx.Where("class_id = ? AND status = ?", id, "confirmed").Find(&absent).Error; err != nil {
+				return fmt.Errorf("查询课程预订失败: %w", err)
+			}
+			for _, b := range absent {
+				if err := refundCredit(tx, b, "爽约", ""); err != nil {
+					return err
+				}
+			}
+		}
+
+		result = tx.Model(&booking.Booking{}).
+			Where("class_id = ? AND status = ?", id, "confirmed").
+			Updates(map[string]interface{}{
+				"status":           "no_show",
+				"credit_forfeited": forfeitCredit,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("更新预订状态失败: %w", result.Error)
+		}
+		noShows = int(result.RowsAffected)
+		return nil
+	})
+	return attended, noShows, err
+}
+
+// ListEndedClasses 列出在before之前已下课但仍未结束的课程，按下课时间排序
+func (r *BookingRepository) ListEndedClasses(ctx context.Context, before time.Time, limit int) ([]*booking.Class, error) {
+	var classes []*booking.Class
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND end_time <= ?", "scheduled", before).
+		Order("end_time ASC").
+		Limit(limit).
+		Find(&classes).Error; err != nil {
+		return nil, fmt.Errorf("查询已下课的课程失败: %w", err)
+	}
+	return classes, nil
+}
+
+// FindInstructorConflict 查找同一授课教练时间重叠的未取消课程，没有冲突时返回nil。
+// 课程关联了教练档案或教练用户时按档案ID与用户ID判断，否则按教练名称判断。
+func (r *BookingRepository) FindInstructorConflict(ctx context.Context, class *booking.Class) (*booking.Class, error) {
+	return findInstructorConflict(r.db.WithContext(ctx), class)
+}
+
+// findInstructorConflict 在给定连接（或事务）上查找教练时间冲突
+func findInstructorConflict(db *gorm.DB, class *booking.Class) (*booking.Class, error) {
+	query := db.
+		Where("id <> ? AND status = ?", class.ID, "scheduled").
+		Where("start_time < ? AND end_time > ?", class.EndTime, class.StartTime)
+
+	switch {
+	case class.InstructorID != nil && class.InstructorUserID != nil:
+		query = query.Where("(instructor_id = ? OR instructor_user_id = ?)", *class.InstructorID, *class.InstructorUserID)
+	case class.InstructorID != nil:
+		query = query.Where("instructor_id = ?", *class.InstructorID)
+	case class.InstructorUserID != nil:
+		query = query.Where("instructor_user_id = ?", *class.InstructorUserID)
+	case class.Instructor != "":
+		query = query.Where("instructor = ?", class.Instructor)
+	default:
+		return nil, nil
+	}
+
+	var conflict booking.Class
+	if err := query.Order("start_time ASC").First(&conflict).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询教练课程冲突失败: %w", err)
+	}
+	return &conflict, nil
 }
 
-// CreateBooking 创建预订
-func (r *BookingRepository) CreateBooking(ctx context.Context, b *booking.Booking) error {
+// FindRoomConflict 查找同一教室时间重叠的未取消课程，课程未安排教室或没有冲突时返回nil
+func (r *BookingRepository) FindRoomConflict(ctx context.Context, class *booking.Class) (*booking.Class, error) {
+	return findRoomConflict(r.db.WithContext(ctx), class)
+}
+
+// findRoomConflict 在给定连接（或事务）上查找教室时间冲突
+func findRoomConflict(db *gorm.DB, class *booking.Class) (*booking.Class, error) {
+	if class.RoomID == nil {
+		return nil, nil
+	}
+
+	var conflict booking.Class
+	if err := db.
+		Where("id <> ? AND status = ? AND room_id = ?", class.ID, "scheduled", *class.RoomID).
+		Where("start_time < ? AND end_time > ?", class.EndTime, class.StartTime).
+		Order("start_time ASC").
+		First(&conflict).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询教室课程冲突失败: %w", err)
+	}
+	return &conflict, nil
+}
+
+// CreateBooking 创建预订。requireCredit为true时在同一事务内从会员卡扣除课时，没有可用会员卡返回booking.ErrNoCredits。
+// 名额以条件更新占用，并发预订最后一个名额时只有一个成功，其余返回booking.ErrClassFull。
+func (r *BookingRepository) CreateBooking(ctx context.Context, b *booking.Booking, requireCredit bool) error {
 	if b.ID == uuid.Nil {
 		b.ID = uuid.New()
 	}
+	if b.CheckInToken == "" {
+		b.CheckInToken = booking.NewCheckInToken()
+	}
 	now := time.Now()
 	b.CreatedAt = now
 	b.UpdatedAt = now
 
-	// 使用事务确保原子性
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 检查课程是否存在且可预订
-		var class booking.Class
-		if err := tx.Where("id = ?", b.ClassID).First(&class).Error; err != nil {
-			return fmt.Errorf("课程不存在: %w", err)
+		// 先占用名额：条件更新同时锁定课程行，并发预订同一课程时依次执行
+		class, err := reserveSeat(tx, b.ClassID, now)
+		if err != nil {
+			return err
 		}
 
-		if !class.CanBook() {
-			return fmt.Errorf("课程不可预订")
+		// 检查是否已预订或已在候补名单中
+		if booked, err := hasActiveBooking(tx, b.ClassID, b.UserID); err != nil {
+			return err
+		} else if booked {
+			return booking.ErrAlreadyBooked
 		}
 
-		// 检查是否已预订
-		var existing booking.Booking
-		if err := tx.Where("class_id = ? AND user_id = ? AND status = ?", b.ClassID, b.UserID, "confirmed").First(&existing).Error; err == nil {
-			return fmt.Errorf("您已预订该课程")
+		if requireCredit {
+			if err := chargeCredit(tx, b, class.StartTime); err != nil {
+				return err
+			}
 		}
 
-		// 创建预订
+		// 创建预订，唯一索引兜底同一用户的重复预订
 		if err := tx.Create(b).Error; err != nil {
+			if isUniqueViolation(err, activeBookingIndex) {
+				return booking.ErrAlreadyBooked
+			}
 			return fmt.Errorf("创建预订失败: %w", err)
 		}
-
-		// 更新课程预订数量
-		if err := tx.Model(&class).Update("booked_count", gorm.Expr("booked_count + 1")).Error; err != nil {
-			return fmt.Errorf("更新课程预订数量失败: %w", err)
-		}
-
 		return nil
 	})
 }
 
+// reserveSeat 在事务内为预订占用课程的一个名额。以 booked_count < capacity 为条件原子地增加预订数量，
+// 只有已排课、未开始且未满员的课程才会更新成功；更新同时锁定课程行直到事务结束。
+// 更新失败时按课程当前状态返回 booking.ErrClassFull 或 booking.ErrClassNotBookable。
+func reserveSeat(tx *gorm.DB, classID uuid.UUID, now time.Time) (*booking.Class, error) {
+	result := tx.Model(&booking.Class{}).
+		Where("id = ? AND status = ? AND start_time > ? AND booked_count < capacity", classID, "scheduled", now).
+		Update("booked_count", gorm.Expr("booked_count + 1"))
+	if result.Error != nil {
+		return nil, fmt.Errorf("更新课程预订数量失败: %w", result.Error)
+	}
+
+	var class booking.Class
+	if err := tx.Where("id = ?", classID).First(&class).Error; err != nil {
+		return nil, fmt.Errorf("课程不存在: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		if class.Status == "scheduled" && now.Before(class.StartTime) && class.IsFull() {
+			return nil, booking.ErrClassFull
+		}
+		return nil, booking.ErrClassNotBookable
+	}
+	return &class, nil
+}
+
 // GetBooking 获取预订
 func (r *BookingRepository) GetBooking(ctx context.Context, id uuid.UUID) (*booking.Booking, error) {
 	var b booking.Booking
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&b).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("预订不存在: %w", err)
+			return nil, booking.ErrBookingNotFound
 		}
 		return nil, fmt.Errorf("查询预订失败: %w", err)
 	}
@@ -141,32 +378,142 @@ func (r *BookingRepository) ListUserBookings(ctx context.Context, userID string,
 	return bookings, nil
 }
 
-// CancelBooking 取消预订
-func (r *BookingRepository) CancelBooking(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var b booking.Booking
-		if err := tx.Where("id = ?", id).First(&b).Error; err != nil {
-			return fmt.Errorf("预订不存在: %w", err)
+// CancelBooking 取消预订（已确认或候补中），记录操作人与原因。
+// 在事务内先锁定课程行、再锁定预订行后校验状态，避免并发取消重复扣减课程预订数量；
+// 加锁顺序与预订、候补转正一致（课程、预订、会员卡），避免死锁。
+// c.Policy非空时在锁定后按预订当时的状态和课程时间应用取消政策（课程已开始、已过截止时间、迟取消不退课时）。
+// 释放已确认的名额且当前早于c.PromoteBefore时，同一事务内将候补名单第一位转为已确认并返回该预订。
+// 返回取消后的预订与转正的预订（没有转正时为nil）。
+func (r *BookingRepository) CancelBooking(ctx context.Context, id uuid.UUID, c booking.Cancellation) (cancelled, promoted *booking.Booking, err error) {
+	var b booking.Booking
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("class_id").Where("id = ?", id).First(&b).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return booking.ErrBookingNotFound
+			}
+			return fmt.Errorf("查询预订失败: %w", err)
+		}
+		var class booking.Class
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", b.ClassID).First(&class).Error; err != nil {
+			return fmt.Errorf("查询课程失败: %w", err)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&b).Error; err != nil {
+			return fmt.Errorf("查询预订失败: %w", err)
 		}
 
-		if b.Status != "confirmed" {
-			return fmt.Errorf("只能取消已确认的预订")
+		if b.Status != "confirmed" && b.Status != "waitlisted" {
+			return booking.ErrBookingNotCancellable
+		}
+		// 取消政策按锁定后的预订状态与上课时间判定：读取后预订可能已被候补转正或取消，课程也可能已改期
+		var late, forfeit bool
+		if c.Policy != nil && b.Status == "confirmed" {
+			var err error
+			late, err = c.Policy.CheckCancel(class.StartTime, time.Now())
+			if err != nil {
+				return err
+			}
+			forfeit = late && c.Policy.ForfeitCredit
 		}
+		// Updates会回写b的字段，先记下取消前的状态与候补位置
+		wasWaitlisted := b.Status == "waitlisted"
+		position := b.WaitlistPosition
 
 		// 更新预订状态
-		if err := tx.Model(&b).Update("status", "cancelled").Error; err != nil {
+		if err := tx.Model(&b).Updates(map[string]interface{}{
+			"status":            "cancelled",
+			"waitlist_position": nil,
+			"cancelled_by":      c.By,
+			"cancel_reason":     c.Reason,
+			"cancelled_at":      time.Now(),
+			"late_cancel":       late,
+			"credit_forfeited":  forfeit,
+		}).Error; err != nil {
 			return fmt.Errorf("取消预订失败: %w", err)
 		}
 
-		// 更新课程预订数量
+		// 退出候补名单，不占用名额
+		if wasWaitlisted {
+			return shiftWaitlist(tx, b.ClassID, *position)
+		}
+
+		if !forfeit {
+			if err := refundCredit(tx, &b, "取消预订", c.By); err != nil {
+				return err
+			}
+		}
+
+		// 释放名额
 		if err := tx.Model(&booking.Class{}).
 			Where("id = ?", b.ClassID).
 			Update("booked_count", gorm.Expr("booked_count - 1")).Error; err != nil {
 			return fmt.Errorf("更新课程预订数量失败: %w", err)
 		}
 
+		if time.Now().Before(c.PromoteBefore) {
+			var err error
+			promoted, err = promoteWaitlist(tx, b.ClassID, c.RequireCredit)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &b, promoted, nil
+}
+
+// MarkNoShow 将已确认且未签到的预订标记为爽约，名额不释放。
+// 签到同样先锁定预订行，已签到的预订在锁内查到签到记录后返回booking.ErrBookingCheckedIn。
+func (r *BookingRepository) MarkNoShow(ctx context.Context, id uuid.UUID, forfeitCredit bool) (*booking.Booking, error) {
+	var b booking.Booking
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&b).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return booking.ErrBookingNotFound
+			}
+			return fmt.Errorf("查询预订失败: %w", err)
+		}
+
+		if b.Status != "confirmed" {
+			return booking.ErrBookingNotConfirmed
+		}
+		var checkedIn int64
+		if err := tx.Model(&booking.Attendance{}).Where("booking_id = ?", id).Count(&checkedIn).Error; err != nil {
+			return fmt.Errorf("查询签到记录失败: %w", err)
+		}
+		if checkedIn > 0 {
+			return booking.ErrBookingCheckedIn
+		}
+
+		if err := tx.Model(&b).Updates(map[string]interface{}{
+			"status":           "no_show",
+			"credit_forfeited": forfeitCredit,
+		}).Error; err != nil {
+			return fmt.Errorf("标记爽约失败: %w", err)
+		}
+		if !forfeitCredit {
+			return refundCredit(tx, &b, "爽约", "")
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ListNoShowTimes 列出用户自since以来爽约课程的上课时间
+func (r *BookingRepository) ListNoShowTimes(ctx context.Context, userID string, since time.Time) ([]time.Time, error) {
+	var times []time.Time
+	if err := r.db.WithContext(ctx).
+		Table("bookings").
+		Joins("JOIN classes ON classes.id = bookings.class_id").
+		Where("bookings.user_id = ? AND bookings.status = ? AND classes.start_time >= ?", userID, "no_show", since).
+		Pluck("classes.start_time", &times).Error; err != nil {
+		return nil, fmt.Errorf("查询爽约记录失败: %w", err)
+	}
+	return times, nil
 }
 
 // CreateReview 创建评价
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"gorm.io/gorm"
+)
+
+// 并发预订测试：从多个goroutine同时预订同一节课，验证 booked_count < capacity 条件更新不超卖、
+// idx_bookings_active_user 唯一索引保证同一用户只有一个有效预订。
+// TEST_DATABASE_URL 指向已执行 configs/init.sql 的测试数据库，未设置时跳过。
+
+// testDB 连接测试数据库，未配置时跳过测试
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("未设置TEST_DATABASE_URL，跳过需要数据库的测试")
+	}
+	db, err := GetDB(dsn)
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接池失败: %v", err)
+	}
+	// 并发的goroutine多于连接数，预订在连接池上排队，避免超过数据库的最大连接数
+	sqlDB.SetMaxOpenConns(20)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// newTestClass 创建一节明天开始的测试课程，测试结束后删除（预订随课程级联删除）
+func newTestClass(t *testing.T, repo *BookingRepository, capacity int) *booking.Class {
+	t.Helper()
+
+	start := time.Now().Add(24 * time.Hour)
+	class := &booking.Class{
+		Name:      "booking-concurrency-test",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Capacity:  capacity,
+		Status:    "scheduled",
+	}
+	if err := repo.CreateClass(context.Background(), class); err != nil {
+		t.Fatalf("创建课程失败: %v", err)
+	}
+	t.Cleanup(func() { repo.db.Where("id = ?", class.ID).Delete(&booking.Class{}) })
+	return class
+}
+
+// bookConcurrently 同时启动len(userIDs)个goroutine，每个以对应用户身份预订课程，返回各自的结果
+func bookConcurrently(repo *BookingRepository, classID uuid.UUID, userIDs []string) []error {
+	results := make([]error, len(userIDs))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, userID := range userIDs {
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			<-start
+			results[i] = repo.CreateBooking(context.Background(), &booking.Booking{
+				ClassID:  classID,
+				UserID:   userID,
+				UserName: "booking-concurrency-test",
+				Status:   "confirmed",
+			}, false)
+		}(i, userID)
+	}
+	close(start)
+	wg.Wait()
+	return results
+}
+
+// checkBookings 校验课程的booked_count等于期望值和已确认预订数，且每个用户最多一个有效预订
+func checkBookings(t *testing.T, repo *BookingRepository, classID uuid.UUID, wantBooked int) {
+	t.Helper()
+
+	class, err := repo.GetClass(context.Background(), classID)
+	if err != nil {
+		t.Fatalf("查询课程失败: %v", err)
+	}
+	if class.BookedCount != wantBooked {
+		t.Errorf("booked_count为%d，期望%d", class.BookedCount, wantBooked)
+	}
+
+	var bookings []*booking.Booking
+	if err := repo.db.Where("class_id = ? AND status IN ?", classID, []string{"confirmed", "waitlisted"}).
+		Find(&bookings).Error; err != nil {
+		t.Fatalf("查询预订失败: %v", err)
+	}
+	if len(bookings) != class.BookedCount {
+		t.Errorf("有效预订%d个，booked_count为%d", len(bookings), class.BookedCount)
+	}
+	seen := make(map[string]bool, len(bookings))
+	for _, b := range bookings {
+		if seen[b.UserID] {
+			t.Errorf("用户%s有多个有效预订", b.UserID)
+		}
+		seen[b.UserID] = true
+	}
+}
+
+func TestCreateBookingConcurrentLastSeats(t *testing.T) {
+	repo := NewBookingRepository(testDB(t))
+	const capacity, users = 10, 100
+	class := newTestClass(t, repo, capacity)
+
+	userIDs := make([]string, users)
+	for i := range userIDs {
+		userIDs[i] = uuid.NewString()
+	}
+
+	booked, full := 0, 0
+	for _, err := range bookConcurrently(repo, class.ID, userIDs) {
+		switch {
+		case err == nil:
+			booked++
+		case errors.Is(err, booking.ErrClassFull):
+			full++
+		default:
+			t.Fatalf("预订返回了意外的错误: %v", err)
+		}
+	}
+	if booked != capacity || full != users-capacity {
+		t.Errorf("成功%d个、满员%d个，期望成功%d个、满员%d个", booked, full, capacity, users-capacity)
+	}
+	checkBookings(t, repo, class.ID, capacity)
+}
+
+func TestCreateBookingConcurrentDuplicates(t *testing.T) {
+	repo := NewBookingRepository(testDB(t))
+	const capacity, users, attempts = 5, 20, 10
+	class := newTestClass(t, repo, capacity)
+
+	// 每个用户同时预订多次，总人数多于名额
+	var userIDs []string
+	for i := 0; i < users; i++ {
+		userID := uuid.NewString()
+		for j := 0; j < attempts; j++ {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	booked := 0
+	for _, err := range bookConcurrently(repo, class.ID, userIDs) {
+		switch {
+		case err == nil:
+			booked++
+		case errors.Is(err, booking.ErrClassFull), errors.Is(err, booking.ErrAlreadyBooked):
+		default:
+			t.Fatalf("预订返回了意外的错误: %v", err)
+		}
+	}
+	if booked != capacity {
+		t.Errorf("成功预订%d次，期望%d次", booked, capacity)
+	}
+	checkBookings(t, repo, class.ID, capacity)
+}
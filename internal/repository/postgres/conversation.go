@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/ai"
+	"gorm.io/gorm"
+)
+
+// ConversationRepository 会话仓储接口实现
+type ConversationRepository struct {
+	db *gorm.DB
+}
+
+// NewConversationRepository 创建会话仓储
+func NewConversationRepository(db *gorm.DB) *ConversationRepository {
+	return &ConversationRepository{db: db}
+}
+
+// CreateConversation 创建会话
+func (r *ConversationRepository) CreateConversation(ctx context.Context, conv *ai.Conversation) error {
+	if conv.ID == uuid.Nil {
+		conv.ID = uuid.New()
+	}
+	now := time.Now()
+	conv.CreatedAt = now
+	conv.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(conv).Error; err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	return nil
+}
+
+// GetConversation 获取会话
+func (r *ConversationRepository) GetConversation(ctx context.Context, id uuid.UUID) (*ai.Conversation, error) {
+	var conv ai.Conversation
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&conv).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("会话不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询会话失败: %w", err)
+	}
+	return &conv, nil
+}
+
+// ListConversations 列出用户的会话（按最近更新排序）
+func (r *ConversationRepository) ListConversations(ctx context.Context, userID string, limit, offset int) ([]*ai.Conversation, error) {
+	var convs []*ai.Conversation
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&convs).Error; err != nil {
+		return nil, fmt.Errorf("查询会话列表失败: %w", err)
+	}
+	return convs, nil
+}
+
+// UpdateConversationSummary 更新会话摘要及其覆盖的消息条数，只写这两列，不覆盖同时生成的标题
+func (r *ConversationRepository) UpdateConversationSummary(ctx context.Context, id uuid.UUID, summary string, summarizedCount int) error {
+	if err := r.db.WithContext(ctx).Model(&ai.Conversation{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"summary":          summary,
+			"summarized_count": summarizedCount,
+			"updated_at":       time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("更新会话摘要失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateConversationTitle 更新会话标题
+func (r *ConversationRepository) UpdateConversationTitle(ctx context.Context, id uuid.UUID, title string) error {
+	if err := r.db.WithContext(ctx).Model(&ai.Conversation{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"title":      title,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("更新会话标题失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteConversation 删除会话（消息级联删除）
+func (r *ConversationRepository) DeleteConversation(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Delete(&ai.Conversation{}, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("删除会话失败: %w", err)
+	}
+	return nil
+}
+
+// AddMessages 追加会话消息并刷新会话更新时间
+func (r *ConversationRepository) AddMessages(ctx context.Context, conversationID uuid.UUID, messages ...*ai.Message) error {
+	now := time.Now()
+	for i, msg := range messages {
+		if msg.ID == uuid.Nil {
+			msg.ID = uuid.New()
+		}
+		msg.ConversationID = conversationID
+		// 同一批消息保持先后顺序
+		msg.CreatedAt = now.Add(time.Duration(i) * time.Microsecond)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&messages).Error; err != nil {
+			return fmt.Errorf("保存会话消息失败: %w", err)
+		}
+		if err := tx.Model(&ai.Conversation{}).
+			Where("id = ?", conversationID).
+			Update("updated_at", now).Error; err != nil {
+			return fmt.Errorf("更新会话时间失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListMessages 按时间顺序列出会话的全部消息
+func (r *ConversationRepository) ListMessages(ctx context.Context, conversationID uuid.UUID) ([]*ai.Message, error) {
+	var messages []*ai.Message
+	if err := r.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Order("created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询会话消息失败: %w", err)
+	}
+	return messages, nil
+}
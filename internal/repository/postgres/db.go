@@ -1,12 +1,34 @@
 package postgres
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// activeBookingIndex 同一用户在同一课程只能有一个已确认或候补中的预订（见 configs/init.sql）
+const activeBookingIndex = "idx_bookings_active_user"
+
+// roomOverlapConstraint 同一教室的未取消课程时间不能重叠（见 configs/init.sql）
+const roomOverlapConstraint = "classes_room_no_overlap"
+
+// instructorOverlapConstraint、instructorUserOverlapConstraint 同一教练档案、同一教练账号的
+// 未取消课程时间不能重叠（见 configs/init.sql）
+const (
+	instructorOverlapConstraint     = "classes_instructor_no_overlap"
+	instructorUserOverlapConstraint = "classes_instructor_user_no_overlap"
+)
+
+// locationNameKey 场馆名称唯一（见 configs/init.sql）
+const locationNameKey = "locations_name_key"
+
+// roomNameKey 同一场馆内教室名称唯一（见 configs/init.sql）
+const roomNameKey = "rooms_location_id_name_key"
+
 // GetDB 获取数据库连接（用于共享连接）
 func GetDB(dsn string) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
@@ -16,3 +38,26 @@ func GetDB(dsn string) (*gorm.DB, error) {
 	return db, nil
 }
 
+// isUniqueViolation 是否为违反指定唯一索引的错误
+func isUniqueViolation(err error, index string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == index
+}
+
+// isExclusionViolation 是否为违反指定排他约束的错误
+func isExclusionViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23P01" && pgErr.ConstraintName == constraint
+}
+
+// classOverlapError 将违反课程时间排他约束的错误转换为对应的领域错误，其他错误返回nil
+func classOverlapError(err error) error {
+	switch {
+	case isExclusionViolation(err, roomOverlapConstraint):
+		return booking.ErrRoomConflict
+	case isExclusionViolation(err, instructorOverlapConstraint),
+		isExclusionViolation(err, instructorUserOverlapConstraint):
+		return booking.ErrInstructorConflict
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/instructor"
+	"gorm.io/gorm"
+)
+
+// InstructorRepository 教练档案仓储实现
+type InstructorRepository struct {
+	db *gorm.DB
+}
+
+// NewInstructorRepository 创建教练档案仓储
+func NewInstructorRepository(db *gorm.DB) *InstructorRepository {
+	return &InstructorRepository{db: db}
+}
+
+// CreateInstructor 创建教练档案
+func (r *InstructorRepository) CreateInstructor(ctx context.Context, ins *instructor.Instructor) error {
+	if ins.ID == uuid.Nil {
+		ins.ID = uuid.New()
+	}
+	now := time.Now()
+	ins.CreatedAt = now
+	ins.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(ins).Error; err != nil {
+		return fmt.Errorf("创建教练档案失败: %w", err)
+	}
+	return nil
+}
+
+// GetInstructor 获取教练档案
+func (r *InstructorRepository) GetInstructor(ctx context.Context, id uuid.UUID) (*instructor.Instructor, error) {
+	return getInstructor(r.db.WithContext(ctx), id)
+}
+
+// GetInstructor 获取教练档案，排课时按档案填写授课教练
+func (r *BookingRepository) GetInstructor(ctx context.Context, id uuid.UUID) (*instructor.Instructor, error) {
+	return getInstructor(r.db.WithContext(ctx), id)
+}
+
+// getInstructor 在给定连接上查询教练档案
+func getInstructor(db *gorm.DB, id uuid.UUID) (*instructor.Instructor, error) {
+	var ins instructor.Instructor
+	if err := db.Where("id = ?", id).First(&ins).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, instructor.ErrInstructorNotFound
+		}
+		return nil, fmt.Errorf("查询教练档案失败: %w", err)
+	}
+	return &ins, nil
+}
+
+// ListInstructors 按姓名列出教练档案，activeOnly为true时只列出在职教练
+func (r *InstructorRepository) ListInstructors(ctx context.Context, activeOnly bool, limit, offset int) ([]*instructor.Instructor, error) {
+	query := r.db.WithContext(ctx).Order("name ASC").Limit(limit).Offset(offset)
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+
+	var list []*instructor.Instructor
+	if err := query.Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("查询教练档案失败: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateInstructor 更新教练档案，并在同一事务内同步尚未结束的课程与课程系列上的教练姓名和教练账号
+func (r *InstructorRepository) UpdateInstructor(ctx context.Context, ins *instructor.Instructor) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ins.UpdatedAt = time.Now()
+		if err := tx.Omit("created_at").Save(ins).Error; err != nil {
+			return fmt.Errorf("更新教练档案失败: %w", err)
+		}
+
+		assigned := map[string]interface{}{
+			"instructor":         ins.Name,
+			"instructor_user_id": ins.UserID,
+		}
+		if err := tx.Model(&booking.Class{}).
+			Where("instructor_id = ? AND status = ?", ins.ID, "scheduled").
+			Updates(assigned).Error; err != nil {
+			// 新关联的教练账号在同一时段已有其他课程
+			if conflictErr := classOverlapError(err); conflictErr != nil {
+				return conflictErr
+			}
+			return fmt.Errorf("同步课程教练信息失败: %w", err)
+		}
+		if err := tx.Model(&booking.ClassSeries{}).
+			Where("instructor_id = ?", ins.ID).
+			Updates(assigned).Error; err != nil {
+			return fmt.Errorf("同步课程系列教练信息失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// InstructorRatings 按教练汇总其所授课程的评价，没有评价的教练不在结果中
+func (r *InstructorRepository) InstructorRatings(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]instructor.Rating, error) {
+	ratings := make(map[uuid.UUID]instructor.Rating, len(ids))
+	if len(ids) == 0 {
+		return ratings, nil
+	}
+
+	var rows []struct {
+		InstructorID uuid.UUID
+		Average      float64
+		Count        int
+	}
+	if err := r.db.WithContext(ctx).
+		Table("reviews").
+		Select("classes.instructor_id, AVG(reviews.rating) AS average, COUNT(*) AS count").
+		Joins("JOIN classes ON classes.id = reviews.class_id").
+		Where("classes.instructor_id IN ?", ids).
+		Group("classes.instructor_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("汇总教练评价失败: %w", err)
+	}
+
+	for _, row := range rows {
+		ratings[row.InstructorID] = instructor.Rating{
+			Average: math.Round(row.Average*10) / 10,
+			Count:   row.Count,
+		}
+	}
+	return ratings, nil
+}
+
+// ListInstructorClasses 列出教练在时间范围内的课程（含已取消的课程），按开课时间排序
+func (r *InstructorRepository) ListInstructorClasses(ctx context.Context, id uuid.UUID, startTime, endTime time.Time, limit, offset int) ([]*booking.Class, error) {
+	var classes []*booking.Class
+	if err := r.db.WithContext(ctx).
+		Where("instructor_id = ? AND start_time >= ? AND start_time < ?", id, startTime, endTime).
+		Order("start_time ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&classes).Error; err != nil {
+		return nil, fmt.Errorf("查询教练课程失败: %w", err)
+	}
+	return classes, nil
+}
@@ -99,13 +99,25 @@ func (r *KnowledgeRepository) GetItem(ctx context.Context, id uuid.UUID) (*knowl
 	var item knowledge.KnowledgeItem
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&item).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("知识项不存在: %w", err)
+			return nil, knowledge.ErrItemNotFound
 		}
 		return nil, fmt.Errorf("查询知识项失败: %w", err)
 	}
 	return &item, nil
 }
 
+// GetItemsByIDs 批量获取知识项，已删除的知识项不会出现在结果中
+func (r *KnowledgeRepository) GetItemsByIDs(ctx context.Context, ids []uuid.UUID) ([]*knowledge.KnowledgeItem, error) {
+	var items []*knowledge.KnowledgeItem
+	if len(ids) == 0 {
+		return items, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("批量查询知识项失败: %w", err)
+	}
+	return items, nil
+}
+
 // ListItems 列出知识项
 func (r *KnowledgeRepository) ListItems(ctx context.Context, baseID uuid.UUID, limit, offset int) ([]*knowledge.KnowledgeItem, error) {
 	var items []*knowledge.KnowledgeItem
@@ -125,19 +137,71 @@ func (r *KnowledgeRepository) UpdateItem(ctx context.Context, item *knowledge.Kn
 	return nil
 }
 
-// UpdateItemEmbeddingStatus 更新知识项的向量化状态
-func (r *KnowledgeRepository) UpdateItemEmbeddingStatus(ctx context.Context, id uuid.UUID, status, vectorID string) error {
+// UpdateItemEmbeddingStatus 更新知识项的向量化状态，只有持有租约leaseToken的worker可以更新，
+// 租约已被其他worker接手时返回ErrLeaseLost
+func (r *KnowledgeRepository) UpdateItemEmbeddingStatus(ctx context.Context, id, leaseToken uuid.UUID, status, vectorID string) error {
 	updates := map[string]interface{}{
 		"embedding_status": status,
-		"updated_at":        time.Now(),
+		"updated_at":       time.Now(),
 	}
 	if vectorID != "" {
 		updates["vector_id"] = vectorID
 	}
+	if status != "processing" {
+		updates["lease_expires_at"] = nil
+		updates["lease_token"] = nil
+	}
+	if status == "completed" {
+		updates["embedding_error"] = ""
+		updates["next_attempt_at"] = nil
+	}
 
-	if err := r.db.WithContext(ctx).Model(&knowledge.KnowledgeItem{}).
-		Where("id = ?", id).Updates(updates).Error; err != nil {
-		return fmt.Errorf("更新向量化状态失败: %w", err)
+	return r.updateLeasedItem(ctx, id, leaseToken, updates, "更新向量化状态失败")
+}
+
+// RenewItemLease 延长向量化任务的租约，租约已被其他worker接手时返回ErrLeaseLost
+func (r *KnowledgeRepository) RenewItemLease(ctx context.Context, id, leaseToken uuid.UUID, leaseTimeout time.Duration) error {
+	now := time.Now()
+	return r.updateLeasedItem(ctx, id, leaseToken, map[string]interface{}{
+		"lease_expires_at": now.Add(leaseTimeout),
+		"updated_at":       now,
+	}, "续租向量化任务失败")
+}
+
+// RetryItemEmbedding 记录向量化失败并安排在nextAttemptAt之后重试，租约已被其他worker接手时返回ErrLeaseLost
+func (r *KnowledgeRepository) RetryItemEmbedding(ctx context.Context, id, leaseToken uuid.UUID, errMsg string, nextAttemptAt time.Time) error {
+	return r.updateLeasedItem(ctx, id, leaseToken, map[string]interface{}{
+		"embedding_status": "pending",
+		"embedding_error":  errMsg,
+		"next_attempt_at":  nextAttemptAt,
+		"lease_expires_at": nil,
+		"lease_token":      nil,
+		"updated_at":       time.Now(),
+	}, "更新向量化重试状态失败")
+}
+
+// FailItemEmbedding 将知识项标记为死信（failed），不再自动重试，租约已被其他worker接手时返回ErrLeaseLost
+func (r *KnowledgeRepository) FailItemEmbedding(ctx context.Context, id, leaseToken uuid.UUID, errMsg string) error {
+	return r.updateLeasedItem(ctx, id, leaseToken, map[string]interface{}{
+		"embedding_status": "failed",
+		"embedding_error":  errMsg,
+		"next_attempt_at":  nil,
+		"lease_expires_at": nil,
+		"lease_token":      nil,
+		"updated_at":       time.Now(),
+	}, "更新向量化失败状态失败")
+}
+
+// updateLeasedItem 更新仍由leaseToken持有的处理中知识项
+func (r *KnowledgeRepository) updateLeasedItem(ctx context.Context, id, leaseToken uuid.UUID, updates map[string]interface{}, errMsg string) error {
+	result := r.db.WithContext(ctx).Model(&knowledge.KnowledgeItem{}).
+		Where("id = ? AND lease_token = ? AND embedding_status = ?", id, leaseToken, "processing").
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", errMsg, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return knowledge.ErrLeaseLost
 	}
 	return nil
 }
@@ -150,15 +214,97 @@ func (r *KnowledgeRepository) DeleteItem(ctx context.Context, id uuid.UUID) erro
 	return nil
 }
 
-// GetPendingItems 获取待向量化的知识项
-func (r *KnowledgeRepository) GetPendingItems(ctx context.Context, limit int) ([]*knowledge.KnowledgeItem, error) {
-	var items []*knowledge.KnowledgeItem
+// ReplaceItemChunks 替换知识项的全部分块
+func (r *KnowledgeRepository) ReplaceItemChunks(ctx context.Context, itemID uuid.UUID, chunks []*knowledge.KnowledgeChunk) error {
+	now := time.Now()
+	for _, chunk := range chunks {
+		if chunk.ID == uuid.Nil {
+			chunk.ID = uuid.New()
+		}
+		chunk.KnowledgeItemID = itemID
+		chunk.CreatedAt = now
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("knowledge_item_id = ?", itemID).Delete(&knowledge.KnowledgeChunk{}).Error; err != nil {
+			return fmt.Errorf("删除旧分块失败: %w", err)
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		if err := tx.Create(&chunks).Error; err != nil {
+			return fmt.Errorf("创建分块失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListItemChunks 列出知识项的分块
+func (r *KnowledgeRepository) ListItemChunks(ctx context.Context, itemID uuid.UUID) ([]*knowledge.KnowledgeChunk, error) {
+	var chunks []*knowledge.KnowledgeChunk
 	if err := r.db.WithContext(ctx).
-		Where("embedding_status = ?", "pending").
-		Limit(limit).
-		Find(&items).Error; err != nil {
-		return nil, fmt.Errorf("查询待向量化项失败: %w", err)
+		Where("knowledge_item_id = ?", itemID).
+		Order("chunk_index ASC").
+		Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("查询分块列表失败: %w", err)
 	}
-	return items, nil
+	return chunks, nil
+}
+
+// GetChunksByIDs 批量获取分块
+func (r *KnowledgeRepository) GetChunksByIDs(ctx context.Context, ids []uuid.UUID) ([]*knowledge.KnowledgeChunk, error) {
+	var chunks []*knowledge.KnowledgeChunk
+	if len(ids) == 0 {
+		return chunks, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("批量查询分块失败: %w", err)
+	}
+	return chunks, nil
+}
+
+// ListBaseVectorIDs 列出知识库下所有知识项及分块的向量ID
+func (r *KnowledgeRepository) ListBaseVectorIDs(ctx context.Context, baseID uuid.UUID) ([]string, error) {
+	var ids []string
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT vector_id FROM knowledge_chunks WHERE knowledge_base_id = ? AND vector_id <> ''
+		UNION
+		SELECT vector_id FROM knowledge_items WHERE knowledge_base_id = ? AND vector_id <> ''`,
+		baseID, baseID,
+	).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("查询知识库向量ID失败: %w", err)
+	}
+	return ids, nil
 }
 
+// GetPendingItems 领取待向量化的知识项
+//
+// 使用 FOR UPDATE SKIP LOCKED 在多个worker（或多个实例）之间无冲突地领取任务：
+// 到达重试时间的 pending 项，以及租约已过期的 processing 项（处理中的实例崩溃或重启）
+// 都会被领取。领取后状态置为 processing、尝试次数加一，并设置新的租约令牌与到期时间，
+// 原持有者的续租与状态更新随之失效。
+func (r *KnowledgeRepository) GetPendingItems(ctx context.Context, limit int, leaseTimeout time.Duration) ([]*knowledge.KnowledgeItem, error) {
+	var items []*knowledge.KnowledgeItem
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Raw(`
+		UPDATE knowledge_items
+		SET embedding_status = 'processing',
+		    embedding_attempts = embedding_attempts + 1,
+		    lease_expires_at = ?,
+		    lease_token = gen_random_uuid(),
+		    updated_at = ?
+		WHERE id IN (
+			SELECT id FROM knowledge_items
+			WHERE (embedding_status = 'pending' AND (next_attempt_at IS NULL OR next_attempt_at <= ?))
+			   OR (embedding_status = 'processing' AND (lease_expires_at IS NULL OR lease_expires_at < ?))
+			ORDER BY created_at ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(leaseTimeout), now, now, now, limit,
+	).Scan(&items).Error; err != nil {
+		return nil, fmt.Errorf("领取待向量化项失败: %w", err)
+	}
+	return items, nil
+}
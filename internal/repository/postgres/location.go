@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/location"
+	"gorm.io/gorm"
+)
+
+// LocationRepository 场馆与教室仓储实现
+type LocationRepository struct {
+	db *gorm.DB
+}
+
+// NewLocationRepository 创建场馆与教室仓储
+func NewLocationRepository(db *gorm.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+// CreateLocation 创建场馆
+func (r *LocationRepository) CreateLocation(ctx context.Context, loc *location.Location) error {
+	if loc.ID == uuid.Nil {
+		loc.ID = uuid.New()
+	}
+	now := time.Now()
+	loc.CreatedAt = now
+	loc.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(loc).Error; err != nil {
+		if isUniqueViolation(err, locationNameKey) {
+			return location.ErrLocationExists
+		}
+		return fmt.Errorf("创建场馆失败: %w", err)
+	}
+	return nil
+}
+
+// GetLocation 获取场馆及其教室
+func (r *LocationRepository) GetLocation(ctx context.Context, id uuid.UUID) (*location.Location, error) {
+	var loc location.Location
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&loc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, location.ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("查询场馆失败: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).Where("location_id = ?", id).Order("name ASC").Find(&loc.Rooms).Error; err != nil {
+		return nil, fmt.Errorf("查询教室失败: %w", err)
+	}
+	return &loc, nil
+}
+
+// ListLocations 按名称列出场馆及其教室
+func (r *LocationRepository) ListLocations(ctx context.Context) ([]*location.Location, error) {
+	var locations []*location.Location
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&locations).Error; err != nil {
+		return nil, fmt.Errorf("查询场馆失败: %w", err)
+	}
+
+	var rooms []*location.Room
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&rooms).Error; err != nil {
+		return nil, fmt.Errorf("查询教室失败: %w", err)
+	}
+	byID := make(map[uuid.UUID]*location.Location, len(locations))
+	for _, loc := range locations {
+		byID[loc.ID] = loc
+	}
+	for _, room := range rooms {
+		if loc, ok := byID[room.LocationID]; ok {
+			loc.Rooms = append(loc.Rooms, room)
+		}
+	}
+	return locations, nil
+}
+
+// UpdateLocation 更新场馆信息
+func (r *LocationRepository) UpdateLocation(ctx context.Context, loc *location.Location) error {
+	loc.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Omit("created_at").Save(loc).Error; err != nil {
+		if isUniqueViolation(err, locationNameKey) {
+			return location.ErrLocationExists
+		}
+		return fmt.Errorf("更新场馆失败: %w", err)
+	}
+	return nil
+}
+
+// CreateRoom 创建教室
+func (r *LocationRepository) CreateRoom(ctx context.Context, room *location.Room) error {
+	if room.ID == uuid.Nil {
+		room.ID = uuid.New()
+	}
+	now := time.Now()
+	room.CreatedAt = now
+	room.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(room).Error; err != nil {
+		if isUniqueViolation(err, roomNameKey) {
+			return location.ErrRoomExists
+		}
+		return fmt.Errorf("创建教室失败: %w", err)
+	}
+	return nil
+}
+
+// GetRoom 获取教室
+func (r *LocationRepository) GetRoom(ctx context.Context, id uuid.UUID) (*location.Room, error) {
+	var room location.Room
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&room).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, location.ErrRoomNotFound
+		}
+		return nil, fmt.Errorf("查询教室失败: %w", err)
+	}
+	return &room, nil
+}
+
+// UpdateRoom 更新教室信息，已排的课程容量不受影响
+func (r *LocationRepository) UpdateRoom(ctx context.Context, room *location.Room) error {
+	room.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Omit("created_at").Save(room).Error; err != nil {
+		if isUniqueViolation(err, roomNameKey) {
+			return location.ErrRoomExists
+		}
+		return fmt.Errorf("更新教室失败: %w", err)
+	}
+	return nil
+}
+
+// GetRooms 按ID批量获取教室并填写所属场馆，不存在的教室不在结果中
+func (r *BookingRepository) GetRooms(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*location.Room, error) {
+	rooms := make(map[uuid.UUID]*location.Room, len(ids))
+	if len(ids) == 0 {
+		return rooms, nil
+	}
+
+	var list []*location.Room
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("查询教室失败: %w", err)
+	}
+
+	locationIDs := make([]uuid.UUID, 0, len(list))
+	for _, room := range list {
+		locationIDs = append(locationIDs, room.LocationID)
+	}
+	var locations []*location.Location
+	if err := r.db.WithContext(ctx).Where("id IN ?", locationIDs).Find(&locations).Error; err != nil {
+		return nil, fmt.Errorf("查询场馆失败: %w", err)
+	}
+	byID := make(map[uuid.UUID]*location.Location, len(locations))
+	for _, loc := range locations {
+		byID[loc.ID] = loc
+	}
+
+	for _, room := range list {
+		room.Location = byID[room.LocationID]
+		rooms[room.ID] = room
+	}
+	return rooms, nil
+}
@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/membership"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MembershipRepository 会员卡仓储实现
+type MembershipRepository struct {
+	db *gorm.DB
+}
+
+// NewMembershipRepository 创建会员卡仓储
+func NewMembershipRepository(db *gorm.DB) *MembershipRepository {
+	return &MembershipRepository{db: db}
+}
+
+// CreatePlan 创建会员卡方案
+func (r *MembershipRepository) CreatePlan(ctx context.Context, plan *membership.Plan) error {
+	if plan.ID == uuid.Nil {
+		plan.ID = uuid.New()
+	}
+	now := time.Now()
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(plan).Error; err != nil {
+		return fmt.Errorf("创建会员卡方案失败: %w", err)
+	}
+	return nil
+}
+
+// GetPlan 获取会员卡方案
+func (r *MembershipRepository) GetPlan(ctx context.Context, id uuid.UUID) (*membership.Plan, error) {
+	var plan membership.Plan
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, membership.ErrPlanNotFound
+		}
+		return nil, fmt.Errorf("查询会员卡方案失败: %w", err)
+	}
+	return &plan, nil
+}
+
+// ListPlans 列出会员卡方案，activeOnly为true时只列出在售方案
+func (r *MembershipRepository) ListPlans(ctx context.Context, activeOnly bool) ([]*membership.Plan, error) {
+	query := r.db.WithContext(ctx).Order("price_cents ASC")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+
+	var plans []*membership.Plan
+	if err := query.Find(&plans).Error; err != nil {
+		return nil, fmt.Errorf("查询会员卡方案失败: %w", err)
+	}
+	return plans, nil
+}
+
+// UpdatePlan 更新会员卡方案，已开出的会员卡不受影响
+func (r *MembershipRepository) UpdatePlan(ctx context.Context, plan *membership.Plan) error {
+	plan.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Omit("created_at").Save(plan).Error; err != nil {
+		return fmt.Errorf("更新会员卡方案失败: %w", err)
+	}
+	return nil
+}
+
+// CreateSubscription 开卡，次卡同时记录一条开卡流水
+func (r *MembershipRepository) CreateSubscription(ctx context.Context, sub *membership.Subscription, createdBy string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createSubscription(tx, sub, "", createdBy)
+	})
+}
+
+// GetSubscription 获取会员卡
+func (r *MembershipRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*membership.Subscription, error) {
+	var sub membership.Subscription
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, membership.ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("查询会员卡失败: %w", err)
+	}
+	return &sub, nil
+}
+
+// ListUserSubscriptions 列出用户的会员卡，按到期时间倒序
+func (r *MembershipRepository) ListUserSubscriptions(ctx context.Context, userID string) ([]*membership.Subscription, error) {
+	var subs []*membership.Subscription
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("expires_at DESC").
+		Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("查询会员卡失败: %w", err)
+	}
+	return subs, nil
+}
+
+// FreezeSubscription 冻结使用中的会员卡，冻结期间不能用于预订
+func (r *MembershipRepository) FreezeSubscription(ctx context.Context, id uuid.UUID, at time.Time) (*membership.Subscription, error) {
+	var sub membership.Subscription
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSubscription(tx, id, &sub); err != nil {
+			return err
+		}
+		if sub.Status != membership.StatusActive {
+			return membership.ErrNotFreezable
+		}
+
+		sub.Status = membership.StatusFrozen
+		sub.FrozenAt = &at
+		return tx.Model(&sub).Updates(map[string]interface{}{
+			"status":    sub.Status,
+			"frozen_at": at,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UnfreezeSubscription 解冻会员卡，有效期按冻结时长顺延
+func (r *MembershipRepository) UnfreezeSubscription(ctx context.Context, id uuid.UUID, at time.Time) (*membership.Subscription, error) {
+	var sub membership.Subscription
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSubscription(tx, id, &sub); err != nil {
+			return err
+		}
+		if sub.Status != membership.StatusFrozen || sub.FrozenAt == nil {
+			return membership.ErrNotFrozen
+		}
+
+		if frozen := at.Sub(*sub.FrozenAt); frozen > 0 {
+			sub.ExpiresAt = sub.ExpiresAt.Add(frozen)
+		}
+		sub.Status = membership.StatusActive
+		sub.FrozenAt = nil
+		return tx.Model(&sub).Updates(map[string]interface{}{
+			"status":     sub.Status,
+			"frozen_at":  nil,
+			"expires_at": sub.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// AdjustCredits 管理员调整次卡的剩余节数并记录流水
+func (r *MembershipRepository) AdjustCredits(ctx context.Context, id uuid.UUID, delta int, note, by string) (*membership.Subscription, error) {
+	var sub membership.Subscription
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSubscription(tx, id, &sub); err != nil {
+			return err
+		}
+		if delta == 0 || sub.Unlimited() || *sub.RemainingCredits+delta < 0 {
+			return membership.ErrInvalidAdjustment
+		}
+		return changeCredits(tx, &sub, nil, delta, membership.ReasonAdjustment, note, by)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListLedger 列出会员卡的课时流水，按时间倒序
+func (r *MembershipRepository) ListLedger(ctx context.Context, subscriptionID uuid.UUID) ([]*membership.LedgerEntry, error) {
+	var entries []*membership.LedgerEntry
+	if err := r.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at DESC").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("查询课时流水失败: %w", err)
+	}
+	return entries, nil
+}
+
+// createSubscription 在事务内开卡，次卡同时记录一条开卡流水，note为流水备注
+func createSubscription(tx *gorm.DB, sub *membership.Subscription, note, createdBy string) error {
+	now := time.Now()
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+
+	if err := tx.Create(sub).Error; err != nil {
+		return fmt.Errorf("开卡失败: %w", err)
+	}
+	if sub.Unlimited() {
+		return nil
+	}
+	return appendLedger(tx, sub, nil, *sub.RemainingCredits, membership.ReasonPurchase, note, createdBy)
+}
+
+// lockSubscription 在事务内锁定并读取会员卡
+func lockSubscription(tx *gorm.DB, id uuid.UUID, sub *membership.Subscription) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return membership.ErrSubscriptionNotFound
+		}
+		return fmt.Errorf("查询会员卡失败: %w", err)
+	}
+	return nil
+}
+
+// changeCredits 在事务内变动已锁定次卡的剩余节数并记录流水
+func changeCredits(tx *gorm.DB, sub *membership.Subscription, bookingID *uuid.UUID, delta int, reason, note, by string) error {
+	balance := *sub.RemainingCredits + delta
+	if err := tx.Model(sub).Update("remaining_credits", balance).Error; err != nil {
+		return fmt.Errorf("更新剩余课时失败: %w", err)
+	}
+	sub.RemainingCredits = &balance
+	return appendLedger(tx, sub, bookingID, delta, reason, note, by)
+}
+
+// appendLedger 记录一条课时流水，balance取会员卡当前剩余节数
+func appendLedger(tx *gorm.DB, sub *membership.Subscription, bookingID *uuid.UUID, delta int, reason, note, by string) error {
+	entry := &membership.LedgerEntry{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		BookingID:      bookingID,
+		Delta:          delta,
+		Balance:        *sub.RemainingCredits,
+		Reason:         reason,
+		Note:           note,
+		CreatedBy:      by,
+		CreatedAt:      time.Now(),
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("记录课时流水失败: %w", err)
+	}
+	return nil
+}
+
+// chargeCredit 在预订事务内为预订选择可用的会员卡并扣除一节课时，没有可用会员卡时返回booking.ErrNoCredits。
+// 优先使用期限卡（不扣节数），其次使用最早到期的次卡。
+func chargeCredit(tx *gorm.DB, b *booking.Booking, classStart time.Time) error {
+	var sub membership.Subscription
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND status = ? AND starts_at <= ? AND expires_at > ?",
+			b.UserID, membership.StatusActive, classStart, classStart).
+		Where("remaining_credits IS NULL OR remaining_credits > 0").
+		Order("remaining_credits IS NULL DESC, expires_at ASC").
+		First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return booking.ErrNoCredits
+		}
+		return fmt.Errorf("查询会员卡失败: %w", err)
+	}
+
+	b.SubscriptionID = &sub.ID
+	if sub.Unlimited() {
+		return nil
+	}
+	return changeCredits(tx, &sub, &b.ID, -1, membership.ReasonBooking, "", b.UserID)
+}
+
+// hasUsableSubscription 用户是否有可用于该时间上课的会员卡（不扣课时）
+func hasUsableSubscription(tx *gorm.DB, userID string, classStart time.Time) (bool, error) {
+	var count int64
+	if err := tx.Model(&membership.Subscription{}).
+		Where("user_id = ? AND status = ? AND starts_at <= ? AND expires_at > ?",
+			userID, membership.StatusActive, classStart, classStart).
+		Where("remaining_credits IS NULL OR remaining_credits > 0").
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("查询会员卡失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// refundCredit 在事务内退还预订扣除的课时，期限卡与未扣课时的预订无需处理
+func refundCredit(tx *gorm.DB, b *booking.Booking, note, by string) error {
+	if b.SubscriptionID == nil {
+		return nil
+	}
+
+	var sub membership.Subscription
+	if err := lockSubscription(tx, *b.SubscriptionID, &sub); err != nil {
+		return err
+	}
+	if sub.Unlimited() {
+		return nil
+	}
+	return changeCredits(tx, &sub, &b.ID, 1, membership.ReasonRefund, note, by)
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/notification"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository 通知仓储：通知偏好与上课提醒发送记录
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository 创建通知仓储
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetPreference 获取用户的通知偏好，未设置时返回默认偏好
+func (r *NotificationRepository) GetPreference(ctx context.Context, userID string) (*notification.Preference, error) {
+	var p notification.Preference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notification.DefaultPreference(userID), nil
+		}
+		return nil, fmt.Errorf("查询通知偏好失败: %w", err)
+	}
+	return &p, nil
+}
+
+// SavePreference 保存用户的通知偏好
+func (r *NotificationRepository) SavePreference(ctx context.Context, p *notification.Preference) error {
+	p.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, UpdateAll: true}).
+		Create(p).Error; err != nil {
+		return fmt.Errorf("保存通知偏好失败: %w", err)
+	}
+	return nil
+}
+
+// ClaimReminders 认领在(now, now+lead]内开课、尚未提醒的已确认预订，最多limit个。
+// 认领即写入发送记录，多个实例并发执行时每个预订只被一个实例认领；发送失败不重试，避免重复提醒。
+func (r *NotificationRepository) ClaimReminders(ctx context.Context, now time.Time, lead time.Duration, limit int) ([]*booking.Booking, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO booking_reminders (booking_id, class_start, sent_at)
+		SELECT b.id, c.start_time, ?
+		FROM bookings b
+		JOIN classes c ON c.id = b.class_id
+		WHERE b.status = 'confirmed' AND c.status = 'scheduled'
+			AND c.start_time > ? AND c.start_time <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM booking_reminders r WHERE r.booking_id = b.id AND r.class_start = c.start_time
+			)
+		ORDER BY c.start_time ASC
+		LIMIT ?
+		ON CONFLICT DO NOTHING
+		RETURNING booking_id`,
+		now, now, now.Add(lead), limit,
+	).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("认领上课提醒失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var bookings []*booking.Booking
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("查询预订失败: %w", err)
+	}
+	return bookings, nil
+}
+
+// ListActiveBookings 列出课程下已确认和候补中的预订
+func (r *NotificationRepository) ListActiveBookings(ctx context.Context, classID uuid.UUID) ([]*booking.Booking, error) {
+	var bookings []*booking.Booking
+	if err := r.db.WithContext(ctx).
+		Where("class_id = ? AND status IN ?", classID, []string{"confirmed", "waitlisted"}).
+		Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("查询课程预订失败: %w", err)
+	}
+	return bookings, nil
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/membership"
+	"github.com/yoga/knowledge-base/internal/domain/order"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderRepository 订单仓储实现
+type OrderRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderRepository 创建订单仓储
+func NewOrderRepository(db *gorm.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+// CreateOrder 创建订单
+func (r *OrderRepository) CreateOrder(ctx context.Context, o *order.Order) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	now := time.Now()
+	o.CreatedAt = now
+	o.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(o).Error; err != nil {
+		return fmt.Errorf("创建订单失败: %w", err)
+	}
+	return nil
+}
+
+// GetOrder 获取订单
+func (r *OrderRepository) GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	return r.findOrder(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+// GetOrderByNo 按商户订单号获取订单
+func (r *OrderRepository) GetOrderByNo(ctx context.Context, orderNo string) (*order.Order, error) {
+	return r.findOrder(r.db.WithContext(ctx).Where("order_no = ?", orderNo))
+}
+
+func (r *OrderRepository) findOrder(query *gorm.DB) (*order.Order, error) {
+	var o order.Order
+	if err := query.First(&o).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, order.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	return &o, nil
+}
+
+// ListUserOrders 列出用户的订单，按下单时间倒序
+func (r *OrderRepository) ListUserOrders(ctx context.Context, userID string) ([]*order.Order, error) {
+	var orders []*order.Order
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	return orders, nil
+}
+
+// ListExpiredOrders 列出已过支付截止时间仍待支付的订单，最多limit条
+func (r *OrderRepository) ListExpiredOrders(ctx context.Context, now time.Time, limit int) ([]*order.Order, error) {
+	var orders []*order.Order
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", order.StatusPending, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("查询超时订单失败: %w", err)
+	}
+	return orders, nil
+}
+
+// MarkPaid 将订单标记为已支付并在同一事务内按方案开卡，返回订单及本次是否发生了状态变化。
+// 锁定订单行后判断状态：已支付或已退款的订单直接返回（重复的支付通知），保证每个订单只开一次卡。
+func (r *OrderRepository) MarkPaid(ctx context.Context, orderNo, transactionID string, paidAt time.Time) (*order.Order, bool, error) {
+	var o order.Order
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx.Where("order_no = ?", orderNo), &o); err != nil {
+			return err
+		}
+		if o.Status == order.StatusPaid || o.Status == order.StatusRefunded {
+			return nil
+		}
+		if !order.CanTransition(o.Status, order.StatusPaid) {
+			return order.ErrInvalidTransition
+		}
+
+		// 付款已完成，方案此后停售也照常开卡
+		var plan membership.Plan
+		if err := tx.Where("id = ?", o.PlanID).First(&plan).Error; err != nil {
+			return fmt.Errorf("查询会员卡方案失败: %w", err)
+		}
+		sub := membership.NewSubscription(&plan, o.UserID, paidAt)
+		if err := createSubscription(tx, sub, "订单"+o.OrderNo, o.UserID); err != nil {
+			return err
+		}
+
+		o.Status = order.StatusPaid
+		o.TransactionID = transactionID
+		o.PaidAt = &paidAt
+		o.SubscriptionID = &sub.ID
+		changed = true
+		return tx.Model(&o).Updates(map[string]interface{}{
+			"status":          o.Status,
+			"transaction_id":  transactionID,
+			"paid_at":         paidAt,
+			"subscription_id": sub.ID,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &o, changed, nil
+}
+
+// CloseOrder 关闭待支付的订单
+func (r *OrderRepository) CloseOrder(ctx context.Context, id uuid.UUID, at time.Time) (*order.Order, error) {
+	var o order.Order
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx.Where("id = ?", id), &o); err != nil {
+			return err
+		}
+		if !order.CanTransition(o.Status, order.StatusClosed) {
+			return order.ErrInvalidTransition
+		}
+
+		o.Status = order.StatusClosed
+		o.ClosedAt = &at
+		return tx.Model(&o).Updates(map[string]interface{}{
+			"status":    o.Status,
+			"closed_at": at,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// MarkRefunded 将已支付的订单标记为已退款，并作废支付开出的会员卡。已退款的订单直接返回。
+func (r *OrderRepository) MarkRefunded(ctx context.Context, id uuid.UUID, refundNo string, at time.Time) (*order.Order, error) {
+	var o order.Order
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx.Where("id = ?", id), &o); err != nil {
+			return err
+		}
+		if o.Status == order.StatusRefunded {
+			return nil
+		}
+		if !order.CanTransition(o.Status, order.StatusRefunded) {
+			return order.ErrInvalidTransition
+		}
+
+		if o.SubscriptionID != nil {
+			if err := tx.Model(&membership.Subscription{}).
+				Where("id = ?", *o.SubscriptionID).
+				Update("status", membership.StatusCancelled).Error; err != nil {
+				return fmt.Errorf("作废会员卡失败: %w", err)
+			}
+		}
+
+		o.Status = order.StatusRefunded
+		o.RefundNo = refundNo
+		o.RefundedAt = &at
+		return tx.Model(&o).Updates(map[string]interface{}{
+			"status":      o.Status,
+			"refund_no":   refundNo,
+			"refunded_at": at,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// lockOrder 在事务内锁定并读取订单
+func lockOrder(query *gorm.DB, o *order.Order) error {
+	if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).First(o).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return order.ErrOrderNotFound
+		}
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	return nil
+}
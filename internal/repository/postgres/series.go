@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateSeries 创建课程系列
+func (r *BookingRepository) CreateSeries(ctx context.Context, series *booking.ClassSeries) error {
+	return createSeries(r.db.WithContext(ctx), series)
+}
+
+// createSeries 在给定连接（或事务）上创建课程系列
+func createSeries(db *gorm.DB, series *booking.ClassSeries) error {
+	if series.ID == uuid.Nil {
+		series.ID = uuid.New()
+	}
+	now := time.Now()
+	series.CreatedAt = now
+	series.UpdatedAt = now
+
+	if err := db.Create(series).Error; err != nil {
+		return fmt.Errorf("创建课程系列失败: %w", err)
+	}
+	return nil
+}
+
+// GetSeries 获取课程系列
+func (r *BookingRepository) GetSeries(ctx context.Context, id uuid.UUID) (*booking.ClassSeries, error) {
+	var series booking.ClassSeries
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("课程系列不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询课程系列失败: %w", err)
+	}
+	return &series, nil
+}
+
+// ListSeries 列出课程系列；activeOn非空时只返回在该日期及之后仍有课的系列
+func (r *BookingRepository) ListSeries(ctx context.Context, activeOn *time.Time, limit, offset int) ([]*booking.ClassSeries, error) {
+	var series []*booking.ClassSeries
+	query := r.db.WithContext(ctx)
+	if activeOn != nil {
+		query = query.Where("end_date IS NULL OR end_date >= ?", activeOn.Format(booking.DateLayout))
+	}
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	if err := query.Order("created_at ASC").Find(&series).Error; err != nil {
+		return nil, fmt.Errorf("查询课程系列列表失败: %w", err)
+	}
+	return series, nil
+}
+
+// InsertSeriesClasses 批量写入系列生成的课程，(series_id, series_date)已存在的跳过
+// （包括已被单独取消或调整过的课程），返回实际新增的数量
+func (r *BookingRepository) InsertSeriesClasses(ctx context.Context, classes []*booking.Class) (int, error) {
+	if len(classes) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	for _, class := range classes {
+		if class.ID == uuid.Nil {
+			class.ID = uuid.New()
+		}
+		class.CreatedAt = now
+		class.UpdatedAt = now
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&classes)
+	if result.Error != nil {
+		return 0, fmt.Errorf("生成系列课程失败: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// ReviseSeries 保存系列的修改，并在同一事务内按新规则调整from之后尚未开始的已生成课程：
+// successor非空时（“此节及以后”拆分），current应已截止到from前一天，successor为新系列，
+// 相关课程改挂到successor下。按新规则不再上课的课程连同其预订一起取消。
+// 返回调整的课程数、被取消的课程及其预订、更换了授课教练的课程，供调用方在提交后通知会员。
+func (r *BookingRepository) ReviseSeries(ctx context.Context, current, successor *booking.ClassSeries, from time.Time, cancelledBy, reason string) (*booking.SeriesChanges, error) {
+	changes := &booking.SeriesChanges{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current.UpdatedAt = time.Now()
+		if err := tx.Omit("created_at").Save(current).Error; err != nil {
+			return fmt.Errorf("更新课程系列失败: %w", err)
+		}
+
+		target := current
+		if successor != nil {
+			if err := createSeries(tx, successor); err != nil {
+				return err
+			}
+			target = successor
+		}
+
+		var classes []*booking.Class
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("series_id = ? AND series_date >= ? AND status = ? AND start_time > ?",
+				current.ID, from.Format(booking.DateLayout), "scheduled", time.Now()).
+			Order("start_time ASC").
+			Find(&classes).Error; err != nil {
+			return fmt.Errorf("查询系列课程失败: %w", err)
+		}
+
+		for _, class := range classes {
+			next := target.NewClass(*class.SeriesDate)
+			if next == nil {
+				bookings, err := cancelClass(tx, class, cancelledBy, reason)
+				if err != nil {
+					return err
+				}
+				changes.Cancelled = append(changes.Cancelled, booking.ClassCancellation{Class: class, Bookings: bookings})
+				continue
+			}
+
+			if next.Capacity < class.BookedCount {
+				return fmt.Errorf("%w: %s的课程已有%d人预订", booking.ErrCapacityBelowBooked,
+					class.SeriesDate.Format(booking.DateLayout), class.BookedCount)
+			}
+
+			previous := class.Instructor
+			class.Name = next.Name
+			class.Description = next.Description
+			class.Instructor = next.Instructor
+			class.InstructorID = next.InstructorID
+			class.InstructorUserID = next.InstructorUserID
+			class.RoomID = next.RoomID
+			class.StartTime = next.StartTime
+			class.EndTime = next.EndTime
+			class.Capacity = next.Capacity
+			class.SeriesID = next.SeriesID
+
+			conflict, err := findInstructorConflict(tx, class)
+			if err != nil {
+				return err
+			}
+			if conflict != nil {
+				return fmt.Errorf("%w: %s与课程「%s」时间重叠", booking.ErrInstructorConflict,
+					class.SeriesDate.Format(booking.DateLayout), conflict.Name)
+			}
+			conflict, err = findRoomConflict(tx, class)
+			if err != nil {
+				return err
+			}
+			if conflict != nil {
+				return fmt.Errorf("%w: %s与课程「%s」时间重叠", booking.ErrRoomConflict,
+					class.SeriesDate.Format(booking.DateLayout), conflict.Name)
+			}
+
+			class.UpdatedAt = time.Now()
+			if err := tx.Omit("booked_count", "status", "created_at").Save(class).Error; err != nil {
+				if conflictErr := classOverlapError(err); conflictErr != nil {
+					return conflictErr
+				}
+				return fmt.Errorf("更新系列课程失败: %w", err)
+			}
+			changes.Updated++
+			if class.Instructor != previous {
+				changes.InstructorChanges = append(changes.InstructorChanges, booking.InstructorChange{Class: class, Previous: previous})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"gorm.io/gorm"
+)
+
+// UserRepository 用户仓储接口实现
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository 创建用户仓储
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// GetUser 获取用户
+func (r *UserRepository) GetUser(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	var u user.User
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&u).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("用户不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	return &u, nil
+}
+
+// UpsertWeChatUser 按openid创建或更新用户并记录登录时间，昵称、头像为空时保留原值。
+// 使用 INSERT ... ON CONFLICT 保证同一openid并发首次登录时只创建一个用户。
+func (r *UserRepository) UpsertWeChatUser(ctx context.Context, openID, unionID, nickname, avatarURL string) (*user.User, error) {
+	now := time.Now()
+	var u user.User
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO users (id, open_id, union_id, nickname, avatar_url, last_login_at, created_at, updated_at)
+		VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?)
+		ON CONFLICT (open_id) DO UPDATE SET
+			union_id = COALESCE(EXCLUDED.union_id, users.union_id),
+			nickname = COALESCE(NULLIF(EXCLUDED.nickname, ''), users.nickname),
+			avatar_url = COALESCE(NULLIF(EXCLUDED.avatar_url, ''), users.avatar_url),
+			last_login_at = EXCLUDED.last_login_at
+		RETURNING *`,
+		uuid.New(), openID, unionID, nickname, avatarURL, now, now, now,
+	).Scan(&u).Error
+	if err != nil {
+		return nil, fmt.Errorf("保存用户失败: %w", err)
+	}
+	return &u, nil
+}
+
+// GetUserByCalendarToken 根据日历订阅令牌获取用户
+func (r *UserRepository) GetUserByCalendarToken(ctx context.Context, token string) (*user.User, error) {
+	var u user.User
+	if err := r.db.WithContext(ctx).Where("calendar_token = ?", token).First(&u).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("用户不存在: %w", err)
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	return &u, nil
+}
+
+// SetCalendarToken 设置用户的日历订阅令牌，原令牌随即失效
+func (r *UserRepository) SetCalendarToken(ctx context.Context, id uuid.UUID, token string) error {
+	if err := r.db.WithContext(ctx).Model(&user.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"calendar_token": token,
+			"updated_at":     time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("保存日历订阅令牌失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateUser 更新用户资料
+func (r *UserRepository) UpdateUser(ctx context.Context, u *user.User) error {
+	u.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Save(u).Error; err != nil {
+		return fmt.Errorf("更新用户失败: %w", err)
+	}
+	return nil
+}
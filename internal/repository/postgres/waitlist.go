@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JoinWaitlist 加入课程候补名单，排在队尾。
+// 锁定课程行后校验课程已满，保证与取消预订时的候补转正互斥。
+// requireCredit为true时要求用户有可用的会员卡（转正时才扣除课时）。
+func (r *BookingRepository) JoinWaitlist(ctx context.Context, b *booking.Booking, requireCredit bool) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	if b.CheckInToken == "" {
+		b.CheckInToken = booking.NewCheckInToken()
+	}
+	now := time.Now()
+	b.CreatedAt = now
+	b.UpdatedAt = now
+	b.Status = "waitlisted"
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var class booking.Class
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", b.ClassID).First(&class).Error; err != nil {
+			return fmt.Errorf("课程不存在: %w", err)
+		}
+
+		if class.Status != "scheduled" || !now.Before(class.StartTime) {
+			return booking.ErrClassNotBookable
+		}
+		if !class.IsFull() {
+			return booking.ErrClassAvailable
+		}
+
+		if booked, err := hasActiveBooking(tx, b.ClassID, b.UserID); err != nil {
+			return err
+		} else if booked {
+			return booking.ErrAlreadyBooked
+		}
+
+		if requireCredit {
+			if usable, err := hasUsableSubscription(tx, b.UserID, class.StartTime); err != nil {
+				return err
+			} else if !usable {
+				return booking.ErrNoCredits
+			}
+		}
+
+		var last int
+		if err := tx.Model(&booking.Booking{}).
+			Where("class_id = ? AND status = ?", b.ClassID, "waitlisted").
+			Select("COALESCE(MAX(waitlist_position), 0)").
+			Scan(&last).Error; err != nil {
+			return fmt.Errorf("查询候补名单失败: %w", err)
+		}
+		position := last + 1
+		b.WaitlistPosition = &position
+
+		if err := tx.Create(b).Error; err != nil {
+			if isUniqueViolation(err, activeBookingIndex) {
+				return booking.ErrAlreadyBooked
+			}
+			return fmt.Errorf("加入候补名单失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListWaitlist 按候补顺序列出课程的候补名单
+func (r *BookingRepository) ListWaitlist(ctx context.Context, classID uuid.UUID) ([]*booking.Booking, error) {
+	var bookings []*booking.Booking
+	if err := r.db.WithContext(ctx).
+		Where("class_id = ? AND status = ?", classID, "waitlisted").
+		Order("waitlist_position ASC").
+		Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("查询候补名单失败: %w", err)
+	}
+	return bookings, nil
+}
+
+// promoteWaitlist 在事务内将候补名单第一位转为已确认并占用一个名额，没有候补时返回nil。
+// requireCredit为true时转正需扣除课时，已没有可用会员卡的候补被移出名单，由下一位递补。
+// 调用方需已锁定课程行。
+func promoteWaitlist(tx *gorm.DB, classID uuid.UUID, requireCredit bool) (*booking.Booking, error) {
+	var class booking.Class
+	if err := tx.Where("id = ?", classID).First(&class).Error; err != nil {
+		return nil, fmt.Errorf("查询课程失败: %w", err)
+	}
+
+	for {
+		var next booking.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("class_id = ? AND status = ?", classID, "waitlisted").
+			Order("waitlist_position ASC").
+			First(&next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("查询候补名单失败: %w", err)
+		}
+		position := *next.WaitlistPosition
+
+		updates := map[string]interface{}{
+			"status":            "confirmed",
+			"waitlist_position": nil,
+		}
+		if requireCredit {
+			err := chargeCredit(tx, &next, class.StartTime)
+			if errors.Is(err, booking.ErrNoCredits) {
+				if err := tx.Model(&next).Updates(map[string]interface{}{
+					"status":            "cancelled",
+					"waitlist_position": nil,
+					"cancel_reason":     "没有可用的会员卡或课时，候补未能转正",
+					"cancelled_at":      time.Now(),
+				}).Error; err != nil {
+					return nil, fmt.Errorf("移出候补名单失败: %w", err)
+				}
+				if err := shiftWaitlist(tx, classID, position); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			updates["subscription_id"] = next.SubscriptionID
+		}
+
+		if err := tx.Model(&next).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("候补转正失败: %w", err)
+		}
+		if err := tx.Model(&booking.Class{}).
+			Where("id = ?", classID).
+			Update("booked_count", gorm.Expr("booked_count + 1")).Error; err != nil {
+			return nil, fmt.Errorf("更新课程预订数量失败: %w", err)
+		}
+		if err := shiftWaitlist(tx, classID, position); err != nil {
+			return nil, err
+		}
+
+		next.Status = "confirmed"
+		next.WaitlistPosition = nil
+		return &next, nil
+	}
+}
+
+// shiftWaitlist 候补名单中position之后的成员依次前移一位
+func shiftWaitlist(tx *gorm.DB, classID uuid.UUID, position int) error {
+	if err := tx.Model(&booking.Booking{}).
+		Where("class_id = ? AND status = ? AND waitlist_position > ?", classID, "waitlisted", position).
+		Update("waitlist_position", gorm.Expr("waitlist_position - 1")).Error; err != nil {
+		return fmt.Errorf("更新候补名单顺序失败: %w", err)
+	}
+	return nil
+}
+
+// hasActiveBooking 用户是否已预订该课程或已在候补名单中
+func hasActiveBooking(tx *gorm.DB, classID uuid.UUID, userID string) (bool, error) {
+	var count int64
+	if err := tx.Model(&booking.Booking{}).
+		Where("class_id = ? AND user_id = ? AND status IN ?", classID, userID, []string{"confirmed", "waitlisted"}).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("查询预订失败: %w", err)
+	}
+	return count > 0, nil
+}
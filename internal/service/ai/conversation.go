@@ -0,0 +1,277 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/ai"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// ConversationRepository 会话仓储接口
+type ConversationRepository interface {
+	CreateConversation(ctx context.Context, conv *ai.Conversation) error
+	GetConversation(ctx context.Context, id uuid.UUID) (*ai.Conversation, error)
+	ListConversations(ctx context.Context, userID string, limit, offset int) ([]*ai.Conversation, error)
+	UpdateConversationSummary(ctx context.Context, id uuid.UUID, summary string, summarizedCount int) error
+	UpdateConversationTitle(ctx context.Context, id uuid.UUID, title string) error
+	DeleteConversation(ctx context.Context, id uuid.UUID) error
+	AddMessages(ctx context.Context, conversationID uuid.UUID, messages ...*ai.Message) error
+	ListMessages(ctx context.Context, conversationID uuid.UUID) ([]*ai.Message, error)
+}
+
+// titleMaxLength 自动生成标题的最大字符数
+const titleMaxLength = 20
+
+// ErrConversationNotFound 会话不存在或不属于当前用户
+var ErrConversationNotFound = errors.New("会话不存在")
+
+// CreateConversation 为当前用户创建会话
+func (s *Service) CreateConversation(ctx context.Context, title string) (*ai.Conversation, error) {
+	ctx, span := observability.StartSpan(ctx, "ai-service", "CreateConversation")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := &ai.Conversation{
+		UserID: u.ID.String(),
+		Title:  title,
+	}
+	if err := s.convRepo.CreateConversation(ctx, conv); err != nil {
+		return nil, fmt.Errorf("创建会话失败: %w", err)
+	}
+	return conv, nil
+}
+
+// GetConversation 获取当前用户的会话及其消息
+func (s *Service) GetConversation(ctx context.Context, id uuid.UUID) (*ai.Conversation, []*ai.Message, error) {
+	ctx, span := observability.StartSpan(ctx, "ai-service", "GetConversation")
+	defer span.End()
+
+	conv, err := s.ownConversation(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages, err := s.convRepo.ListMessages(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conv, messages, nil
+}
+
+// ListConversations 列出当前用户的会话
+func (s *Service) ListConversations(ctx context.Context, limit, offset int) ([]*ai.Conversation, error) {
+	ctx, span := observability.StartSpan(ctx, "ai-service", "ListConversations")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.convRepo.ListConversations(ctx, u.ID.String(), limit, offset)
+}
+
+// DeleteConversation 删除当前用户的会话
+func (s *Service) DeleteConversation(ctx context.Context, id uuid.UUID) error {
+	ctx, span := observability.StartSpan(ctx, "ai-service", "DeleteConversation")
+	defer span.End()
+
+	if _, err := s.ownConversation(ctx, id); err != nil {
+		return err
+	}
+
+	return s.convRepo.DeleteConversation(ctx, id)
+}
+
+// ownConversation 加载会话并校验归属，不属于当前用户时与不存在同样处理，避免泄露会话是否存在
+func (s *Service) ownConversation(ctx context.Context, id uuid.UUID) (*ai.Conversation, error) {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.convRepo.GetConversation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConversationNotFound, err)
+	}
+	if conv.UserID != u.ID.String() {
+		return nil, ErrConversationNotFound
+	}
+	return conv, nil
+}
+
+// loadConversation 若请求指定了会话，则由服务端加载（必要时压缩后的）历史消息替换req.History
+func (s *Service) loadConversation(ctx context.Context, req *ai.ChatRequest) (*ai.Conversation, error) {
+	if req.ConversationID == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(req.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无效的会话ID", ErrConversationNotFound)
+	}
+	conv, err := s.ownConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.convRepo.ListMessages(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if conv.SummarizedCount < len(messages) {
+		messages = messages[conv.SummarizedCount:]
+	} else {
+		messages = nil
+	}
+	messages = s.compactHistory(ctx, conv, messages)
+
+	history := make([]ai.ChatMessage, 0, len(messages)+1)
+	if conv.Summary != "" {
+		history = append(history, ai.ChatMessage{
+			Role:    "system",
+			Content: "以下是本次会话早期内容的摘要：\n" + conv.Summary,
+		})
+	}
+	for _, msg := range messages {
+		history = append(history, ai.ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	req.History = history
+
+	return conv, nil
+}
+
+// compactHistory 历史消息超出token窗口时，将较早的消息合并进会话摘要，只保留最近的消息
+//
+// 摘要失败时直接丢弃较早的消息，保证请求不超出模型上下文。
+func (s *Service) compactHistory(ctx context.Context, conv *ai.Conversation, messages []*ai.Message) []*ai.Message {
+	maxTokens := s.agentCfg.MaxHistoryTokens
+	total := estimateTokens(conv.Summary)
+	for _, msg := range messages {
+		total += estimateTokens(msg.Content)
+	}
+	if total <= maxTokens {
+		return messages
+	}
+
+	// 保留最近约一半窗口的消息，至少保留一条
+	keep, tokens := 0, 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		t := estimateTokens(messages[i].Content)
+		if keep > 0 && tokens+t > maxTokens/2 {
+			break
+		}
+		tokens += t
+		keep++
+	}
+	older, recent := messages[:len(messages)-keep], messages[len(messages)-keep:]
+	if len(older) == 0 {
+		return recent
+	}
+
+	summary, err := s.summarize(ctx, conv.Summary, older)
+	if err != nil {
+		s.logger.Warn("生成会话摘要失败，直接截断历史消息", zap.Error(err), zap.String("conversation_id", conv.ID.String()))
+		return recent
+	}
+
+	conv.Summary = summary
+	conv.SummarizedCount += len(older)
+	if err := s.convRepo.UpdateConversationSummary(ctx, conv.ID, conv.Summary, conv.SummarizedCount); err != nil {
+		s.logger.Warn("保存会话摘要失败", zap.Error(err), zap.String("conversation_id", conv.ID.String()))
+	}
+	return recent
+}
+
+// summarize 将已有摘要与较早的消息合并为新的摘要
+func (s *Service) summarize(ctx context.Context, previous string, messages []*ai.Message) (string, error) {
+	var builder strings.Builder
+	if previous != "" {
+		builder.WriteString("已有摘要：\n")
+		builder.WriteString(previous)
+		builder.WriteString("\n\n")
+	}
+	builder.WriteString("新增对话：\n")
+	for _, msg := range messages {
+		role := "用户"
+		if msg.Role == "assistant" {
+			role = "助手"
+		}
+		builder.WriteString(fmt.Sprintf("%s：%s\n", role, msg.Content))
+	}
+
+	response, err := s.openAIClient.Chat(ctx, []ai.ChatMessage{
+		{Role: "system", Content: "请将以下对话整理为简洁的中文摘要，保留用户的身份信息、偏好、已预订或关注的课程等关键事实，不超过300字，只输出摘要内容。"},
+		{Role: "user", Content: builder.String()},
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.Message), nil
+}
+
+// saveTurn 保存本轮的用户消息与助手回复，首轮对话后自动生成标题
+func (s *Service) saveTurn(ctx context.Context, conv *ai.Conversation, userMessage, reply string) {
+	if err := s.convRepo.AddMessages(ctx, conv.ID,
+		&ai.Message{Role: "user", Content: userMessage},
+		&ai.Message{Role: "assistant", Content: reply},
+	); err != nil {
+		s.logger.Error("保存会话消息失败", zap.Error(err), zap.String("conversation_id", conv.ID.String()))
+		return
+	}
+
+	if conv.Title != "" {
+		return
+	}
+
+	// 标题生成不影响本轮回复，在后台完成
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+
+		title := s.generateTitle(ctx, userMessage, reply)
+		if err := s.convRepo.UpdateConversationTitle(ctx, conv.ID, title); err != nil {
+			s.logger.Warn("保存会话标题失败", zap.Error(err), zap.String("conversation_id", conv.ID.String()))
+		}
+	}()
+}
+
+// generateTitle 根据首轮对话生成会话标题，失败时使用用户消息的开头
+func (s *Service) generateTitle(ctx context.Context, userMessage, reply string) string {
+	response, err := s.openAIClient.Chat(ctx, []ai.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("请为下面的对话生成一个不超过%d个字的中文标题，只输出标题本身，不要标点和引号。", titleMaxLength)},
+		{Role: "user", Content: fmt.Sprintf("用户：%s\n助手：%s", userMessage, reply)},
+	}, nil)
+	if err == nil {
+		if title := strings.Trim(strings.TrimSpace(response.Message), "\"'“”《》。"); title != "" {
+			return truncateRunes(title, titleMaxLength)
+		}
+	} else {
+		s.logger.Warn("生成会话标题失败", zap.Error(err))
+	}
+	return truncateRunes(strings.TrimSpace(userMessage), titleMaxLength)
+}
+
+// estimateTokens 粗略估算文本的token数：中日韩字符按1个计，其他字符约4个计1个
+func estimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
@@ -3,35 +3,56 @@ package ai
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/yoga/knowledge-base/internal/domain/ai"
+	"github.com/yoga/knowledge-base/internal/domain/knowledge"
 	"github.com/yoga/knowledge-base/internal/repository/postgres"
 	"github.com/yoga/knowledge-base/pkg/vector"
 	"go.uber.org/zap"
 )
 
+// candidateFactor 向量检索时多取的倍数，用于弥补同一知识项多次命中去重后的数量损失
+const candidateFactor = 3
+
 // KnowledgeRetriever 知识库检索器实现
 type KnowledgeRetriever struct {
-	vectorClient *vector.Client
-	kbRepo       *postgres.KnowledgeRepository
-	logger       *zap.Logger
+	vectorClient     *vector.Client
+	kbRepo           *postgres.KnowledgeRepository
+	maxContentLength int
+	logger           *zap.Logger
 }
 
-// NewRetriever 创建检索器
-func NewRetriever(vectorClient *vector.Client, kbRepo *postgres.KnowledgeRepository, logger *zap.Logger) Retriever {
+// NewRetriever 创建检索器，maxContentLength为每条来源返回内容的最大字符数
+func NewRetriever(vectorClient *vector.Client, kbRepo *postgres.KnowledgeRepository, maxContentLength int, logger *zap.Logger) Retriever {
+	if maxContentLength <= 0 {
+		maxContentLength = 800
+	}
 	return &KnowledgeRetriever{
-		vectorClient: vectorClient,
-		kbRepo:       kbRepo,
-		logger:       logger,
+		vectorClient:     vectorClient,
+		kbRepo:           kbRepo,
+		maxContentLength: maxContentLength,
+		logger:           logger,
 	}
 }
 
+// hit 单个向量命中
+type hit struct {
+	chunkID     uuid.UUID
+	chunkIndex  int
+	startOffset int
+	endOffset   int
+	score       float64
+}
+
 // Search 检索知识库内容
 func (r *KnowledgeRetriever) Search(ctx context.Context, query string, limit int, knowledgeBaseID string) ([]ai.Source, error) {
 	// 调用向量服务检索
 	searchReq := vector.SearchRequest{
 		Query:           query,
-		Limit:           limit,
+		Limit:           limit * candidateFactor,
 		KnowledgeBaseID: knowledgeBaseID,
 	}
 
@@ -40,27 +61,136 @@ func (r *KnowledgeRetriever) Search(ctx context.Context, query string, limit int
 		return nil, fmt.Errorf("向量检索失败: %w", err)
 	}
 
-	// 转换为Source
-	sources := make([]ai.Source, 0, len(searchResp.Results))
+	// 按知识项归并命中结果
+	var itemOrder []uuid.UUID
+	hitsByItem := make(map[uuid.UUID][]hit)
+	var chunkIDs []uuid.UUID
 	for _, result := range searchResp.Results {
-		// 从payload中提取信息
-		title, _ := result.Payload["title"].(string)
-		itemID, _ := result.Payload["item_id"].(string)
-
-		// 如果需要，可以从数据库获取完整内容
-		var content string
-		if itemID != "" {
-			// TODO: 从数据库获取完整内容
-			content = title // 暂时使用标题
+		itemID, err := uuid.Parse(payloadString(result.Payload, "item_id"))
+		if err != nil {
+			continue
+		}
+
+		h := hit{
+			chunkIndex:  payloadInt(result.Payload, "chunk_index"),
+			startOffset: payloadInt(result.Payload, "start_offset"),
+			endOffset:   payloadInt(result.Payload, "end_offset"),
+			score:       result.Score,
+		}
+		// 分块之前写入的向量没有chunk_id，直接使用知识项内容
+		if chunkID, err := uuid.Parse(payloadString(result.Payload, "chunk_id")); err == nil {
+			h.chunkID = chunkID
+			chunkIDs = append(chunkIDs, chunkID)
 		}
 
+		if _, ok := hitsByItem[itemID]; !ok {
+			itemOrder = append(itemOrder, itemID)
+		}
+		hitsByItem[itemID] = append(hitsByItem[itemID], h)
+	}
+
+	if len(itemOrder) == 0 {
+		return []ai.Source{}, nil
+	}
+
+	// 批量加载知识项与分块
+	items, err := r.kbRepo.GetItemsByIDs(ctx, itemOrder)
+	if err != nil {
+		return nil, err
+	}
+	itemsByID := make(map[uuid.UUID]*knowledge.KnowledgeItem, len(items))
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
+
+	chunks, err := r.kbRepo.GetChunksByIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	chunksByID := make(map[uuid.UUID]*knowledge.KnowledgeChunk, len(chunks))
+	for _, chunk := range chunks {
+		chunksByID[chunk.ID] = chunk
+	}
+
+	// 转换为Source，每个知识项只保留一条
+	sources := make([]ai.Source, 0, len(itemOrder))
+	for _, itemID := range itemOrder {
+		item, ok := itemsByID[itemID]
+		if !ok {
+			// 知识项已被删除，向量尚未清理
+			r.logger.Debug("忽略已删除知识项的检索结果", zap.String("item_id", itemID.String()))
+			continue
+		}
+
+		// 检索结果按分数降序返回，首个命中即为该知识项的最高分
+		hits := hitsByItem[itemID]
+		score := hits[0].score
 		sources = append(sources, ai.Source{
-			ID:      result.ID,
-			Title:   title,
-			Content: content,
-			Score:   result.Score,
+			ID:      item.ID.String(),
+			Title:   item.Title,
+			Content: r.buildContent(item, hits, chunksByID),
+			Score:   score,
 		})
 	}
 
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Score > sources[j].Score
+	})
+	if len(sources) > limit {
+		sources = sources[:limit]
+	}
+
 	return sources, nil
 }
+
+// buildContent 拼接同一知识项的命中片段（按原文顺序），并截断到最大长度
+func (r *KnowledgeRetriever) buildContent(item *knowledge.KnowledgeItem, hits []hit, chunksByID map[uuid.UUID]*knowledge.KnowledgeChunk) string {
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].chunkIndex < hits[j].chunkIndex
+	})
+
+	content := []rune(item.Content)
+	var parts []string
+	for _, h := range hits {
+		if chunk, ok := chunksByID[h.chunkID]; ok {
+			parts = append(parts, chunk.Content)
+			continue
+		}
+		// 分块记录缺失（旧数据或正在重新分块），按payload中的偏移从知识项内容中截取
+		if h.endOffset > h.startOffset && h.endOffset <= len(content) {
+			parts = append(parts, strings.TrimSpace(string(content[h.startOffset:h.endOffset])))
+			continue
+		}
+		parts = append(parts, item.Content)
+		break
+	}
+
+	return truncateRunes(strings.Join(parts, "\n……\n"), r.maxContentLength)
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen]) + "……"
+}
+
+// payloadString 读取payload中的字符串字段
+func payloadString(payload map[string]interface{}, key string) string {
+	v, _ := payload[key].(string)
+	return v
+}
+
+// payloadInt 读取payload中的整数字段（JSON解码后为float64）
+func payloadInt(payload map[string]interface{}, key string) int {
+	switch v := payload[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
@@ -2,11 +2,14 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/yoga/knowledge-base/internal/domain/ai"
 	"github.com/yoga/knowledge-base/internal/repository/postgres"
+	"github.com/yoga/knowledge-base/pkg/mcp"
 	"github.com/yoga/knowledge-base/pkg/observability"
 	"go.uber.org/zap"
 )
@@ -14,6 +17,7 @@ import (
 // OpenAI 客户端接口
 type OpenAIClient interface {
 	Chat(ctx context.Context, messages []ai.ChatMessage, tools []Tool) (*ai.ChatResponse, error)
+	ChatStream(ctx context.Context, messages []ai.ChatMessage, tools []Tool, onDelta func(content string) error) (*ai.ChatResponse, error)
 }
 
 // Retriever 检索器接口（定义在retriever.go中）
@@ -29,8 +33,8 @@ type MCPService interface {
 
 // Tool MCP工具定义（与OpenAI Tool兼容）
 type Tool struct {
-	Type        string                 `json:"type"`
-	Function    FunctionDefinition     `json:"function"`
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
 }
 
 // FunctionDefinition 函数定义
@@ -40,22 +44,42 @@ type FunctionDefinition struct {
 	Parameters  map[string]interface{} `json:"parameters"`
 }
 
+// AgentConfig 工具调用循环配置
+type AgentConfig struct {
+	MaxIterations    int // 单轮对话中最多调用模型的次数
+	MaxToolCalls     int // 单轮对话中最多执行的工具调用次数
+	MaxHistoryTokens int // 会话历史的token上限，超出后较早的消息会被摘要
+}
+
 // Service AI问答服务
 type Service struct {
 	openAIClient OpenAIClient
 	retriever    Retriever
 	mcpSvc       MCPService
 	kbRepo       *postgres.KnowledgeRepository
+	convRepo     ConversationRepository
+	agentCfg     AgentConfig
 	logger       *zap.Logger
 }
 
 // NewService 创建AI问答服务
-func NewService(openAIClient OpenAIClient, retriever Retriever, mcpSvc MCPService, kbRepo *postgres.KnowledgeRepository, logger *zap.Logger) *Service {
+func NewService(openAIClient OpenAIClient, retriever Retriever, mcpSvc MCPService, kbRepo *postgres.KnowledgeRepository, convRepo ConversationRepository, agentCfg AgentConfig, logger *zap.Logger) *Service {
+	if agentCfg.MaxIterations <= 0 {
+		agentCfg.MaxIterations = 5
+	}
+	if agentCfg.MaxToolCalls <= 0 {
+		agentCfg.MaxToolCalls = 8
+	}
+	if agentCfg.MaxHistoryTokens <= 0 {
+		agentCfg.MaxHistoryTokens = 3000
+	}
 	return &Service{
 		openAIClient: openAIClient,
 		retriever:    retriever,
 		mcpSvc:       mcpSvc,
 		kbRepo:       kbRepo,
+		convRepo:     convRepo,
+		agentCfg:     agentCfg,
 		logger:       logger,
 	}
 }
@@ -65,6 +89,146 @@ func (s *Service) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatRespons
 	ctx, span := observability.StartSpan(ctx, "ai-service", "Chat")
 	defer span.End()
 
+	conv, err := s.loadConversation(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, tools, sources := s.prepare(ctx, req)
+
+	response, err := s.runAgent(ctx, messages, tools, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 添加知识库来源
+	response.Sources = sources
+
+	if conv != nil {
+		response.ConversationID = conv.ID.String()
+		s.saveTurn(ctx, conv, req.Message, response.Message)
+	}
+
+	return response, nil
+}
+
+// ChatStream 以流式方式处理聊天请求，依次通过emit推送来源、文本增量、工具调用与结果事件。
+// emit返回错误（如客户端断开）或ctx取消时立即停止，并中断上游模型请求。
+func (s *Service) ChatStream(ctx context.Context, req ai.ChatRequest, emit func(ai.StreamEvent) error) error {
+	ctx, span := observability.StartSpan(ctx, "ai-service", "ChatStream")
+	defer span.End()
+
+	conv, err := s.loadConversation(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	messages, tools, sources := s.prepare(ctx, req)
+
+	if err := emit(ai.StreamEvent{Type: ai.StreamEventSources, Data: sources}); err != nil {
+		return err
+	}
+
+	response, err := s.runAgent(ctx, messages, tools, emit)
+	if err != nil {
+		return err
+	}
+
+	if conv != nil {
+		response.ConversationID = conv.ID.String()
+		s.saveTurn(ctx, conv, req.Message, response.Message)
+	}
+
+	return emit(ai.StreamEvent{Type: ai.StreamEventDone, Data: response})
+}
+
+// runAgent 执行工具调用循环：模型发起工具调用时执行工具，并将assistant的tool_calls消息
+// 与各工具的tool消息追加到上下文后再次调用模型，直到模型给出最终回答。
+// 达到最大迭代次数或工具调用预算耗尽时，最后一次调用不再提供工具，迫使模型直接作答。
+// emit不为nil时使用流式接口，并推送文本增量与工具调用事件。
+func (s *Service) runAgent(ctx context.Context, messages []ai.ChatMessage, tools []Tool, emit func(ai.StreamEvent) error) (*ai.ChatResponse, error) {
+	var executed []ai.ToolCall
+	budget := s.agentCfg.MaxToolCalls
+
+	for iteration := 1; ; iteration++ {
+		roundTools := tools
+		if iteration >= s.agentCfg.MaxIterations || budget <= 0 || s.mcpSvc == nil {
+			roundTools = nil
+		}
+
+		var (
+			response *ai.ChatResponse
+			err      error
+		)
+		if emit != nil {
+			response, err = s.openAIClient.ChatStream(ctx, messages, roundTools, func(content string) error {
+				return emit(ai.StreamEvent{Type: ai.StreamEventDelta, Data: content})
+			})
+		} else {
+			response, err = s.openAIClient.Chat(ctx, messages, roundTools)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("AI聊天失败: %w", err)
+		}
+
+		if len(response.ToolCalls) == 0 || roundTools == nil {
+			response.ToolCalls = executed
+			return response, nil
+		}
+
+		messages = append(messages, ai.ChatMessage{
+			Role:      "assistant",
+			Content:   response.Message,
+			ToolCalls: response.ToolCalls,
+		})
+
+		for i := range response.ToolCalls {
+			toolCall := &response.ToolCalls[i]
+			if emit != nil {
+				if err := emit(ai.StreamEvent{Type: ai.StreamEventToolCall, Data: toolCall}); err != nil {
+					return nil, err
+				}
+			}
+
+			if budget > 0 {
+				s.callTool(ctx, toolCall)
+				budget--
+			} else {
+				toolCall.Result = map[string]interface{}{"error": "本轮对话的工具调用次数已达上限，请根据已有信息直接回答"}
+			}
+			executed = append(executed, *toolCall)
+
+			if emit != nil {
+				if err := emit(ai.StreamEvent{Type: ai.StreamEventToolResult, Data: toolCall}); err != nil {
+					return nil, err
+				}
+			}
+
+			messages = append(messages, ai.ChatMessage{
+				Role:       "tool",
+				Content:    toolResultContent(toolCall.Result),
+				ToolCallID: toolCall.ID,
+			})
+		}
+
+		s.logger.Debug("工具调用完成，继续请求模型",
+			zap.Int("iteration", iteration),
+			zap.Int("tool_calls", len(response.ToolCalls)),
+			zap.Int("remaining_budget", budget))
+	}
+}
+
+// toolResultContent 将工具结果序列化为tool消息内容
+func toolResultContent(result interface{}) string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": "序列化工具结果失败: " + err.Error()})
+	}
+	return string(data)
+}
+
+// prepare 检索知识库、获取工具列表并构建发送给模型的消息
+func (s *Service) prepare(ctx context.Context, req ai.ChatRequest) ([]ai.ChatMessage, []Tool, []ai.Source) {
 	// 1. 从知识库检索相关内容
 	var sources []ai.Source
 	if req.Message != "" {
@@ -86,32 +250,39 @@ func (s *Service) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatRespons
 	}
 
 	// 3. 构建消息历史
-	messages := s.buildMessages(req, sources)
+	return s.buildMessages(req, sources), tools, sources
+}
 
-	// 4. 调用OpenAI
-	response, err := s.openAIClient.Chat(ctx, messages, tools)
-	if err != nil {
-		return nil, fmt.Errorf("AI聊天失败: %w", err)
+// callTool 执行单个工具调用，并将结果（或错误）写回toolCall
+func (s *Service) callTool(ctx context.Context, toolCall *ai.ToolCall) {
+	if s.mcpSvc == nil {
+		return
 	}
 
-	// 5. 处理工具调用
-	if len(response.ToolCalls) > 0 && s.mcpSvc != nil {
-		for i := range response.ToolCalls {
-			toolCall := &response.ToolCalls[i]
-			result, err := s.mcpSvc.CallTool(ctx, toolCall.Name, toolCall.Arguments)
-			if err != nil {
-				s.logger.Error("工具调用失败", zap.Error(err), zap.String("tool", toolCall.Name))
-				toolCall.Result = map[string]interface{}{"error": err.Error()}
-			} else {
-				toolCall.Result = result
+	result, err := s.mcpSvc.CallTool(ctx, toolCall.Name, toolCall.Arguments)
+	if err != nil {
+		// 参数校验错误返回逐项说明，模型可据此修正参数后重新调用
+		var validationErr *mcp.ValidationError
+		if errors.As(err, &validationErr) {
+			s.logger.Warn("工具参数校验失败", zap.Error(err), zap.String("tool", toolCall.Name))
+			toolCall.Result = map[string]interface{}{
+				"error":             err.Error(),
+				"invalid_arguments": validationErr.Errors,
 			}
+			return
+		}
+		// 业务错误（如超过取消截止时间）附带错误码，由模型向用户解释
+		var toolErr *mcp.ToolError
+		if errors.As(err, &toolErr) {
+			s.logger.Info("工具返回业务错误", zap.Error(err), zap.String("tool", toolCall.Name), zap.String("code", toolErr.Code))
+			toolCall.Result = map[string]interface{}{"error": err.Error(), "code": toolErr.Code}
+			return
 		}
+		s.logger.Error("工具调用失败", zap.Error(err), zap.String("tool", toolCall.Name))
+		toolCall.Result = map[string]interface{}{"error": err.Error()}
+	} else {
+		toolCall.Result = result
 	}
-
-	// 添加知识库来源
-	response.Sources = sources
-
-	return response, nil
 }
 
 // buildMessages 构建消息列表
@@ -157,4 +328,3 @@ func (s *Service) buildSystemPrompt(sources []ai.Source) string {
 
 	return builder.String()
 }
-
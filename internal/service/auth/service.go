@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/auth"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"github.com/yoga/knowledge-base/pkg/wechat"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrLoginFailed 微信登录失败（code无效或已使用）
+	ErrLoginFailed = errors.New("微信登录失败")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrInvalidRole 未定义的角色
+	ErrInvalidRole = errors.New("无效的角色")
+	// ErrInvalidCalendarToken 日历订阅令牌无效或已重置
+	ErrInvalidCalendarToken = errors.New("无效的日历订阅地址")
+)
+
+// Repository 用户仓储接口
+type Repository interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*user.User, error)
+	UpsertWeChatUser(ctx context.Context, openID, unionID, nickname, avatarURL string) (*user.User, error)
+	UpdateUser(ctx context.Context, u *user.User) error
+	GetUserByCalendarToken(ctx context.Context, token string) (*user.User, error)
+	SetCalendarToken(ctx context.Context, id uuid.UUID, token string) error
+}
+
+// WeChatClient 微信code2session接口，生产环境为wechat.Client，本地开发与测试使用wechat.FakeClient
+type WeChatClient interface {
+	Code2Session(ctx context.Context, code string) (*wechat.Session, error)
+}
+
+// LoginResult 登录结果
+type LoginResult struct {
+	Token     string     `json:"token"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	User      *user.User `json:"user"`
+}
+
+// Service 认证服务
+type Service struct {
+	repo   Repository
+	wechat WeChatClient
+	signer *auth.TokenSigner
+	logger *zap.Logger
+}
+
+// NewService 创建认证服务
+func NewService(repo Repository, wechatClient WeChatClient, signer *auth.TokenSigner, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		wechat: wechatClient,
+		signer: signer,
+		logger: logger,
+	}
+}
+
+// LoginWithWeChat 用小程序登录code换取会话令牌，首次登录时创建用户
+func (s *Service) LoginWithWeChat(ctx context.Context, code, nickname, avatarURL string) (*LoginResult, error) {
+	ctx, span := observability.StartSpan(ctx, "auth-service", "LoginWithWeChat")
+	defer span.End()
+
+	session, err := s.wechat.Code2Session(ctx, code)
+	if err != nil {
+		s.logger.Warn("code2session失败", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+
+	u, err := s.repo.UpsertWeChatUser(ctx, session.OpenID, session.UnionID, nickname, avatarURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.signer.Issue(u.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      u,
+	}, nil
+}
+
+// Authenticate 校验会话令牌并加载对应用户
+func (s *Service) Authenticate(ctx context.Context, token string) (*user.User, error) {
+	claims, err := s.signer.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	return s.repo.GetUser(ctx, userID)
+}
+
+// UpdateProfile 更新当前用户的昵称与头像，空值表示不修改
+func (s *Service) UpdateProfile(ctx context.Context, nickname, avatarURL string) (*user.User, error) {
+	ctx, span := observability.StartSpan(ctx, "auth-service", "UpdateProfile")
+	defer span.End()
+
+	current, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.GetUser(ctx, current.ID)
+	if err != nil {
+		return nil, err
+	}
+	if nickname != "" {
+		u.Nickname = nickname
+	}
+	if avatarURL != "" {
+		u.AvatarURL = avatarURL
+	}
+	if err := s.repo.UpdateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SetUserRole 设置用户角色，仅管理员可操作；管理员不能修改自己的角色，避免误操作后无人可管理
+func (s *Service) SetUserRole(ctx context.Context, userID uuid.UUID, role user.Role) (*user.User, error) {
+	ctx, span := observability.StartSpan(ctx, "auth-service", "SetUserRole")
+	defer span.End()
+
+	current, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !current.IsAdmin() || current.ID == userID {
+		return nil, user.ErrForbidden
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	u, err := s.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUserNotFound, err)
+	}
+	u.Role = role
+	if err := s.repo.UpdateUser(ctx, u); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("用户角色已变更",
+		zap.String("user_id", u.ID.String()),
+		zap.String("role", string(role)),
+		zap.String("operator", current.ID.String()),
+	)
+	return u, nil
+}
+
+// CalendarToken 获取当前用户的日历订阅令牌，尚未生成或reset为true时生成新令牌（原订阅地址随即失效）
+func (s *Service) CalendarToken(ctx context.Context, reset bool) (string, error) {
+	ctx, span := observability.StartSpan(ctx, "auth-service", "CalendarToken")
+	defer span.End()
+
+	current, err := user.Require(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if !reset {
+		u, err := s.repo.GetUser(ctx, current.ID)
+		if err != nil {
+			return "", err
+		}
+		if u.CalendarToken != nil && *u.CalendarToken != "" {
+			return *u.CalendarToken, nil
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成日历订阅令牌失败: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	if err := s.repo.SetCalendarToken(ctx, current.ID, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// AuthenticateCalendarToken 根据日历订阅令牌加载用户，日历客户端无法携带会话令牌，以订阅地址中的令牌识别用户
+func (s *Service) AuthenticateCalendarToken(ctx context.Context, token string) (*user.User, error) {
+	if token == "" {
+		return nil, ErrInvalidCalendarToken
+	}
+	u, err := s.repo.GetUserByCalendarToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendarToken, err)
+	}
+	return u, nil
+}
@@ -0,0 +1,138 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// completeBatchSize 每次结束课程任务最多处理的课程数，其余留给下一次执行
+const completeBatchSize = 100
+
+// CheckInByToken 工作人员扫描会员的签到码签到（管理员，或该课程的授课教练），开课前至下课前有效
+func (s *Service) CheckInByToken(ctx context.Context, token string) (*booking.Attendance, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CheckInByToken")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.repo.GetBookingByCheckInToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := s.manageableClass(ctx, b.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.cfg.CheckIn.StaffOpen(class, time.Now()) {
+		return nil, booking.ErrCheckInNotOpen
+	}
+
+	return s.checkIn(ctx, b, booking.CheckInByQRCode, u)
+}
+
+// CheckIn 按预订签到：会员本人在自助签到时间内签到，工作人员可为其管理课程下的预订签到至下课
+func (s *Service) CheckIn(ctx context.Context, bookingID uuid.UUID) (*booking.Attendance, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CheckIn")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.repo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := s.repo.GetClass(ctx, b.ClassID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrClassNotFound, err)
+	}
+
+	now := time.Now()
+	if b.UserID == u.ID.String() {
+		if !s.cfg.CheckIn.SelfOpen(class, now) {
+			return nil, booking.ErrCheckInNotOpen
+		}
+		return s.checkIn(ctx, b, booking.CheckInBySelf, u)
+	}
+
+	if !class.ManageableBy(u) {
+		return nil, user.ErrForbidden
+	}
+	if !s.cfg.CheckIn.StaffOpen(class, now) {
+		return nil, booking.ErrCheckInNotOpen
+	}
+	return s.checkIn(ctx, b, booking.CheckInByStaff, u)
+}
+
+// checkIn 记录签到
+func (s *Service) checkIn(ctx context.Context, b *booking.Booking, method string, by *user.User) (*booking.Attendance, error) {
+	a := &booking.Attendance{
+		BookingID:   b.ID,
+		Method:      method,
+		CheckedInBy: by.ID.String(),
+		CheckedInAt: time.Now(),
+	}
+	if err := s.repo.CheckIn(ctx, a); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("会员已签到",
+		zap.String("booking_id", b.ID.String()),
+		zap.String("class_id", b.ClassID.String()),
+		zap.String("method", method),
+	)
+	return a, nil
+}
+
+// ClassAttendance 查看课程点名表（管理员，或该课程的授课教练）
+func (s *Service) ClassAttendance(ctx context.Context, classID uuid.UUID) ([]*booking.RosterEntry, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "ClassAttendance")
+	defer span.End()
+
+	if _, err := s.manageableClass(ctx, classID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListAttendance(ctx, classID)
+}
+
+// CompleteEndedClasses 将已下课的课程标记为已完成，未签到的已确认预订记为爽约，返回处理的课程数。
+// 由定时任务调用，单节课程失败时记录日志并继续处理其余课程。
+func (s *Service) CompleteEndedClasses(ctx context.Context) (int, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CompleteEndedClasses")
+	defer span.End()
+
+	classes, err := s.repo.ListEndedClasses(ctx, time.Now(), completeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, class := range classes {
+		if err := s.completeClass(ctx, class); err != nil {
+			// 课程已被手动结束或取消
+			if errors.Is(err, booking.ErrClassNotEditable) {
+				continue
+			}
+			s.logger.Error("结束课程失败", zap.String("class_id", class.ID.String()), zap.Error(err))
+			continue
+		}
+		completed++
+	}
+	return completed, nil
+}
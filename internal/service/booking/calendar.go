@@ -0,0 +1,141 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/pkg/ical"
+	"github.com/yoga/knowledge-base/pkg/observability"
+)
+
+const (
+	// calendarProdID 日历订阅源的生成方标识
+	calendarProdID = "-//Yoga Studio//Booking//ZH"
+	// calendarRefresh 建议日历客户端刷新订阅的间隔
+	calendarRefresh = time.Hour
+	// calendarMaxEvents 日历订阅源最多包含的事件数
+	calendarMaxEvents = 500
+	// calendarUIDDomain 事件UID的域名部分
+	calendarUIDDomain = "yoga-knowledge-base"
+)
+
+// ClassCalendar 场馆课程表的日历订阅源，包含start至end之间的课程，已取消的课程标记为CANCELLED
+func (s *Service) ClassCalendar(ctx context.Context, start, end time.Time) (*ical.Calendar, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "ClassCalendar")
+	defer span.End()
+
+	classes, err := s.ListClasses(ctx, &start, &end, nil, calendarMaxEvents, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := s.newCalendar("课程表")
+	for _, class := range classes {
+		ev := s.classEvent(class)
+		ev.UID = fmt.Sprintf("class-%s@%s", class.ID, calendarUIDDomain)
+		cal.Events = append(cal.Events, ev)
+	}
+	return cal, nil
+}
+
+// UserCalendar 当前登录用户预订的日历订阅源，每个预订一个事件（UID按预订生成，取消或改期后客户端按UID更新）。
+// 已取消的预订和课程标记为CANCELLED，候补中的预订标记为TENTATIVE。
+func (s *Service) UserCalendar(ctx context.Context) (*ical.Calendar, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "UserCalendar")
+	defer span.End()
+
+	bookings, err := s.ListUserBookings(ctx, calendarMaxEvents, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := s.newCalendar("我的瑜伽课")
+	classes := make(map[uuid.UUID]*booking.Class)
+	for _, b := range bookings {
+		class, ok := classes[b.ClassID]
+		if !ok {
+			class, err = s.repo.GetClass(ctx, b.ClassID)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrClassNotFound, err)
+			}
+			if err := s.withRooms(ctx, class); err != nil {
+				return nil, err
+			}
+			classes[b.ClassID] = class
+		}
+
+		ev := s.classEvent(class)
+		ev.UID = fmt.Sprintf("booking-%s@%s", b.ID, calendarUIDDomain)
+		ev.Created = b.CreatedAt
+		if b.UpdatedAt.After(ev.LastModified) {
+			ev.LastModified = b.UpdatedAt
+		}
+		ev.Sequence = sequence(b.CreatedAt, ev.LastModified)
+		switch {
+		case b.Status == "cancelled" || class.Status == "cancelled":
+			ev.Status = ical.StatusCancelled
+		case b.Status == "waitlisted":
+			ev.Status = ical.StatusTentative
+			ev.Summary = "[候补] " + ev.Summary
+		}
+		cal.Events = append(cal.Events, ev)
+	}
+	return cal, nil
+}
+
+// newCalendar 创建日历，默认显示时区为场馆时区
+func (s *Service) newCalendar(name string) *ical.Calendar {
+	return &ical.Calendar{
+		ProdID:   calendarProdID,
+		Name:     name,
+		TimeZone: s.cfg.Location.String(),
+		Refresh:  calendarRefresh,
+	}
+}
+
+// classEvent 课程对应的日历事件（未设置UID）
+func (s *Service) classEvent(class *booking.Class) ical.Event {
+	var desc []string
+	if class.Instructor != "" {
+		desc = append(desc, "教练："+class.Instructor)
+	}
+	if class.Description != "" {
+		desc = append(desc, class.Description)
+	}
+
+	var where string
+	if class.Room != nil {
+		where = class.Room.Label()
+		if class.Room.Location != nil && class.Room.Location.Address != "" {
+			where += "，" + class.Room.Location.Address
+		}
+	}
+
+	status := ical.StatusConfirmed
+	if class.Status == "cancelled" {
+		status = ical.StatusCancelled
+	}
+	return ical.Event{
+		Summary:      class.Name,
+		Description:  strings.Join(desc, "\n"),
+		Location:     where,
+		Start:        class.StartTime,
+		End:          class.EndTime,
+		Status:       status,
+		Sequence:     sequence(class.CreatedAt, class.UpdatedAt),
+		Created:      class.CreatedAt,
+		LastModified: class.UpdatedAt,
+	}
+}
+
+// sequence 由创建与最后修改时间推导事件修订号，每次修改后递增，无需单独记录修订次数
+func sequence(created, modified time.Time) int {
+	if modified.Before(created) {
+		return 0
+	}
+	return int(modified.Sub(created) / time.Second)
+}
@@ -0,0 +1,262 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// ErrClassNotFound 课程不存在
+var ErrClassNotFound = errors.New("课程不存在")
+
+// ClassInput 创建或编辑课程的参数
+type ClassInput struct {
+	Name             string
+	Description      string
+	Instructor       string
+	InstructorID     *uuid.UUID // 授课教练档案，指定时按档案填写教练姓名与教练账号
+	InstructorUserID *uuid.UUID // 授课教练的用户ID，教练操作时固定为本人
+	RoomID           *uuid.UUID // 上课的教室，为空表示未安排教室
+	StartTime        time.Time
+	EndTime          time.Time
+	Capacity         int
+}
+
+// CreateClass 创建课程，仅教练与管理员可操作；教练创建的课程归属于本人
+func (s *Service) CreateClass(ctx context.Context, in ClassInput) (*booking.Class, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CreateClass")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsStaff() {
+		return nil, user.ErrForbidden
+	}
+	if !u.IsAdmin() {
+		in.InstructorUserID = &u.ID
+	}
+	ins, err := s.instructorFor(ctx, u, in.InstructorID)
+	if err != nil {
+		return nil, err
+	}
+
+	class := &booking.Class{
+		Name:             in.Name,
+		Description:      in.Description,
+		Instructor:       in.Instructor,
+		InstructorUserID: in.InstructorUserID,
+		RoomID:           in.RoomID,
+		StartTime:        in.StartTime,
+		EndTime:          in.EndTime,
+		Capacity:         in.Capacity,
+		Status:           "scheduled",
+	}
+	if ins != nil {
+		assignInstructor(class, ins)
+	}
+
+	if err := s.checkSchedule(ctx, class); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateClass(ctx, class); err != nil {
+		return nil, fmt.Errorf("创建课程失败: %w", err)
+	}
+
+	return class, nil
+}
+
+// UpdateClass 编辑课程信息，管理员可编辑全部课程，教练只能编辑自己授课的课程
+func (s *Service) UpdateClass(ctx context.Context, id uuid.UUID, in ClassInput) (*booking.Class, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "UpdateClass")
+	defer span.End()
+
+	class, err := s.manageableClass(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := class.Instructor
+	class.Name = in.Name
+	class.Description = in.Description
+	class.Instructor = in.Instructor
+	class.StartTime = in.StartTime
+	class.EndTime = in.EndTime
+	class.Capacity = in.Capacity
+	class.RoomID = in.RoomID
+	// 只有管理员可以更换授课教练，未指定教练档案或教练账号时保持不变
+	u, _ := user.FromContext(ctx)
+	if u.IsAdmin() {
+		switch {
+		case in.InstructorID != nil:
+			class.InstructorID = in.InstructorID
+		case in.InstructorUserID != nil:
+			// 改为其他教练账号时不再沿用原教练档案
+			if class.InstructorUserID == nil || *class.InstructorUserID != *in.InstructorUserID {
+				class.InstructorID = nil
+			}
+			class.InstructorUserID = in.InstructorUserID
+		}
+	}
+	if class.InstructorID != nil {
+		ins, err := s.instructorFor(ctx, u, class.InstructorID)
+		if err != nil {
+			return nil, err
+		}
+		assignInstructor(class, ins)
+	}
+
+	class, err = s.saveClass(ctx, class)
+	if err != nil {
+		return nil, err
+	}
+	if class.Instructor != previous {
+		s.notifier.InstructorChanged(ctx, class, previous)
+	}
+	return class, nil
+}
+
+// RescheduleClass 调整课程时间
+func (s *Service) RescheduleClass(ctx context.Context, id uuid.UUID, startTime, endTime time.Time) (*booking.Class, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "RescheduleClass")
+	defer span.End()
+
+	class, err := s.manageableClass(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	class.StartTime = startTime
+	class.EndTime = endTime
+
+	return s.saveClass(ctx, class)
+}
+
+// CancelClass 取消课程，同一事务内取消该课程下所有已确认的预订，返回被取消的预订数
+func (s *Service) CancelClass(ctx context.Context, id uuid.UUID, reason string) (int, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CancelClass")
+	defer span.End()
+
+	class, err := s.manageableClass(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	u, _ := user.FromContext(ctx)
+	cancelled, err := s.repo.CancelClass(ctx, class.ID, u.ID.String(), reason)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("课程已取消",
+		zap.String("class_id", class.ID.String()),
+		zap.String("cancelled_by", u.ID.String()),
+		zap.Int("cancelled_bookings", len(cancelled)),
+	)
+	s.notifier.ClassCancelled(ctx, class, cancelled, reason)
+	return len(cancelled), nil
+}
+
+// CompleteClass 将已开始的课程标记为已完成，未签到的已确认预订记为爽约
+func (s *Service) CompleteClass(ctx context.Context, id uuid.UUID) error {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CompleteClass")
+	defer span.End()
+
+	class, err := s.manageableClass(ctx, id)
+	if err != nil {
+		return err
+	}
+	if time.Now().Before(class.StartTime) {
+		return booking.ErrClassNotStarted
+	}
+
+	return s.completeClass(ctx, class)
+}
+
+// completeClass 结束课程并结算其下的预订
+func (s *Service) completeClass(ctx context.Context, class *booking.Class) error {
+	attended, noShows, err := s.repo.CompleteClass(ctx, class.ID, s.cfg.Policy.ForfeitCredit)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("课程已完成",
+		zap.String("class_id", class.ID.String()),
+		zap.Int("attended", attended),
+		zap.Int("no_shows", noShows),
+	)
+	return nil
+}
+
+// saveClass 校验并保存编辑后的课程
+func (s *Service) saveClass(ctx context.Context, class *booking.Class) (*booking.Class, error) {
+	if class.Status != "scheduled" {
+		return nil, booking.ErrClassNotEditable
+	}
+	if err := s.checkSchedule(ctx, class); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateClass(ctx, class); err != nil {
+		return nil, err
+	}
+
+	return class, nil
+}
+
+// checkSchedule 校验课程时间与容量，并确认授课教练和教室在该时段没有其他课程
+func (s *Service) checkSchedule(ctx context.Context, class *booking.Class) error {
+	if err := class.Validate(); err != nil {
+		return err
+	}
+	room, err := s.checkRoom(ctx, class.RoomID, class.Capacity)
+	if err != nil {
+		return err
+	}
+	class.Room = room
+
+	conflict, err := s.repo.FindInstructorConflict(ctx, class)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		return fmt.Errorf("%w: 与课程「%s」（%s）时间重叠", booking.ErrInstructorConflict,
+			conflict.Name, conflict.StartTime.Format("2006-01-02 15:04"))
+	}
+
+	conflict, err = s.repo.FindRoomConflict(ctx, class)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		return fmt.Errorf("%w: 与课程「%s」（%s）时间重叠", booking.ErrRoomConflict,
+			conflict.Name, conflict.StartTime.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// manageableClass 加载当前用户有权管理的课程
+func (s *Service) manageableClass(ctx context.Context, id uuid.UUID) (*booking.Class, error) {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := s.repo.GetClass(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrClassNotFound, err)
+	}
+	if !class.ManageableBy(u) {
+		return nil, user.ErrForbidden
+	}
+	return class, nil
+}
@@ -0,0 +1,85 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/instructor"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// SubstituteInstructor 为课程安排代课教练（管理员），新教练在该时段不能有其他课程，
+// 保存后通知已预订和候补中的会员
+func (s *Service) SubstituteInstructor(ctx context.Context, classID, instructorID uuid.UUID, reason string) (*booking.Class, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "SubstituteInstructor")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+
+	class, err := s.repo.GetClass(ctx, classID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrClassNotFound, err)
+	}
+	if class.InstructorID != nil && *class.InstructorID == instructorID {
+		return class, nil
+	}
+
+	ins, err := s.instructorFor(ctx, u, &instructorID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := class.Instructor
+	assignInstructor(class, ins)
+	class, err = s.saveClass(ctx, class)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("课程已更换教练",
+		zap.String("class_id", class.ID.String()),
+		zap.String("previous", previous),
+		zap.String("instructor_id", ins.ID.String()),
+		zap.String("reason", reason),
+		zap.String("operator", u.ID.String()),
+	)
+	s.notifier.InstructorChanged(ctx, class, previous)
+	return class, nil
+}
+
+// instructorFor 加载排课指定的教练档案，id为空时返回nil。
+// 离职的教练不能排课，教练只能为自己的档案排课。
+func (s *Service) instructorFor(ctx context.Context, u *user.User, id *uuid.UUID) (*instructor.Instructor, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	ins, err := s.repo.GetInstructor(ctx, *id)
+	if err != nil {
+		return nil, err
+	}
+	if !ins.Active {
+		return nil, instructor.ErrInstructorInactive
+	}
+	if !ins.ManageableBy(u) {
+		return nil, user.ErrForbidden
+	}
+	return ins, nil
+}
+
+// assignInstructor 按教练档案填写课程的授课教练
+func assignInstructor(class *booking.Class, ins *instructor.Instructor) {
+	class.InstructorID = &ins.ID
+	class.Instructor = ins.Name
+	class.InstructorUserID = ins.UserID
+}
@@ -0,0 +1,62 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// MarkNoShow 将课程已开始后仍未到场的已确认预订标记为爽约（管理员，或该课程的授课教练）
+func (s *Service) MarkNoShow(ctx context.Context, bookingID uuid.UUID) (*booking.Booking, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "MarkNoShow")
+	defer span.End()
+
+	b, err := s.repo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := s.manageableClass(ctx, b.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(class.StartTime) {
+		return nil, booking.ErrClassNotStarted
+	}
+
+	b, err = s.repo.MarkNoShow(ctx, bookingID, s.cfg.Policy.ForfeitCredit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("预订已标记爽约",
+		zap.String("booking_id", bookingID.String()),
+		zap.String("class_id", b.ClassID.String()),
+		zap.String("user_id", b.UserID),
+	)
+	return b, nil
+}
+
+// checkBookingBan 统计窗口内爽约次数达到阈值的用户暂停预订
+func (s *Service) checkBookingBan(ctx context.Context, u *user.User) error {
+	policy := s.cfg.Policy
+	if policy.NoShowBanThreshold <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	noShows, err := s.repo.ListNoShowTimes(ctx, u.ID.String(), policy.NoShowSince(now))
+	if err != nil {
+		return err
+	}
+	if until, banned := policy.BannedUntil(noShows, now); banned {
+		return fmt.Errorf("%w（%s前）", booking.ErrBookingBanned, until.In(s.cfg.Location).Format("2006-01-02 15:04"))
+	}
+	return nil
+}
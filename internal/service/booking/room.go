@@ -0,0 +1,54 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/location"
+)
+
+// withRooms 为安排了教室的课程填写教室及所属场馆
+func (s *Service) withRooms(ctx context.Context, classes ...*booking.Class) error {
+	var ids []uuid.UUID
+	for _, class := range classes {
+		if class.RoomID != nil {
+			ids = append(ids, *class.RoomID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rooms, err := s.repo.GetRooms(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, class := range classes {
+		if class.RoomID != nil {
+			class.Room = rooms[*class.RoomID]
+		}
+	}
+	return nil
+}
+
+// checkRoom 校验教室存在且课程容量不超过教室容量，roomID为空时不校验
+func (s *Service) checkRoom(ctx context.Context, roomID *uuid.UUID, capacity int) (*location.Room, error) {
+	if roomID == nil {
+		return nil, nil
+	}
+
+	rooms, err := s.repo.GetRooms(ctx, []uuid.UUID{*roomID})
+	if err != nil {
+		return nil, err
+	}
+	room, ok := rooms[*roomID]
+	if !ok {
+		return nil, location.ErrRoomNotFound
+	}
+	if capacity > room.Capacity {
+		return nil, fmt.Errorf("%w: 「%s」最多容纳%d人", booking.ErrRoomCapacityExceeded, room.Label(), room.Capacity)
+	}
+	return room, nil
+}
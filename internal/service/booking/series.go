@@ -0,0 +1,311 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/instructor"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// ErrSeriesNotFound 课程系列不存在
+var ErrSeriesNotFound = errors.New("课程系列不存在")
+
+// seriesRevisionReason 修改课程系列后不再上课的课程的取消原因
+const seriesRevisionReason = "课程系列调整"
+
+// SeriesInput 创建或修改课程系列的参数
+type SeriesInput struct {
+	Name             string
+	Description      string
+	Instructor       string
+	InstructorID     *uuid.UUID // 授课教练档案，指定时按档案填写教练姓名与教练账号
+	InstructorUserID *uuid.UUID // 授课教练的用户ID，教练操作时固定为本人
+	RoomID           *uuid.UUID // 上课的教室，为空表示未安排教室
+	Weekdays         []int
+	StartClock       string
+	DurationMinutes  int
+	Capacity         int
+	StartDate        time.Time
+	EndDate          *time.Time
+	ExceptionDates   []string
+}
+
+// SeriesRevision 修改课程系列的结果
+type SeriesRevision struct {
+	Series    *booking.ClassSeries `json:"series"`              // 修改后的系列（整体修改）或截止后的原系列（“此节及以后”）
+	Successor *booking.ClassSeries `json:"successor,omitempty"` // “此节及以后”修改时拆分出的新系列
+	Updated   int                  `json:"updated_classes"`     // 按新规则调整的已生成课程数
+	Cancelled int                  `json:"cancelled_classes"`   // 按新规则不再上课而取消的课程数
+	Generated int                  `json:"generated_classes"`   // 新生成的课程数
+}
+
+// CreateSeries 创建课程系列并立即生成时间窗口内的课程，仅教练与管理员可操作
+func (s *Service) CreateSeries(ctx context.Context, in SeriesInput) (*booking.ClassSeries, int, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "CreateSeries")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !u.IsStaff() {
+		return nil, 0, user.ErrForbidden
+	}
+	if !u.IsAdmin() {
+		in.InstructorUserID = &u.ID
+	}
+	ins, err := s.instructorFor(ctx, u, in.InstructorID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	series := s.newSeries(in, ins)
+	if err := series.Validate(); err != nil {
+		return nil, 0, err
+	}
+	if _, err := s.checkRoom(ctx, series.RoomID, series.Capacity); err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.repo.CreateSeries(ctx, series); err != nil {
+		return nil, 0, err
+	}
+
+	generated, err := s.generateSeries(ctx, series)
+	if err != nil {
+		return nil, 0, err
+	}
+	return series, generated, nil
+}
+
+// GetSeries 获取课程系列
+func (s *Service) GetSeries(ctx context.Context, id uuid.UUID) (*booking.ClassSeries, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "GetSeries")
+	defer span.End()
+
+	series, err := s.repo.GetSeries(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSeriesNotFound, err)
+	}
+	return series, nil
+}
+
+// ListSeries 列出仍在进行中的课程系列
+func (s *Service) ListSeries(ctx context.Context, limit, offset int) ([]*booking.ClassSeries, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "ListSeries")
+	defer span.End()
+
+	today := time.Now().In(s.cfg.Location)
+	return s.repo.ListSeries(ctx, &today, limit, offset)
+}
+
+// UpdateSeries 修改课程系列。
+//
+// effectiveFrom为空或不晚于系列开始日期时整体修改（“全部”），否则从effectiveFrom起拆分（“此节及以后”）：
+// 原系列截止到前一天，之后的课程改挂到新系列。两种方式都只调整尚未开始的已生成课程，
+// 按新规则不再上课的课程连同预订一起取消。只调整单节课程请使用UpdateClass（“仅此节”）。
+func (s *Service) UpdateSeries(ctx context.Context, id uuid.UUID, in SeriesInput, effectiveFrom *time.Time) (*SeriesRevision, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "UpdateSeries")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetSeries(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSeriesNotFound, err)
+	}
+	if !current.ManageableBy(u) {
+		return nil, user.ErrForbidden
+	}
+	if !u.IsAdmin() {
+		in.InstructorID = current.InstructorID
+		in.InstructorUserID = current.InstructorUserID
+	}
+	ins, err := s.instructorFor(ctx, u, in.InstructorID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.checkRoom(ctx, in.RoomID, in.Capacity); err != nil {
+		return nil, err
+	}
+
+	revision := &SeriesRevision{Series: current}
+	target := current
+	from := time.Now().In(s.cfg.Location)
+
+	if effectiveFrom == nil || !effectiveFrom.After(current.StartDate) {
+		// 整体修改：保留系列ID与创建时间
+		next := s.newSeries(in, ins)
+		next.ID = current.ID
+		next.CreatedAt = current.CreatedAt
+		if err := next.Validate(); err != nil {
+			return nil, err
+		}
+		*current = *next
+	} else {
+		// 此节及以后：原系列截止到effectiveFrom前一天
+		successor := s.newSeries(in, ins)
+		successor.StartDate = *effectiveFrom
+		if err := successor.Validate(); err != nil {
+			return nil, err
+		}
+
+		endDate := effectiveFrom.AddDate(0, 0, -1)
+		if current.EndDate != nil && current.EndDate.Before(endDate) {
+			return nil, fmt.Errorf("%w: 系列已在%s结束", booking.ErrInvalidSeries, current.EndDate.Format(booking.DateLayout))
+		}
+		current.EndDate = &endDate
+		revision.Successor = successor
+		target = successor
+		if effectiveFrom.After(from) {
+			from = *effectiveFrom
+		}
+	}
+
+	changes, err := s.repo.ReviseSeries(ctx, current, revision.Successor, from, u.ID.String(), seriesRevisionReason)
+	if err != nil {
+		return nil, err
+	}
+	revision.Updated = changes.Updated
+	revision.Cancelled = len(changes.Cancelled)
+
+	revision.Generated, err = s.generateSeries(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("课程系列已修改",
+		zap.String("series_id", current.ID.String()),
+		zap.Bool("split", revision.Successor != nil),
+		zap.Int("updated_classes", revision.Updated),
+		zap.Int("cancelled_classes", revision.Cancelled),
+		zap.Int("generated_classes", revision.Generated),
+	)
+	for _, c := range changes.Cancelled {
+		s.notifier.ClassCancelled(ctx, c.Class, c.Bookings, seriesRevisionReason)
+	}
+	for _, c := range changes.InstructorChanges {
+		s.notifier.InstructorChanged(ctx, c.Class, c.Previous)
+	}
+	return revision, nil
+}
+
+// GenerateSeriesClasses 为所有进行中的课程系列生成时间窗口内尚未生成的课程，返回新生成的数量。
+// 由定时任务调用，可重复执行。
+func (s *Service) GenerateSeriesClasses(ctx context.Context) (int, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "GenerateSeriesClasses")
+	defer span.End()
+
+	today := time.Now().In(s.cfg.Location)
+	seriesList, err := s.repo.ListSeries(ctx, &today, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, series := range seriesList {
+		generated, err := s.generateSeries(ctx, series)
+		if err != nil {
+			s.logger.Error("生成系列课程失败", zap.String("series_id", series.ID.String()), zap.Error(err))
+			continue
+		}
+		total += generated
+	}
+
+	if total > 0 {
+		s.logger.Info("已生成系列课程", zap.Int("classes", total))
+	}
+	return total, nil
+}
+
+// generateSeries 生成单个系列在时间窗口内尚未生成的课程。
+// 与其他课程时间冲突的日期跳过并记录日志，不影响其余日期。
+func (s *Service) generateSeries(ctx context.Context, series *booking.ClassSeries) (int, error) {
+	now := time.Now()
+	var classes []*booking.Class
+
+	for _, date := range series.Occurrences(now, now.Add(s.cfg.SeriesHorizon)) {
+		class := series.NewClass(date)
+		if class == nil || !class.StartTime.After(now) {
+			continue
+		}
+
+		conflict, err := s.repo.FindInstructorConflict(ctx, class)
+		if err != nil {
+			return 0, err
+		}
+		if conflict != nil {
+			// 冲突的是本系列已生成的同一节课，无需处理
+			if !sameOccurrence(conflict, class) {
+				s.logger.Warn("系列课程与教练的其他课程时间冲突，跳过生成",
+					zap.String("series_id", series.ID.String()),
+					zap.String("date", date.Format(booking.DateLayout)),
+					zap.String("conflict_class_id", conflict.ID.String()),
+				)
+			}
+			continue
+		}
+
+		conflict, err = s.repo.FindRoomConflict(ctx, class)
+		if err != nil {
+			return 0, err
+		}
+		if conflict != nil {
+			if !sameOccurrence(conflict, class) {
+				s.logger.Warn("系列课程与教室的其他课程时间冲突，跳过生成",
+					zap.String("series_id", series.ID.String()),
+					zap.String("date", date.Format(booking.DateLayout)),
+					zap.String("conflict_class_id", conflict.ID.String()),
+				)
+			}
+			continue
+		}
+
+		classes = append(classes, class)
+	}
+
+	return s.repo.InsertSeriesClasses(ctx, classes)
+}
+
+// newSeries 由参数构造课程系列，时区使用场馆时区；ins非空时按教练档案填写授课教练
+func (s *Service) newSeries(in SeriesInput, ins *instructor.Instructor) *booking.ClassSeries {
+	if in.ExceptionDates == nil {
+		in.ExceptionDates = []string{}
+	}
+	if ins != nil {
+		in.InstructorID = &ins.ID
+		in.Instructor = ins.Name
+		in.InstructorUserID = ins.UserID
+	}
+	return &booking.ClassSeries{
+		Name:             in.Name,
+		Description:      in.Description,
+		Instructor:       in.Instructor,
+		InstructorID:     in.InstructorID,
+		InstructorUserID: in.InstructorUserID,
+		RoomID:           in.RoomID,
+		Weekdays:         in.Weekdays,
+		StartClock:       in.StartClock,
+		DurationMinutes:  in.DurationMinutes,
+		Capacity:         in.Capacity,
+		StartDate:        in.StartDate,
+		EndDate:          in.EndDate,
+		ExceptionDates:   in.ExceptionDates,
+		Timezone:         s.cfg.Location.String(),
+	}
+}
+
+// sameOccurrence 两节课是否为同一系列的同一日期
+func sameOccurrence(a, b *booking.Class) bool {
+	return a.SeriesID != nil && b.SeriesID != nil && *a.SeriesID == *b.SeriesID &&
+		a.SeriesDate != nil && b.SeriesDate != nil && a.SeriesDate.Format(booking.DateLayout) == b.SeriesDate.Format(booking.DateLayout)
+}
@@ -7,6 +7,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/instructor"
+	"github.com/yoga/knowledge-base/internal/domain/location"
+	"github.com/yoga/knowledge-base/internal/domain/user"
 	"github.com/yoga/knowledge-base/pkg/observability"
 	"go.uber.org/zap"
 )
@@ -15,113 +18,243 @@ import (
 type Repository interface {
 	CreateClass(ctx context.Context, class *booking.Class) error
 	GetClass(ctx context.Context, id uuid.UUID) (*booking.Class, error)
-	ListClasses(ctx context.Context, startTime, endTime *time.Time, limit, offset int) ([]*booking.Class, error)
+	ListClasses(ctx context.Context, startTime, endTime *time.Time, locationID *uuid.UUID, limit, offset int) ([]*booking.Class, error)
 	UpdateClass(ctx context.Context, class *booking.Class) error
-	CreateBooking(ctx context.Context, b *booking.Booking) error
+	CancelClass(ctx context.Context, id uuid.UUID, cancelledBy, reason string) ([]*booking.Booking, error)
+	CompleteClass(ctx context.Context, id uuid.UUID, forfeitCredit bool) (attended, noShows int, err error)
+	ListEndedClasses(ctx context.Context, before time.Time, limit int) ([]*booking.Class, error)
+	FindInstructorConflict(ctx context.Context, class *booking.Class) (*booking.Class, error)
+	GetInstructor(ctx context.Context, id uuid.UUID) (*instructor.Instructor, error)
+	FindRoomConflict(ctx context.Context, class *booking.Class) (*booking.Class, error)
+	GetRooms(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*location.Room, error)
+	CreateSeries(ctx context.Context, series *booking.ClassSeries) error
+	GetSeries(ctx context.Context, id uuid.UUID) (*booking.ClassSeries, error)
+	ListSeries(ctx context.Context, activeOn *time.Time, limit, offset int) ([]*booking.ClassSeries, error)
+	InsertSeriesClasses(ctx context.Context, classes []*booking.Class) (int, error)
+	ReviseSeries(ctx context.Context, current, successor *booking.ClassSeries, from time.Time, cancelledBy, reason string) (*booking.SeriesChanges, error)
+	CreateBooking(ctx context.Context, b *booking.Booking, requireCredit bool) error
 	GetBooking(ctx context.Context, id uuid.UUID) (*booking.Booking, error)
 	ListUserBookings(ctx context.Context, userID string, limit, offset int) ([]*booking.Booking, error)
-	CancelBooking(ctx context.Context, id uuid.UUID) error
+	CancelBooking(ctx context.Context, id uuid.UUID, c booking.Cancellation) (cancelled, promoted *booking.Booking, err error)
+	MarkNoShow(ctx context.Context, id uuid.UUID, forfeitCredit bool) (*booking.Booking, error)
+	ListNoShowTimes(ctx context.Context, userID string, since time.Time) ([]time.Time, error)
+	GetBookingByCheckInToken(ctx context.Context, token string) (*booking.Booking, error)
+	CheckIn(ctx context.Context, a *booking.Attendance) error
+	ListAttendance(ctx context.Context, classID uuid.UUID) ([]*booking.RosterEntry, error)
+	JoinWaitlist(ctx context.Context, b *booking.Booking, requireCredit bool) error
+	ListWaitlist(ctx context.Context, classID uuid.UUID) ([]*booking.Booking, error)
 	CreateReview(ctx context.Context, review *booking.Review) error
 	ListClassReviews(ctx context.Context, classID uuid.UUID, limit, offset int) ([]*booking.Review, error)
 	GetUserReview(ctx context.Context, classID uuid.UUID, userID string) (*booking.Review, error)
 }
 
-// Service 定课服务
-type Service struct {
-	repo   Repository
-	logger *zap.Logger
+// Notifier 预订事件通知，方法应立即返回，发送在后台进行
+type Notifier interface {
+	BookingConfirmed(ctx context.Context, b *booking.Booking)
+	WaitlistPromoted(ctx context.Context, b *booking.Booking)
+	ClassCancelled(ctx context.Context, class *booking.Class, bookings []*booking.Booking, reason string)
+	InstructorChanged(ctx context.Context, class *booking.Class, previous string)
 }
 
-// NewService 创建定课服务
-func NewService(repo Repository, logger *zap.Logger) *Service {
-	return &Service{
-		repo:   repo,
-		logger: logger,
-	}
+// noopNotifier 不发送通知
+type noopNotifier struct{}
+
+func (noopNotifier) BookingConfirmed(context.Context, *booking.Booking)                         {}
+func (noopNotifier) WaitlistPromoted(context.Context, *booking.Booking)                         {}
+func (noopNotifier) ClassCancelled(context.Context, *booking.Class, []*booking.Booking, string) {}
+func (noopNotifier) InstructorChanged(context.Context, *booking.Class, string)                  {}
+
+// Config 定课服务配置
+type Config struct {
+	Location      *time.Location // 场馆所在时区，课程系列的上课时间按此时区解释
+	SeriesHorizon time.Duration  // 课程系列提前生成课程的时间窗口
+	// WaitlistCutoff 开课前多久停止候补转正（此后释放的名额不再自动转给候补成员）
+	WaitlistCutoff time.Duration
+	// Policy 取消与爽约政策
+	Policy booking.CancellationPolicy
+	// CheckIn 签到时间窗口
+	CheckIn booking.CheckInWindow
+	// RequireMembership 预订需从会员卡扣除课时
+	RequireMembership bool
 }
 
-// CreateClass 创建课程
-func (s *Service) CreateClass(ctx context.Context, name, description, instructor string, startTime, endTime time.Time, capacity int) (*booking.Class, error) {
-	ctx, span := observability.StartSpan(ctx, "booking-service", "CreateClass")
-	defer span.End()
+// Service 定课服务
+type Service struct {
+	repo     Repository
+	cfg      Config
+	notifier Notifier
+	logger   *zap.Logger
+}
 
-	class := &booking.Class{
-		Name:        name,
-		Description: description,
-		Instructor:  instructor,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Capacity:    capacity,
-		Status:      "scheduled",
+// NewService 创建定课服务，notifier为nil时不发送通知
+func NewService(repo Repository, cfg Config, notifier Notifier, logger *zap.Logger) *Service {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
 	}
-
-	if err := s.repo.CreateClass(ctx, class); err != nil {
-		return nil, fmt.Errorf("创建课程失败: %w", err)
+	if cfg.SeriesHorizon <= 0 {
+		cfg.SeriesHorizon = 28 * 24 * time.Hour
 	}
+	if notifier == nil {
+		notifier = noopNotifier{}
+	}
+	return &Service{
+		repo:     repo,
+		cfg:      cfg,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
 
-	return class, nil
+// Location 场馆所在时区，按日期查询课程时日期按此时区解释
+func (s *Service) Location() *time.Location {
+	return s.cfg.Location
 }
 
-// GetClass 获取课程
+// GetClass 获取课程（含教室与场馆）
 func (s *Service) GetClass(ctx context.Context, id uuid.UUID) (*booking.Class, error) {
 	ctx, span := observability.StartSpan(ctx, "booking-service", "GetClass")
 	defer span.End()
 
-	return s.repo.GetClass(ctx, id)
+	class, err := s.repo.GetClass(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withRooms(ctx, class); err != nil {
+		return nil, err
+	}
+	return class, nil
 }
 
-// ListClasses 列出课程
-func (s *Service) ListClasses(ctx context.Context, startTime, endTime *time.Time, limit, offset int) ([]*booking.Class, error) {
+// ListClasses 列出课程（含教室与场馆），locationID非空时只列出该场馆的课程
+func (s *Service) ListClasses(ctx context.Context, startTime, endTime *time.Time, locationID *uuid.UUID, limit, offset int) ([]*booking.Class, error) {
 	ctx, span := observability.StartSpan(ctx, "booking-service", "ListClasses")
 	defer span.End()
 
-	return s.repo.ListClasses(ctx, startTime, endTime, limit, offset)
+	classes, err := s.repo.ListClasses(ctx, startTime, endTime, locationID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.withRooms(ctx, classes...); err != nil {
+		return nil, err
+	}
+	return classes, nil
 }
 
-// BookClass 预订课程
-func (s *Service) BookClass(ctx context.Context, classID uuid.UUID, userID, userName string) (*booking.Booking, error) {
+// BookClass 以当前登录用户身份预订课程，要求会员卡时同时扣除课时
+func (s *Service) BookClass(ctx context.Context, classID uuid.UUID) (*booking.Booking, error) {
 	ctx, span := observability.StartSpan(ctx, "booking-service", "BookClass")
 	defer span.End()
 
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBookingBan(ctx, u); err != nil {
+		return nil, err
+	}
+
 	b := &booking.Booking{
 		ClassID:  classID,
-		UserID:   userID,
-		UserName: userName,
+		UserID:   u.ID.String(),
+		UserName: u.DisplayName(),
 		Status:   "confirmed",
 	}
 
-	if err := s.repo.CreateBooking(ctx, b); err != nil {
+	if err := s.repo.CreateBooking(ctx, b, s.cfg.RequireMembership); err != nil {
 		return nil, fmt.Errorf("预订课程失败: %w", err)
 	}
 
+	s.notifier.BookingConfirmed(ctx, b)
 	return b, nil
 }
 
-// CancelBooking 取消预订
-func (s *Service) CancelBooking(ctx context.Context, bookingID uuid.UUID) error {
+// CancelBooking 取消预订或退出候补，返回取消后的预订。会员只能取消自己的预订；工作人员可以取消其有权管理的课程下的预订
+// （管理员为全部课程，教练为自己授课的课程）。会员取消已确认的预订受取消政策约束：课程开始后不能取消，
+// 过了免费取消截止时间记为迟取消（场馆不允许迟取消时拒绝）。释放的名额在候补截止时间前自动转给候补名单第一位。
+func (s *Service) CancelBooking(ctx context.Context, bookingID uuid.UUID, reason string) (*booking.Booking, error) {
 	ctx, span := observability.StartSpan(ctx, "booking-service", "CancelBooking")
 	defer span.End()
 
-	return s.repo.CancelBooking(ctx, bookingID)
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.repo.GetBooking(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := s.repo.GetClass(ctx, b.ClassID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrClassNotFound, err)
+	}
+	byStaff := b.UserID != u.ID.String()
+	if byStaff && !class.ManageableBy(u) {
+		return nil, user.ErrForbidden
+	}
+
+	cancellation := booking.Cancellation{
+		By:            u.ID.String(),
+		Reason:        reason,
+		PromoteBefore: class.StartTime.Add(-s.cfg.WaitlistCutoff),
+		RequireCredit: s.cfg.RequireMembership,
+	}
+	// 工作人员代为取消不受取消政策约束；退出候补同样不受约束，由仓储在锁定预订后按当时的状态判定
+	if !byStaff {
+		cancellation.Policy = &s.cfg.Policy
+	}
+
+	cancelled, promoted, err := s.repo.CancelBooking(ctx, bookingID, cancellation)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("预订已取消",
+		zap.String("booking_id", bookingID.String()),
+		zap.String("cancelled_by", u.ID.String()),
+		zap.Bool("by_staff", byStaff),
+		zap.Bool("late", cancelled.LateCancel),
+	)
+	if promoted != nil {
+		s.logger.Info("候补已转正",
+			zap.String("booking_id", promoted.ID.String()),
+			zap.String("class_id", promoted.ClassID.String()),
+			zap.String("user_id", promoted.UserID),
+		)
+		s.notifier.WaitlistPromoted(ctx, promoted)
+	}
+
+	return cancelled, nil
 }
 
-// ListUserBookings 列出用户预订
-func (s *Service) ListUserBookings(ctx context.Context, userID string, limit, offset int) ([]*booking.Booking, error) {
+// ListUserBookings 列出当前登录用户的预订
+func (s *Service) ListUserBookings(ctx context.Context, limit, offset int) ([]*booking.Booking, error) {
 	ctx, span := observability.StartSpan(ctx, "booking-service", "ListUserBookings")
 	defer span.End()
 
-	return s.repo.ListUserBookings(ctx, userID, limit, offset)
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListUserBookings(ctx, u.ID.String(), limit, offset)
 }
 
-// CreateReview 创建评价
-func (s *Service) CreateReview(ctx context.Context, classID uuid.UUID, userID, userName string, rating int, content string, images []string) (*booking.Review, error) {
+// CreateReview 以当前登录用户身份创建评价
+func (s *Service) CreateReview(ctx context.Context, classID uuid.UUID, rating int, content string, images []string) (*booking.Review, error) {
 	ctx, span := observability.StartSpan(ctx, "booking-service", "CreateReview")
 	defer span.End()
 
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	review := &booking.Review{
 		ClassID:  classID,
-		UserID:   userID,
-		UserName: userName,
+		UserID:   u.ID.String(),
+		UserName: u.DisplayName(),
 		Rating:   rating,
 		Content:  content,
 		Images:   images,
@@ -0,0 +1,49 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+)
+
+// JoinWaitlist 以当前登录用户身份加入已满课程的候补名单，返回的预订中带有候补位置
+func (s *Service) JoinWaitlist(ctx context.Context, classID uuid.UUID) (*booking.Booking, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "JoinWaitlist")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBookingBan(ctx, u); err != nil {
+		return nil, err
+	}
+
+	b := &booking.Booking{
+		ClassID:  classID,
+		UserID:   u.ID.String(),
+		UserName: u.DisplayName(),
+	}
+
+	if err := s.repo.JoinWaitlist(ctx, b, s.cfg.RequireMembership); err != nil {
+		return nil, fmt.Errorf("加入候补名单失败: %w", err)
+	}
+
+	return b, nil
+}
+
+// ListWaitlist 查看课程的候补名单（管理员，或该课程的授课教练）
+func (s *Service) ListWaitlist(ctx context.Context, classID uuid.UUID) ([]*booking.Booking, error) {
+	ctx, span := observability.StartSpan(ctx, "booking-service", "ListWaitlist")
+	defer span.End()
+
+	if _, err := s.manageableClass(ctx, classID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListWaitlist(ctx, classID)
+}
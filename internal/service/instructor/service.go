@@ -0,0 +1,239 @@
+package instructor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/instructor"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"github.com/yoga/knowledge-base/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// maxPhotoSize 教练照片的最大字节数
+const maxPhotoSize = 5 << 20
+
+// Repository 教练档案仓储接口
+type Repository interface {
+	CreateInstructor(ctx context.Context, ins *instructor.Instructor) error
+	GetInstructor(ctx context.Context, id uuid.UUID) (*instructor.Instructor, error)
+	ListInstructors(ctx context.Context, activeOnly bool, limit, offset int) ([]*instructor.Instructor, error)
+	UpdateInstructor(ctx context.Context, ins *instructor.Instructor) error
+	InstructorRatings(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]instructor.Rating, error)
+	ListInstructorClasses(ctx context.Context, id uuid.UUID, startTime, endTime time.Time, limit, offset int) ([]*booking.Class, error)
+}
+
+// Input 创建或修改教练档案的参数
+type Input struct {
+	UserID         *uuid.UUID // 关联的教练账号，仅管理员可修改
+	Name           string
+	Bio            string
+	Specialties    []string
+	Certifications []string
+	Active         bool // 是否在职，仅管理员可修改
+}
+
+// Service 教练档案服务
+type Service struct {
+	repo       Repository
+	storage    storage.Storage
+	bucketName string
+	logger     *zap.Logger
+}
+
+// NewService 创建教练档案服务，照片保存在bucketName存储桶中
+func NewService(repo Repository, storage storage.Storage, bucketName string, logger *zap.Logger) *Service {
+	return &Service{
+		repo:       repo,
+		storage:    storage,
+		bucketName: bucketName,
+		logger:     logger,
+	}
+}
+
+// CreateInstructor 创建教练档案（管理员）
+func (s *Service) CreateInstructor(ctx context.Context, in Input) (*instructor.Instructor, error) {
+	ctx, span := observability.StartSpan(ctx, "instructor-service", "CreateInstructor")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+
+	ins := &instructor.Instructor{
+		UserID:         in.UserID,
+		Name:           in.Name,
+		Bio:            in.Bio,
+		Specialties:    in.Specialties,
+		Certifications: in.Certifications,
+		Active:         in.Active,
+	}
+	if err := ins.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateInstructor(ctx, ins); err != nil {
+		return nil, err
+	}
+	return s.withDetails(ctx, ins)
+}
+
+// UpdateInstructor 修改教练档案，管理员可修改全部档案，教练只能修改本人的简介、专长与证书。
+// 姓名变更会同步到尚未结束的课程。
+func (s *Service) UpdateInstructor(ctx context.Context, id uuid.UUID, in Input) (*instructor.Instructor, error) {
+	ctx, span := observability.StartSpan(ctx, "instructor-service", "UpdateInstructor")
+	defer span.End()
+
+	u, ins, err := s.manageableInstructor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ins.Name = in.Name
+	ins.Bio = in.Bio
+	ins.Specialties = in.Specialties
+	ins.Certifications = in.Certifications
+	if u.IsAdmin() {
+		ins.UserID = in.UserID
+		ins.Active = in.Active
+	}
+	if err := ins.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateInstructor(ctx, ins); err != nil {
+		return nil, err
+	}
+	return s.withDetails(ctx, ins)
+}
+
+// UploadPhoto 上传教练照片并替换原照片，管理员或教练本人可操作
+func (s *Service) UploadPhoto(ctx context.Context, id uuid.UUID, file io.Reader, fileSize int64, contentType, fileName string) (*instructor.Instructor, error) {
+	ctx, span := observability.StartSpan(ctx, "instructor-service", "UploadPhoto")
+	defer span.End()
+
+	if !strings.HasPrefix(contentType, "image/") || fileSize <= 0 || fileSize > maxPhotoSize {
+		return nil, instructor.ErrInvalidPhoto
+	}
+
+	_, ins, err := s.manageableInstructor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	photoPath := fmt.Sprintf("instructors/%s/%s%s", ins.ID, uuid.New(), strings.ToLower(path.Ext(fileName)))
+	if err := s.storage.PutObject(ctx, s.bucketName, photoPath, file, fileSize, contentType); err != nil {
+		return nil, fmt.Errorf("上传教练照片失败: %w", err)
+	}
+
+	previous := ins.PhotoPath
+	ins.PhotoPath = photoPath
+	if err := s.repo.UpdateInstructor(ctx, ins); err != nil {
+		_ = s.storage.RemoveObject(ctx, s.bucketName, photoPath)
+		return nil, err
+	}
+	if previous != "" {
+		if err := s.storage.RemoveObject(ctx, s.bucketName, previous); err != nil {
+			s.logger.Warn("删除原教练照片失败", zap.Error(err), zap.String("photo_path", previous))
+		}
+	}
+
+	return s.withDetails(ctx, ins)
+}
+
+// GetInstructor 获取教练主页：档案、照片地址与所授课程的评价汇总
+func (s *Service) GetInstructor(ctx context.Context, id uuid.UUID) (*instructor.Instructor, error) {
+	ctx, span := observability.StartSpan(ctx, "instructor-service", "GetInstructor")
+	defer span.End()
+
+	ins, err := s.repo.GetInstructor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withDetails(ctx, ins)
+}
+
+// ListInstructors 列出教练，管理员可包含已离职的教练
+func (s *Service) ListInstructors(ctx context.Context, includeInactive bool, limit, offset int) ([]*instructor.Instructor, error) {
+	ctx, span := observability.StartSpan(ctx, "instructor-service", "ListInstructors")
+	defer span.End()
+
+	if u, ok := user.FromContext(ctx); !ok || !u.IsAdmin() {
+		includeInactive = false
+	}
+
+	list, err := s.repo.ListInstructors(ctx, !includeInactive, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, ins := range list {
+		ids = append(ids, ins.ID)
+	}
+	ratings, err := s.repo.InstructorRatings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ins := range list {
+		s.decorate(ins, ratings[ins.ID])
+	}
+	return list, nil
+}
+
+// Schedule 教练在时间范围内的课程表
+func (s *Service) Schedule(ctx context.Context, id uuid.UUID, startTime, endTime time.Time, limit, offset int) ([]*booking.Class, error) {
+	ctx, span := observability.StartSpan(ctx, "instructor-service", "Schedule")
+	defer span.End()
+
+	if _, err := s.repo.GetInstructor(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInstructorClasses(ctx, id, startTime, endTime, limit, offset)
+}
+
+// manageableInstructor 加载当前用户有权维护的教练档案
+func (s *Service) manageableInstructor(ctx context.Context, id uuid.UUID) (*user.User, *instructor.Instructor, error) {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ins, err := s.repo.GetInstructor(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ins.ManageableBy(u) {
+		return nil, nil, user.ErrForbidden
+	}
+	return u, ins, nil
+}
+
+// withDetails 填写照片地址与评价汇总
+func (s *Service) withDetails(ctx context.Context, ins *instructor.Instructor) (*instructor.Instructor, error) {
+	ratings, err := s.repo.InstructorRatings(ctx, []uuid.UUID{ins.ID})
+	if err != nil {
+		return nil, err
+	}
+	s.decorate(ins, ratings[ins.ID])
+	return ins, nil
+}
+
+// decorate 填写照片地址与评价汇总
+func (s *Service) decorate(ins *instructor.Instructor, rating instructor.Rating) {
+	if ins.PhotoPath != "" {
+		ins.PhotoURL = s.storage.GetObjectURL(s.bucketName, ins.PhotoPath)
+	}
+	ins.Rating = &rating
+}
@@ -2,11 +2,14 @@ package knowledge
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/yoga/knowledge-base/internal/domain/knowledge"
+	"github.com/yoga/knowledge-base/pkg/chunker"
 	"github.com/yoga/knowledge-base/pkg/embedding"
 	"github.com/yoga/knowledge-base/pkg/observability"
 	"github.com/yoga/knowledge-base/pkg/storage"
@@ -25,9 +28,15 @@ type Repository interface {
 	GetItem(ctx context.Context, id uuid.UUID) (*knowledge.KnowledgeItem, error)
 	ListItems(ctx context.Context, baseID uuid.UUID, limit, offset int) ([]*knowledge.KnowledgeItem, error)
 	UpdateItem(ctx context.Context, item *knowledge.KnowledgeItem) error
-	UpdateItemEmbeddingStatus(ctx context.Context, id uuid.UUID, status, vectorID string) error
+	UpdateItemEmbeddingStatus(ctx context.Context, id, leaseToken uuid.UUID, status, vectorID string) error
+	RenewItemLease(ctx context.Context, id, leaseToken uuid.UUID, leaseTimeout time.Duration) error
+	RetryItemEmbedding(ctx context.Context, id, leaseToken uuid.UUID, errMsg string, nextAttemptAt time.Time) error
+	FailItemEmbedding(ctx context.Context, id, leaseToken uuid.UUID, errMsg string) error
 	DeleteItem(ctx context.Context, id uuid.UUID) error
-	GetPendingItems(ctx context.Context, limit int) ([]*knowledge.KnowledgeItem, error)
+	GetPendingItems(ctx context.Context, limit int, leaseTimeout time.Duration) ([]*knowledge.KnowledgeItem, error)
+	ReplaceItemChunks(ctx context.Context, itemID uuid.UUID, chunks []*knowledge.KnowledgeChunk) error
+	ListItemChunks(ctx context.Context, itemID uuid.UUID) ([]*knowledge.KnowledgeChunk, error)
+	ListBaseVectorIDs(ctx context.Context, baseID uuid.UUID) ([]string, error)
 }
 
 // Service 知识库服务
@@ -36,19 +45,24 @@ type Service struct {
 	storage      storage.Storage
 	embeddingSvc *embedding.Client
 	vectorSvc    *vector.Client
+	splitter     *chunker.Splitter
 	bucketName   string
 	logger       *zap.Logger
+	// pendingCh 新建知识项后唤醒向量化worker，避免等待下一次轮询
+	pendingCh chan struct{}
 }
 
 // NewService 创建知识库服务
-func NewService(repo Repository, storage storage.Storage, embeddingSvc *embedding.Client, vectorSvc *vector.Client, bucketName string, logger *zap.Logger) *Service {
+func NewService(repo Repository, storage storage.Storage, embeddingSvc *embedding.Client, vectorSvc *vector.Client, splitter *chunker.Splitter, bucketName string, logger *zap.Logger) *Service {
 	return &Service{
 		repo:         repo,
 		storage:      storage,
 		embeddingSvc: embeddingSvc,
 		vectorSvc:    vectorSvc,
+		splitter:     splitter,
 		bucketName:   bucketName,
 		logger:       logger,
+		pendingCh:    make(chan struct{}, 1),
 	}
 }
 
@@ -116,6 +130,13 @@ func (s *Service) DeleteBase(ctx context.Context, id uuid.UUID) error {
 	ctx, span := observability.StartSpan(ctx, "knowledge-service", "DeleteBase")
 	defer span.End()
 
+	// 删除知识库下的全部向量，数据库中的知识项与分块随知识库级联删除
+	vectorIDs, err := s.repo.ListBaseVectorIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.deleteVectors(ctx, vectorIDs)
+
 	return s.repo.DeleteBase(ctx, id)
 }
 
@@ -136,8 +157,8 @@ func (s *Service) CreateTextItem(ctx context.Context, baseID uuid.UUID, title, c
 		return nil, fmt.Errorf("创建知识项失败: %w", err)
 	}
 
-	// 异步触发向量化
-	go s.processEmbedding(context.Background(), item)
+	// 由后台worker领取并向量化
+	s.notifyPending()
 
 	return item, nil
 }
@@ -177,8 +198,8 @@ func (s *Service) CreateFileItem(ctx context.Context, baseID uuid.UUID, title st
 		return nil, fmt.Errorf("创建知识项失败: %w", err)
 	}
 
-	// 异步触发向量化（对于图片和视频，可能需要特殊处理）
-	go s.processEmbedding(context.Background(), item)
+	// 由后台worker领取并向量化（对于图片和视频，可能需要特殊处理）
+	s.notifyPending()
 
 	return item, nil
 }
@@ -216,66 +237,154 @@ func (s *Service) DeleteItem(ctx context.Context, id uuid.UUID) error {
 		}
 	}
 
+	// 删除分块向量，分块记录随知识项级联删除
+	chunks, err := s.repo.ListItemChunks(ctx, id)
+	if err != nil {
+		return err
+	}
+	vectorIDs := make([]string, 0, len(chunks)+1)
+	for _, chunk := range chunks {
+		vectorIDs = append(vectorIDs, chunk.VectorID)
+	}
+	if item.VectorID != "" {
+		vectorIDs = append(vectorIDs, item.VectorID)
+	}
+	s.deleteVectors(ctx, vectorIDs)
+
 	return s.repo.DeleteItem(ctx, id)
 }
 
-// processEmbedding 处理向量化
-func (s *Service) processEmbedding(ctx context.Context, item *knowledge.KnowledgeItem) {
-	ctx, span := observability.StartSpan(ctx, "knowledge-service", "processEmbedding")
-	defer span.End()
+// deleteVectors 删除向量，失败仅记录日志
+func (s *Service) deleteVectors(ctx context.Context, vectorIDs []string) {
+	seen := make(map[string]bool, len(vectorIDs))
+	for _, vectorID := range vectorIDs {
+		if vectorID == "" || seen[vectorID] {
+			continue
+		}
+		seen[vectorID] = true
+		if err := s.vectorSvc.Delete(ctx, vectorID); err != nil {
+			s.logger.Warn("删除向量失败", zap.Error(err), zap.String("vector_id", vectorID))
+		}
+	}
+}
 
-	// 更新状态为处理中
-	if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, "processing", ""); err != nil {
-		s.logger.Error("更新向量化状态失败", zap.Error(err))
-		return
+// notifyPending 非阻塞地唤醒向量化worker
+func (s *Service) notifyPending() {
+	select {
+	case s.pendingCh <- struct{}{}:
+	default:
 	}
+}
+
+// processEmbedding 处理向量化，由EmbeddingWorker在领取任务后调用，item需持有租约令牌，失败时由worker负责重试
+func (s *Service) processEmbedding(ctx context.Context, item *knowledge.KnowledgeItem) error {
+	ctx, span := observability.StartSpan(ctx, "knowledge-service", "processEmbedding")
+	defer span.End()
 
 	// 只处理文本内容
 	if item.ContentType != "text" || item.Content == "" {
 		s.logger.Info("跳过非文本内容的向量化", zap.String("content_type", item.ContentType))
-		if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, "completed", ""); err != nil {
-			s.logger.Error("更新向量化状态失败", zap.Error(err))
+		if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, *item.LeaseToken, "completed", ""); err != nil {
+			return fmt.Errorf("更新向量化状态失败: %w", err)
 		}
-		return
+		return nil
 	}
 
-	// 调用embedding服务
-	embeddingVector, err := s.embeddingSvc.EmbedText(ctx, item.Content)
+	// 旧分块需在写入新向量前查出：分块ID由知识项ID和序号确定，写入会覆盖旧分块指向的向量
+	oldChunks, err := s.repo.ListItemChunks(ctx, item.ID)
 	if err != nil {
-		s.logger.Error("向量化失败", zap.Error(err))
-		if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, "failed", ""); err != nil {
-			s.logger.Error("更新向量化状态失败", zap.Error(err))
+		return err
+	}
+
+	// 切分并逐块向量化。分块ID由知识项ID和序号确定，重试时会覆盖同一批向量
+	pieces := s.splitter.Split(item.Content)
+	chunks := make([]*knowledge.KnowledgeChunk, len(pieces))
+	for i, piece := range pieces {
+		chunkID := uuid.NewSHA1(item.ID, []byte(fmt.Sprintf("chunk-%d", piece.Index)))
+		chunks[i] = &knowledge.KnowledgeChunk{
+			ID:              chunkID,
+			KnowledgeItemID: item.ID,
+			KnowledgeBaseID: item.KnowledgeBaseID,
+			ChunkIndex:      piece.Index,
+			Heading:         piece.Heading,
+			Content:         piece.Content,
+			StartOffset:     piece.Start,
+			EndOffset:       piece.End,
+			VectorID:        chunkID.String(),
+		}
+
+		// 标题和章节路径一并参与向量化，提升短分块的检索效果
+		text := item.Title + "\n" + piece.Content
+		if piece.Heading != "" {
+			text = item.Title + "\n" + piece.Heading + "\n" + piece.Content
+		}
+
+		embeddingVector, err := s.embeddingSvc.EmbedText(ctx, text)
+		if err != nil {
+			return fmt.Errorf("向量化第%d个分块失败: %w", piece.Index, err)
+		}
+
+		// 存储向量到Qdrant
+		payload := map[string]interface{}{
+			"knowledge_base_id": item.KnowledgeBaseID.String(),
+			"item_id":           item.ID.String(),
+			"chunk_id":          chunkID.String(),
+			"chunk_index":       piece.Index,
+			"chunk_count":       len(pieces),
+			"start_offset":      piece.Start,
+			"end_offset":        piece.End,
+			"heading":           piece.Heading,
+			"title":             item.Title,
+			"content_type":      item.ContentType,
+		}
+
+		if err := s.vectorSvc.Store(ctx, vector.StoreRequest{
+			ID:      chunks[i].VectorID,
+			Vector:  embeddingVector,
+			Payload: payload,
+		}); err != nil {
+			return fmt.Errorf("存储第%d个分块向量失败: %w", piece.Index, err)
 		}
-		return
 	}
 
-	// 存储向量到Qdrant
-	vectorID := item.ID.String()
-	payload := map[string]interface{}{
-		"knowledge_base_id": item.KnowledgeBaseID.String(),
-		"item_id":          item.ID.String(),
-		"title":            item.Title,
-		"content_type":     item.ContentType,
+	if err := s.repo.ReplaceItemChunks(ctx, item.ID, chunks); err != nil {
+		// 知识项在处理期间被删除时，刚写入的向量已无分块记录指向，全部清理。
+		// 否则保留向量并返回错误，由worker重试，重试会重新写入同一批向量并保存分块
+		if _, getErr := s.repo.GetItem(ctx, item.ID); errors.Is(getErr, knowledge.ErrItemNotFound) {
+			vectorIDs := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				vectorIDs[i] = chunk.VectorID
+			}
+			s.deleteVectors(ctx, vectorIDs)
+		}
+		return fmt.Errorf("保存分块失败: %w", err)
 	}
 
-	if err := s.vectorSvc.Store(ctx, vector.StoreRequest{
-		ID:      vectorID,
-		Vector:  embeddingVector,
-		Payload: payload,
-	}); err != nil {
-		s.logger.Error("存储向量失败", zap.Error(err))
-		if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, "failed", ""); err != nil {
-			s.logger.Error("更新向量化状态失败", zap.Error(err))
+	// 清理不再使用的旧向量（内容变短后多出的分块，以及分块前整篇存储的向量）
+	current := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		current[chunk.VectorID] = true
+	}
+	var staleVectorIDs []string
+	for _, chunk := range oldChunks {
+		if !current[chunk.VectorID] {
+			staleVectorIDs = append(staleVectorIDs, chunk.VectorID)
 		}
-		return
 	}
+	if item.VectorID != "" && !current[item.VectorID] {
+		staleVectorIDs = append(staleVectorIDs, item.VectorID)
+	}
+	s.deleteVectors(ctx, staleVectorIDs)
 
-	// 更新状态为完成
-	if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, "completed", vectorID); err != nil {
-		s.logger.Error("更新向量化状态失败", zap.Error(err))
-		return
+	// 更新状态为完成，知识项的向量ID记录首个分块的向量ID
+	var vectorID string
+	if len(chunks) > 0 {
+		vectorID = chunks[0].VectorID
+	}
+	if err := s.repo.UpdateItemEmbeddingStatus(ctx, item.ID, *item.LeaseToken, "completed", vectorID); err != nil {
+		return fmt.Errorf("更新向量化状态失败: %w", err)
 	}
 
-	s.logger.Info("向量化完成", zap.String("item_id", item.ID.String()), zap.String("vector_id", vectorID))
+	s.logger.Info("向量化完成", zap.String("item_id", item.ID.String()), zap.Int("chunks", len(chunks)))
+	return nil
 }
-
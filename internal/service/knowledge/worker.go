@@ -0,0 +1,233 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yoga/knowledge-base/internal/domain/knowledge"
+	"go.uber.org/zap"
+)
+
+// statusUpdateTimeout worker停止时仍需写回任务状态，使用独立的超时而非已取消的ctx
+const statusUpdateTimeout = 5 * time.Second
+
+// WorkerConfig 向量化worker配置
+type WorkerConfig struct {
+	Concurrency  int           // 并发处理数，每个空闲的处理协程领取一个任务
+	PollInterval time.Duration // 轮询间隔
+	LeaseTimeout time.Duration // 租约时长，处理期间定期续租，超时未续租的任务会被重新领取
+	MaxAttempts  int           // 最大尝试次数，超过后进入死信状态（failed）
+	BaseBackoff  time.Duration // 首次重试等待时间
+	MaxBackoff   time.Duration // 重试等待时间上限
+}
+
+// EmbeddingWorker 后台向量化worker
+//
+// 通过Repository.GetPendingItems从数据库领取任务，实例重启或崩溃后，
+// 未完成的任务会在租约过期后被重新领取，不会永远停留在pending/processing状态。
+// 领取时生成新的租约令牌，续租与状态更新都要求令牌一致，租约被其他worker接手后原worker的写入不会生效。
+type EmbeddingWorker struct {
+	svc    *Service
+	cfg    WorkerConfig
+	logger *zap.Logger
+
+	stop   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEmbeddingWorker 创建向量化worker
+func NewEmbeddingWorker(svc *Service, cfg WorkerConfig, logger *zap.Logger) *EmbeddingWorker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.LeaseTimeout <= 0 {
+		cfg.LeaseTimeout = 5 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 10 * time.Second
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = cfg.BaseBackoff
+	}
+
+	return &EmbeddingWorker{
+		svc:    svc,
+		cfg:    cfg,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start 启动worker（非阻塞）
+func (w *EmbeddingWorker) Start() {
+	// 任务处理使用独立的context，停止领取后已领取的任务仍可继续完成
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		w.wg.Add(1)
+		go w.runProcessor(ctx)
+	}
+
+	w.logger.Info("向量化worker已启动",
+		zap.Int("concurrency", w.cfg.Concurrency),
+		zap.Duration("poll_interval", w.cfg.PollInterval))
+}
+
+// Shutdown 停止领取新任务并等待已领取的任务处理完成
+//
+// 若ctx先到期，则取消正在进行的任务并返回ctx的错误；被中断的任务立即放回队列，写回失败时在租约过期后被重新领取。
+func (w *EmbeddingWorker) Shutdown(ctx context.Context) error {
+	close(w.stop)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+		w.logger.Info("向量化worker已停止")
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// runProcessor 空闲时领取一个任务并处理，没有任务时等待轮询或新建知识项的唤醒
+func (w *EmbeddingWorker) runProcessor(ctx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		items, err := w.svc.repo.GetPendingItems(ctx, 1, w.cfg.LeaseTimeout)
+		if err != nil {
+			w.logger.Error("领取向量化任务失败", zap.Error(err))
+		}
+		if len(items) > 0 {
+			w.process(ctx, items[0])
+			// 可能还有积压任务，立即继续领取
+			continue
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.svc.pendingCh:
+		}
+	}
+}
+
+// process 处理单个向量化任务，处理期间定期续租，租约失效时中止处理
+func (w *EmbeddingWorker) process(ctx context.Context, item *knowledge.KnowledgeItem) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	leaseLost := make(chan bool, 1)
+	go func() {
+		leaseLost <- w.renewLease(jobCtx, cancel, item)
+	}()
+
+	err := w.svc.processEmbedding(jobCtx, item)
+	cancel()
+	lost := <-leaseLost
+
+	if err == nil {
+		return
+	}
+	if lost || errors.Is(err, knowledge.ErrLeaseLost) {
+		w.logger.Warn("向量化任务租约已失效，放弃处理结果", zap.String("item_id", item.ID.String()))
+		return
+	}
+
+	// worker停止时ctx已取消，状态写回使用独立的ctx
+	statusCtx, statusCancel := context.WithTimeout(context.Background(), statusUpdateTimeout)
+	defer statusCancel()
+
+	if ctx.Err() != nil {
+		// 因worker停止而中断的任务立即放回队列，不计退避
+		if err := w.svc.repo.RetryItemEmbedding(statusCtx, item.ID, *item.LeaseToken, "worker停止，任务已中断", time.Now()); err != nil {
+			w.logger.Error("放回向量化任务失败", zap.Error(err), zap.String("item_id", item.ID.String()))
+		}
+		return
+	}
+
+	if item.EmbeddingAttempts >= w.cfg.MaxAttempts {
+		w.logger.Error("向量化失败，已达最大尝试次数",
+			zap.Error(err),
+			zap.String("item_id", item.ID.String()),
+			zap.Int("attempts", item.EmbeddingAttempts))
+		if err := w.svc.repo.FailItemEmbedding(statusCtx, item.ID, *item.LeaseToken, err.Error()); err != nil {
+			w.logger.Error("更新向量化状态失败", zap.Error(err))
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(w.backoff(item.EmbeddingAttempts))
+	w.logger.Warn("向量化失败，稍后重试",
+		zap.Error(err),
+		zap.String("item_id", item.ID.String()),
+		zap.Int("attempts", item.EmbeddingAttempts),
+		zap.Time("next_attempt_at", nextAttemptAt))
+	if err := w.svc.repo.RetryItemEmbedding(statusCtx, item.ID, *item.LeaseToken, err.Error(), nextAttemptAt); err != nil {
+		w.logger.Error("更新向量化状态失败", zap.Error(err))
+	}
+}
+
+// renewLease 每隔租约时长的三分之一续租一次，直到ctx结束；租约已被其他worker接手时调用cancel中止处理并返回true
+func (w *EmbeddingWorker) renewLease(ctx context.Context, cancel context.CancelFunc, item *knowledge.KnowledgeItem) bool {
+	ticker := time.NewTicker(w.cfg.LeaseTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		err := w.svc.repo.RenewItemLease(ctx, item.ID, *item.LeaseToken, w.cfg.LeaseTimeout)
+		switch {
+		case err == nil:
+		case errors.Is(err, knowledge.ErrLeaseLost):
+			w.logger.Warn("向量化任务租约已失效，中止处理", zap.String("item_id", item.ID.String()))
+			cancel()
+			return true
+		case ctx.Err() == nil:
+			// 暂时无法续租时继续处理，下次再试
+			w.logger.Warn("续租向量化任务失败", zap.Error(err), zap.String("item_id", item.ID.String()))
+		}
+	}
+}
+
+// backoff 计算第attempts次失败后的指数退避时间
+func (w *EmbeddingWorker) backoff(attempts int) time.Duration {
+	d := w.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= w.cfg.MaxBackoff {
+			return w.cfg.MaxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,217 @@
+package location
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/location"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// Repository 场馆与教室仓储接口
+type Repository interface {
+	CreateLocation(ctx context.Context, loc *location.Location) error
+	GetLocation(ctx context.Context, id uuid.UUID) (*location.Location, error)
+	ListLocations(ctx context.Context) ([]*location.Location, error)
+	UpdateLocation(ctx context.Context, loc *location.Location) error
+	CreateRoom(ctx context.Context, room *location.Room) error
+	GetRoom(ctx context.Context, id uuid.UUID) (*location.Room, error)
+	UpdateRoom(ctx context.Context, room *location.Room) error
+}
+
+// LocationInput 创建或修改场馆的参数
+type LocationInput struct {
+	Name    string
+	Address string
+}
+
+// RoomInput 创建或修改教室的参数
+type RoomInput struct {
+	Name      string
+	Capacity  int
+	Equipment []string
+}
+
+// Service 场馆与教室服务
+type Service struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+// NewService 创建场馆与教室服务
+func NewService(repo Repository, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateLocation 创建场馆（管理员）
+func (s *Service) CreateLocation(ctx context.Context, in LocationInput) (*location.Location, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "CreateLocation")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	loc := &location.Location{Name: in.Name, Address: in.Address}
+	if err := loc.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateLocation(ctx, loc); err != nil {
+		return nil, err
+	}
+	loc.Rooms = []*location.Room{}
+	return loc, nil
+}
+
+// UpdateLocation 修改场馆名称与地址（管理员）
+func (s *Service) UpdateLocation(ctx context.Context, id uuid.UUID, in LocationInput) (*location.Location, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "UpdateLocation")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	loc, err := s.repo.GetLocation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	loc.Name = in.Name
+	loc.Address = in.Address
+	if err := loc.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateLocation(ctx, loc); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// GetLocation 获取场馆及其教室
+func (s *Service) GetLocation(ctx context.Context, id uuid.UUID) (*location.Location, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "GetLocation")
+	defer span.End()
+
+	return s.repo.GetLocation(ctx, id)
+}
+
+// ListLocations 列出全部场馆及其教室
+func (s *Service) ListLocations(ctx context.Context) ([]*location.Location, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "ListLocations")
+	defer span.End()
+
+	return s.repo.ListLocations(ctx)
+}
+
+// MatchLocation 按名称查找场馆，名称完全一致优先，否则要求名称片段只匹配到一个场馆，
+// 如“静安”匹配“静安店”。找不到或匹配到多个时错误信息中列出可选场馆。
+func (s *Service) MatchLocation(ctx context.Context, name string) (*location.Location, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "MatchLocation")
+	defer span.End()
+
+	locations, err := s.repo.ListLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	var matched []*location.Location
+	for _, loc := range locations {
+		if loc.Name == name {
+			return loc, nil
+		}
+		if name != "" && strings.Contains(loc.Name, name) {
+			matched = append(matched, loc)
+		}
+	}
+	if len(matched) == 1 {
+		return matched[0], nil
+	}
+
+	candidates := locations
+	if len(matched) > 1 {
+		candidates = matched
+	}
+	names := make([]string, 0, len(candidates))
+	for _, loc := range candidates {
+		names = append(names, loc.Name)
+	}
+	if len(matched) > 1 {
+		return nil, fmt.Errorf("%w: 「%s」匹配到多个场馆：%s", location.ErrLocationNotFound, name, strings.Join(names, "、"))
+	}
+	return nil, fmt.Errorf("%w: 「%s」，可选场馆：%s", location.ErrLocationNotFound, name, strings.Join(names, "、"))
+}
+
+// CreateRoom 在场馆内创建教室（管理员）
+func (s *Service) CreateRoom(ctx context.Context, locationID uuid.UUID, in RoomInput) (*location.Room, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "CreateRoom")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetLocation(ctx, locationID); err != nil {
+		return nil, err
+	}
+
+	room := &location.Room{
+		LocationID: locationID,
+		Name:       in.Name,
+		Capacity:   in.Capacity,
+		Equipment:  in.Equipment,
+	}
+	if err := room.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRoom(ctx, room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// UpdateRoom 修改教室信息（管理员）。缩小容量不影响已排的课程，之后排课或修改课程时按新容量校验。
+func (s *Service) UpdateRoom(ctx context.Context, locationID, roomID uuid.UUID, in RoomInput) (*location.Room, error) {
+	ctx, span := observability.StartSpan(ctx, "location-service", "UpdateRoom")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	room, err := s.repo.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.LocationID != locationID {
+		return nil, location.ErrRoomNotFound
+	}
+	room.Name = in.Name
+	room.Capacity = in.Capacity
+	room.Equipment = in.Equipment
+	if err := room.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRoom(ctx, room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// requireAdmin 要求当前用户为管理员
+func requireAdmin(ctx context.Context) error {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return err
+	}
+	if !u.IsAdmin() {
+		return user.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package membership
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/membership"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// Repository 会员卡仓储接口
+type Repository interface {
+	CreatePlan(ctx context.Context, plan *membership.Plan) error
+	GetPlan(ctx context.Context, id uuid.UUID) (*membership.Plan, error)
+	ListPlans(ctx context.Context, activeOnly bool) ([]*membership.Plan, error)
+	UpdatePlan(ctx context.Context, plan *membership.Plan) error
+	CreateSubscription(ctx context.Context, sub *membership.Subscription, createdBy string) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*membership.Subscription, error)
+	ListUserSubscriptions(ctx context.Context, userID string) ([]*membership.Subscription, error)
+	FreezeSubscription(ctx context.Context, id uuid.UUID, at time.Time) (*membership.Subscription, error)
+	UnfreezeSubscription(ctx context.Context, id uuid.UUID, at time.Time) (*membership.Subscription, error)
+	AdjustCredits(ctx context.Context, id uuid.UUID, delta int, note, by string) (*membership.Subscription, error)
+	ListLedger(ctx context.Context, subscriptionID uuid.UUID) ([]*membership.LedgerEntry, error)
+}
+
+// PlanInput 创建或修改会员卡方案的参数
+type PlanInput struct {
+	Name         string
+	Description  string
+	Kind         membership.PlanKind
+	Credits      *int
+	ValidityDays int
+	PriceCents   int
+	Active       bool
+}
+
+// Service 会员卡服务
+type Service struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+// NewService 创建会员卡服务
+func NewService(repo Repository, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreatePlan 创建会员卡方案（管理员）
+func (s *Service) CreatePlan(ctx context.Context, in PlanInput) (*membership.Plan, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "CreatePlan")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	plan := &membership.Plan{}
+	in.apply(plan)
+	if err := plan.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreatePlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// UpdatePlan 修改会员卡方案（管理员），已开出的会员卡按开卡时的规则使用
+func (s *Service) UpdatePlan(ctx context.Context, id uuid.UUID, in PlanInput) (*membership.Plan, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "UpdatePlan")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.repo.GetPlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	in.apply(plan)
+	if err := plan.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetPlan 获取会员卡方案
+func (s *Service) GetPlan(ctx context.Context, id uuid.UUID) (*membership.Plan, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "GetPlan")
+	defer span.End()
+
+	return s.repo.GetPlan(ctx, id)
+}
+
+// ListPlans 列出在售的会员卡方案，管理员可同时查看已停售的方案
+func (s *Service) ListPlans(ctx context.Context, includeInactive bool) ([]*membership.Plan, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "ListPlans")
+	defer span.End()
+
+	if includeInactive {
+		if err := requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.ListPlans(ctx, !includeInactive)
+}
+
+// IssueSubscription 按方案为用户开卡，有效期从startsAt起算。issuedBy为操作人的用户ID（购买时为本人）。
+func (s *Service) IssueSubscription(ctx context.Context, userID string, planID uuid.UUID, startsAt time.Time, issuedBy string) (*membership.Subscription, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "IssueSubscription")
+	defer span.End()
+
+	plan, err := s.repo.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.Active {
+		return nil, membership.ErrPlanInactive
+	}
+
+	sub := membership.NewSubscription(plan, userID, startsAt)
+	if err := s.repo.CreateSubscription(ctx, sub, issuedBy); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("已开卡",
+		zap.String("subscription_id", sub.ID.String()),
+		zap.String("user_id", userID),
+		zap.String("plan_id", planID.String()),
+		zap.String("issued_by", issuedBy),
+	)
+	return sub, nil
+}
+
+// GrantSubscription 管理员为用户开卡（线下购买、赠送等）
+func (s *Service) GrantSubscription(ctx context.Context, userID, planID uuid.UUID, startsAt *time.Time) (*membership.Subscription, error) {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+
+	start := time.Now()
+	if startsAt != nil {
+		start = *startsAt
+	}
+	return s.IssueSubscription(ctx, userID.String(), planID, start, u.ID.String())
+}
+
+// ListMySubscriptions 列出当前登录用户的会员卡
+func (s *Service) ListMySubscriptions(ctx context.Context) ([]*membership.Subscription, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "ListMySubscriptions")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListUserSubscriptions(ctx, u.ID.String())
+}
+
+// ListUserSubscriptions 列出指定用户的会员卡（管理员）
+func (s *Service) ListUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]*membership.Subscription, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "ListUserSubscriptions")
+	defer span.End()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.ListUserSubscriptions(ctx, userID.String())
+}
+
+// FreezeSubscription 冻结会员卡（持卡人或管理员），冻结期间不能预订
+func (s *Service) FreezeSubscription(ctx context.Context, id uuid.UUID) (*membership.Subscription, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "FreezeSubscription")
+	defer span.End()
+
+	if _, err := s.accessibleSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.FreezeSubscription(ctx, id, time.Now())
+}
+
+// UnfreezeSubscription 解冻会员卡（持卡人或管理员），有效期按冻结时长顺延
+func (s *Service) UnfreezeSubscription(ctx context.Context, id uuid.UUID) (*membership.Subscription, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "UnfreezeSubscription")
+	defer span.End()
+
+	if _, err := s.accessibleSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.UnfreezeSubscription(ctx, id, time.Now())
+}
+
+// AdjustCredits 调整次卡剩余节数（管理员），记录课时流水
+func (s *Service) AdjustCredits(ctx context.Context, id uuid.UUID, delta int, note string) (*membership.Subscription, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "AdjustCredits")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+
+	sub, err := s.repo.AdjustCredits(ctx, id, delta, note, u.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("已调整课时",
+		zap.String("subscription_id", id.String()),
+		zap.Int("delta", delta),
+		zap.String("adjusted_by", u.ID.String()),
+	)
+	return sub, nil
+}
+
+// ListLedger 查看会员卡的课时流水（持卡人或管理员）
+func (s *Service) ListLedger(ctx context.Context, id uuid.UUID) ([]*membership.LedgerEntry, error) {
+	ctx, span := observability.StartSpan(ctx, "membership-service", "ListLedger")
+	defer span.End()
+
+	if _, err := s.accessibleSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListLedger(ctx, id)
+}
+
+// accessibleSubscription 获取当前用户可以操作的会员卡：持卡人本人或管理员
+func (s *Service) accessibleSubscription(ctx context.Context, id uuid.UUID) (*membership.Subscription, error) {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != u.ID.String() && !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+	return sub, nil
+}
+
+// requireAdmin 要求当前用户为管理员
+func requireAdmin(ctx context.Context) error {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return err
+	}
+	if !u.IsAdmin() {
+		return user.ErrForbidden
+	}
+	return nil
+}
+
+// apply 将参数写入方案
+func (in PlanInput) apply(plan *membership.Plan) {
+	plan.Name = in.Name
+	plan.Description = in.Description
+	plan.Kind = in.Kind
+	plan.Credits = in.Credits
+	plan.ValidityDays = in.ValidityDays
+	plan.PriceCents = in.PriceCents
+	plan.Active = in.Active
+}
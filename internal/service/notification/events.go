@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/notification"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+// 预订事件通知在后台异步发送，不阻塞也不影响触发事件的请求；发送失败只记录日志。
+
+// BookingConfirmed 通知会员预订成功
+func (s *Service) BookingConfirmed(ctx context.Context, b *booking.Booking) {
+	s.async(ctx, "BookingConfirmed", func(ctx context.Context) error {
+		class, err := s.classes.GetClass(ctx, b.ClassID)
+		if err != nil {
+			return err
+		}
+		return s.send(ctx, b.UserID, notification.CategoryBooking, notification.TemplateBookingConfirmed, s.classData(class))
+	})
+}
+
+// WaitlistPromoted 通知会员候补已转为已确认
+func (s *Service) WaitlistPromoted(ctx context.Context, b *booking.Booking) {
+	s.async(ctx, "WaitlistPromoted", func(ctx context.Context) error {
+		class, err := s.classes.GetClass(ctx, b.ClassID)
+		if err != nil {
+			return err
+		}
+		return s.send(ctx, b.UserID, notification.CategoryBooking, notification.TemplateWaitlistPromoted, s.classData(class))
+	})
+}
+
+// ClassCancelled 通知课程取消时被一并取消的预订（已确认与候补中）的会员
+func (s *Service) ClassCancelled(ctx context.Context, class *booking.Class, bookings []*booking.Booking, reason string) {
+	if len(bookings) == 0 {
+		return
+	}
+	s.async(ctx, "ClassCancelled", func(ctx context.Context) error {
+		data := s.classData(class)
+		data["reason"] = reason
+		s.broadcast(ctx, bookings, notification.TemplateClassCancelled, data)
+		return nil
+	})
+}
+
+// InstructorChanged 通知课程已确认与候补中的会员授课教练已更换
+func (s *Service) InstructorChanged(ctx context.Context, class *booking.Class, previous string) {
+	s.async(ctx, "InstructorChanged", func(ctx context.Context) error {
+		bookings, err := s.repo.ListActiveBookings(ctx, class.ID)
+		if err != nil {
+			return err
+		}
+		data := s.classData(class)
+		data["previous_instructor"] = previous
+		if previous == "" {
+			data["previous_instructor"] = "待定"
+		}
+		s.broadcast(ctx, bookings, notification.TemplateInstructorChanged, data)
+		return nil
+	})
+}
+
+// broadcast 向多个预订的会员发送课程变动通知，单个会员失败不影响其他会员
+func (s *Service) broadcast(ctx context.Context, bookings []*booking.Booking, template string, data map[string]string) {
+	for _, b := range bookings {
+		if err := s.send(ctx, b.UserID, notification.CategoryClassChange, template, data); err != nil {
+			s.logger.Warn("发送课程变动通知失败",
+				zap.String("template", template),
+				zap.String("booking_id", b.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// async 在后台执行发送，脱离请求的取消信号但保留追踪信息
+func (s *Service) async(ctx context.Context, event string, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+		ctx, span := observability.StartSpan(ctx, "notification-service", event)
+		defer span.End()
+
+		if err := fn(ctx); err != nil {
+			s.logger.Warn("发送通知失败", zap.String("event", event), zap.Error(err))
+		}
+	}()
+}
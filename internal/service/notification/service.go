@@ -0,0 +1,261 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/booking"
+	"github.com/yoga/knowledge-base/internal/domain/notification"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/notify"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.uber.org/zap"
+)
+
+const (
+	// reminderBatchSize 每次上课提醒任务最多处理的预订数，其余留给下一次执行
+	reminderBatchSize = 200
+	// sendTimeout 异步发送一个事件通知的超时时间
+	sendTimeout = 30 * time.Second
+	// timeLayout 消息中上课时间的格式
+	timeLayout = "2006-01-02 15:04"
+)
+
+// Repository 通知仓储接口
+type Repository interface {
+	GetPreference(ctx context.Context, userID string) (*notification.Preference, error)
+	SavePreference(ctx context.Context, p *notification.Preference) error
+	ClaimReminders(ctx context.Context, now time.Time, lead time.Duration, limit int) ([]*booking.Booking, error)
+	ListActiveBookings(ctx context.Context, classID uuid.UUID) ([]*booking.Booking, error)
+}
+
+// UserReader 查询用户（取小程序openid）
+type UserReader interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// ClassReader 查询课程
+type ClassReader interface {
+	GetClass(ctx context.Context, id uuid.UUID) (*booking.Class, error)
+}
+
+// Sender 发送渲染后的消息，由 notify.Dispatcher 实现
+type Sender interface {
+	Send(ctx context.Context, to notify.Recipient, msg notify.Message) error
+}
+
+// Config 通知服务配置
+type Config struct {
+	ReminderLead time.Duration  // 开课前多久发送上课提醒
+	Location     *time.Location // 场馆所在时区，消息中的上课时间按此时区显示
+}
+
+// Service 通知服务：会员通知偏好、上课提醒与预订事件通知
+type Service struct {
+	repo     Repository
+	users    UserReader
+	classes  ClassReader
+	sender   Sender
+	renderer *notify.Renderer
+	cfg      Config
+	logger   *zap.Logger
+}
+
+// NewService 创建通知服务，消息使用默认模板渲染
+func NewService(repo Repository, users UserReader, classes ClassReader, sender Sender, cfg Config, logger *zap.Logger) (*Service, error) {
+	renderer, err := notify.NewRenderer(DefaultTemplates())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.ReminderLead <= 0 {
+		cfg.ReminderLead = 2 * time.Hour
+	}
+	return &Service{
+		repo:     repo,
+		users:    users,
+		classes:  classes,
+		sender:   sender,
+		renderer: renderer,
+		cfg:      cfg,
+		logger:   logger,
+	}, nil
+}
+
+// DefaultTemplates 默认消息模板
+func DefaultTemplates() map[string]notify.Template {
+	const page = "pages/class-detail/index?id={{.class_id}}"
+	return map[string]notify.Template{
+		notification.TemplateBookingConfirmed: {
+			Title: "预订成功：{{.class_name}}",
+			Body:  "您已成功预订{{.start_time}}的「{{.class_name}}」，授课教练{{.instructor}}。请提前到场签到。",
+			Page:  page,
+		},
+		notification.TemplateWaitlistPromoted: {
+			Title: "候补成功：{{.class_name}}",
+			Body:  "您候补的{{.start_time}}「{{.class_name}}」有了空位，预订已自动确认。如无法参加请及时取消。",
+			Page:  page,
+		},
+		notification.TemplateClassCancelled: {
+			Title: "课程取消：{{.class_name}}",
+			Body:  "很抱歉，{{.start_time}}的「{{.class_name}}」已取消{{if .reason}}（{{.reason}}）{{end}}，您的预订已一并取消，已扣除的课时全部退还。",
+			Page:  page,
+		},
+		notification.TemplateInstructorChanged: {
+			Title: "更换教练：{{.class_name}}",
+			Body:  "{{.start_time}}的「{{.class_name}}」授课教练由{{.previous_instructor}}更换为{{.instructor}}。如需取消请在小程序内操作。",
+			Page:  page,
+		},
+		notification.TemplateClassReminder: {
+			Title: "上课提醒：{{.class_name}}",
+			Body:  "您预订的「{{.class_name}}」将于{{.start_time}}开始，授课教练{{.instructor}}。请提前到场签到。",
+			Page:  page,
+		},
+	}
+}
+
+// GetMyPreference 获取当前登录用户的通知偏好
+func (s *Service) GetMyPreference(ctx context.Context) (*notification.Preference, error) {
+	ctx, span := observability.StartSpan(ctx, "notification-service", "GetMyPreference")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetPreference(ctx, u.ID.String())
+}
+
+// PreferenceInput 修改通知偏好的参数，为空的字段保持不变
+type PreferenceInput struct {
+	Phone          *string
+	Email          *string
+	Reminders      *bool
+	BookingUpdates *bool
+	ClassChanges   *bool
+}
+
+// UpdateMyPreference 修改当前登录用户的通知偏好与联系方式
+func (s *Service) UpdateMyPreference(ctx context.Context, in PreferenceInput) (*notification.Preference, error) {
+	ctx, span := observability.StartSpan(ctx, "notification-service", "UpdateMyPreference")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.repo.GetPreference(ctx, u.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if in.Phone != nil {
+		p.Phone = *in.Phone
+	}
+	if in.Email != nil {
+		p.Email = *in.Email
+	}
+	if in.Reminders != nil {
+		p.Reminders = *in.Reminders
+	}
+	if in.BookingUpdates != nil {
+		p.BookingUpdates = *in.BookingUpdates
+	}
+	if in.ClassChanges != nil {
+		p.ClassChanges = *in.ClassChanges
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SavePreference(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SendDueReminders 为即将开课的已确认预订发送上课提醒，返回发送成功的数量。
+// 每个预订（按开课时间）只提醒一次，会员关闭上课提醒时跳过。
+func (s *Service) SendDueReminders(ctx context.Context) (int, error) {
+	ctx, span := observability.StartSpan(ctx, "notification-service", "SendDueReminders")
+	defer span.End()
+
+	bookings, err := s.repo.ClaimReminders(ctx, time.Now(), s.cfg.ReminderLead, reminderBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	classes := make(map[uuid.UUID]*booking.Class)
+	sent := 0
+	for _, b := range bookings {
+		class, ok := classes[b.ClassID]
+		if !ok {
+			class, err = s.classes.GetClass(ctx, b.ClassID)
+			if err != nil {
+				s.logger.Warn("发送上课提醒失败", zap.String("booking_id", b.ID.String()), zap.Error(err))
+				continue
+			}
+			classes[b.ClassID] = class
+		}
+
+		if err := s.send(ctx, b.UserID, notification.CategoryReminder, notification.TemplateClassReminder, s.classData(class)); err != nil {
+			s.logger.Warn("发送上课提醒失败", zap.String("booking_id", b.ID.String()), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+
+	if len(bookings) > 0 {
+		s.logger.Info("已发送上课提醒", zap.Int("claimed", len(bookings)), zap.Int("sent", sent))
+	}
+	return sent, nil
+}
+
+// send 按用户的通知偏好渲染并发送消息，用户关闭该类别时不发送
+func (s *Service) send(ctx context.Context, userID string, category notification.Category, template string, data map[string]string) error {
+	pref, err := s.repo.GetPreference(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !pref.Allows(category) {
+		return nil
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("无效的用户ID %s: %w", userID, err)
+	}
+	u, err := s.users.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.renderer.Render(template, data)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, notify.Recipient{
+		UserID: userID,
+		OpenID: u.OpenID,
+		Phone:  pref.Phone,
+		Email:  pref.Email,
+	}, msg)
+}
+
+// classData 课程的消息数据
+func (s *Service) classData(class *booking.Class) map[string]string {
+	instructor := class.Instructor
+	if instructor == "" {
+		instructor = "待定"
+	}
+	return map[string]string{
+		"class_id":   class.ID.String(),
+		"class_name": class.Name,
+		"instructor": instructor,
+		"start_time": class.StartTime.In(s.cfg.Location).Format(timeLayout),
+	}
+}
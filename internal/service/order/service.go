@@ -0,0 +1,349 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/membership"
+	"github.com/yoga/knowledge-base/internal/domain/order"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"github.com/yoga/knowledge-base/pkg/payment"
+	"go.uber.org/zap"
+)
+
+// expireBatchSize 每次关闭超时订单任务最多处理的订单数，其余留给下一次执行
+const expireBatchSize = 100
+
+// Repository 订单仓储接口
+type Repository interface {
+	CreateOrder(ctx context.Context, o *order.Order) error
+	GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error)
+	GetOrderByNo(ctx context.Context, orderNo string) (*order.Order, error)
+	ListUserOrders(ctx context.Context, userID string) ([]*order.Order, error)
+	ListExpiredOrders(ctx context.Context, now time.Time, limit int) ([]*order.Order, error)
+	MarkPaid(ctx context.Context, orderNo, transactionID string, paidAt time.Time) (*order.Order, bool, error)
+	CloseOrder(ctx context.Context, id uuid.UUID, at time.Time) (*order.Order, error)
+	MarkRefunded(ctx context.Context, id uuid.UUID, refundNo string, at time.Time) (*order.Order, error)
+}
+
+// PlanReader 查询会员卡方案
+type PlanReader interface {
+	GetPlan(ctx context.Context, id uuid.UUID) (*membership.Plan, error)
+}
+
+// paymentSimulator 支持模拟付款的网关（本地假网关）
+type paymentSimulator interface {
+	Pay(outTradeNo string) (*http.Request, error)
+}
+
+// Config 订单业务配置
+type Config struct {
+	OrderTTL time.Duration // 下单后的支付时限
+}
+
+// Service 订单服务：购买会员卡方案、处理支付结果与退款
+type Service struct {
+	repo    Repository
+	plans   PlanReader
+	gateway payment.Gateway
+	cfg     Config
+	logger  *zap.Logger
+}
+
+// NewService 创建订单服务
+func NewService(repo Repository, plans PlanReader, gateway payment.Gateway, cfg Config, logger *zap.Logger) *Service {
+	return &Service{
+		repo:    repo,
+		plans:   plans,
+		gateway: gateway,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// CreateOrder 当前用户购买会员卡方案：向支付网关下单，返回订单及小程序调起支付的参数
+func (s *Service) CreateOrder(ctx context.Context, planID uuid.UUID) (*order.Order, *payment.PayParams, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "CreateOrder")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := s.plans.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !plan.Active {
+		return nil, nil, membership.ErrPlanInactive
+	}
+	if plan.PriceCents <= 0 {
+		return nil, nil, order.ErrFreePlan
+	}
+
+	now := time.Now()
+	o := &order.Order{
+		ID:          uuid.New(),
+		OrderNo:     order.NewOrderNo(now),
+		UserID:      u.ID.String(),
+		PlanID:      plan.ID,
+		Description: plan.Name,
+		AmountCents: plan.PriceCents,
+		Status:      order.StatusPending,
+		ExpiresAt:   now.Add(s.cfg.OrderTTL),
+	}
+
+	// 先向网关下单，失败时不留下本地订单
+	prepay, err := s.gateway.CreateOrder(ctx, payment.OrderRequest{
+		OutTradeNo:  o.OrderNo,
+		Description: o.Description,
+		AmountCents: o.AmountCents,
+		PayerOpenID: u.OpenID,
+		ExpiresAt:   o.ExpiresAt,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	o.PrepayID = prepay.PrepayID
+
+	if err := s.repo.CreateOrder(ctx, o); err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.Info("已创建订单",
+		zap.String("order_no", o.OrderNo),
+		zap.String("user_id", o.UserID),
+		zap.String("plan_id", plan.ID.String()),
+		zap.Int("amount_cents", o.AmountCents),
+	)
+	return o, &prepay.Params, nil
+}
+
+// HandleNotify 处理支付网关的支付结果通知。签名无效时返回payment.ErrInvalidSignature；
+// 网关可能重复通知同一笔交易，重复通知不会重复开卡。
+func (s *Service) HandleNotify(ctx context.Context, r *http.Request) error {
+	ctx, span := observability.StartSpan(ctx, "order-service", "HandleNotify")
+	defer span.End()
+
+	txn, err := s.gateway.ParseNotify(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.applyTransaction(ctx, txn)
+	return err
+}
+
+// applyTransaction 按网关返回的交易更新订单：支付成功时核对金额并开卡，其他状态不做处理
+func (s *Service) applyTransaction(ctx context.Context, txn *payment.Transaction) (*order.Order, error) {
+	o, err := s.repo.GetOrderByNo(ctx, txn.OutTradeNo)
+	if err != nil {
+		return nil, err
+	}
+	if txn.State != payment.TradeSuccess {
+		return o, nil
+	}
+	if txn.AmountCents != o.AmountCents {
+		return nil, fmt.Errorf("%w: 订单%s应付%d分，实付%d分", order.ErrAmountMismatch, o.OrderNo, o.AmountCents, txn.AmountCents)
+	}
+
+	paidAt := time.Now()
+	if txn.PaidAt != nil {
+		paidAt = *txn.PaidAt
+	}
+	o, changed, err := s.repo.MarkPaid(ctx, txn.OutTradeNo, txn.TransactionID, paidAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		s.logger.Info("订单已支付",
+			zap.String("order_no", o.OrderNo),
+			zap.String("transaction_id", txn.TransactionID),
+			zap.String("subscription_id", o.SubscriptionID.String()),
+		)
+	} else {
+		s.logger.Debug("重复的支付结果，已忽略", zap.String("order_no", o.OrderNo))
+	}
+	return o, nil
+}
+
+// GetOrder 获取订单（下单人或管理员）
+func (s *Service) GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "GetOrder")
+	defer span.End()
+
+	return s.accessibleOrder(ctx, id)
+}
+
+// ListMyOrders 列出当前登录用户的订单
+func (s *Service) ListMyOrders(ctx context.Context) ([]*order.Order, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "ListMyOrders")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListUserOrders(ctx, u.ID.String())
+}
+
+// SyncOrder 向支付网关查单并更新订单（下单人或管理员），用于支付通知丢失或延迟时补单
+func (s *Service) SyncOrder(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "SyncOrder")
+	defer span.End()
+
+	o, err := s.accessibleOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != order.StatusPending && o.Status != order.StatusClosed {
+		return o, nil
+	}
+
+	txn, err := s.gateway.QueryOrder(ctx, o.OrderNo)
+	if err != nil {
+		if errors.Is(err, payment.ErrTradeNotFound) {
+			return o, nil
+		}
+		return nil, err
+	}
+	return s.applyTransaction(ctx, txn)
+}
+
+// RefundOrder 全额退款已支付的订单并作废开出的会员卡（管理员）。重复退款返回已退款的订单。
+func (s *Service) RefundOrder(ctx context.Context, id uuid.UUID, reason string) (*order.Order, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "RefundOrder")
+	defer span.End()
+
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+
+	o, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status == order.StatusRefunded {
+		return o, nil
+	}
+	if !order.CanTransition(o.Status, order.StatusRefunded) {
+		return nil, order.ErrInvalidTransition
+	}
+
+	refundNo := order.RefundNoFor(o.OrderNo)
+	refund, err := s.gateway.Refund(ctx, payment.RefundRequest{
+		OutTradeNo:  o.OrderNo,
+		OutRefundNo: refundNo,
+		RefundCents: o.AmountCents,
+		TotalCents:  o.AmountCents,
+		Reason:      reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o, err = s.repo.MarkRefunded(ctx, o.ID, refundNo, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("订单已退款",
+		zap.String("order_no", o.OrderNo),
+		zap.String("refund_id", refund.RefundID),
+		zap.String("refund_status", refund.Status),
+		zap.String("refunded_by", u.ID.String()),
+	)
+	return o, nil
+}
+
+// SimulatePayment 模拟下单人完成付款（仅本地假网关）：由网关生成签名的支付通知并按真实回调处理
+func (s *Service) SimulatePayment(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "SimulatePayment")
+	defer span.End()
+
+	simulator, ok := s.gateway.(paymentSimulator)
+	if !ok {
+		return nil, order.ErrSimulationUnavailable
+	}
+
+	o, err := s.accessibleOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := simulator.Pay(o.OrderNo)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.HandleNotify(ctx, req.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOrder(ctx, id)
+}
+
+// CloseExpiredOrders 关闭超过支付时限的订单，返回关闭的订单数。由定时任务调用。
+// 关闭前先向网关查单，已付款的订单按支付成功处理；单个订单失败时记录日志并继续处理其余订单。
+func (s *Service) CloseExpiredOrders(ctx context.Context) (int, error) {
+	ctx, span := observability.StartSpan(ctx, "order-service", "CloseExpiredOrders")
+	defer span.End()
+
+	now := time.Now()
+	orders, err := s.repo.ListExpiredOrders(ctx, now, expireBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, o := range orders {
+		txn, err := s.gateway.QueryOrder(ctx, o.OrderNo)
+		switch {
+		case err == nil && txn.State == payment.TradeSuccess:
+			if _, err := s.applyTransaction(ctx, txn); err != nil {
+				s.logger.Error("补单失败", zap.String("order_no", o.OrderNo), zap.Error(err))
+			}
+			continue
+		case err != nil && !errors.Is(err, payment.ErrTradeNotFound):
+			s.logger.Error("查询支付订单失败", zap.String("order_no", o.OrderNo), zap.Error(err))
+			continue
+		}
+
+		if _, err := s.repo.CloseOrder(ctx, o.ID, now); err != nil {
+			// 订单在查单期间已支付
+			if errors.Is(err, order.ErrInvalidTransition) {
+				continue
+			}
+			s.logger.Error("关闭订单失败", zap.String("order_no", o.OrderNo), zap.Error(err))
+			continue
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// accessibleOrder 获取当前用户可以查看的订单：下单人本人或管理员
+func (s *Service) accessibleOrder(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	u, err := user.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if o.UserID != u.ID.String() && !u.IsAdmin() {
+		return nil, user.ErrForbidden
+	}
+	return o, nil
+}
@@ -0,0 +1,282 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yoga/knowledge-base/internal/domain/membership"
+	"github.com/yoga/knowledge-base/internal/domain/order"
+	"github.com/yoga/knowledge-base/internal/domain/user"
+	"github.com/yoga/knowledge-base/internal/repository/postgres"
+	"github.com/yoga/knowledge-base/pkg/payment"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 订单流程测试使用本地假网关和真实数据库，验证支付通知的验签、幂等开卡、关闭后付款与退款。
+// TEST_DATABASE_URL 指向已执行 configs/init.sql 的测试数据库，未设置时跳过。
+
+// orderTest 一次测试的订单服务、假网关、方案与下单用户
+type orderTest struct {
+	db      *gorm.DB
+	svc     *Service
+	repo    *postgres.OrderRepository
+	gateway *payment.FakeGateway
+	plan    *membership.Plan
+	member  context.Context
+	admin   context.Context
+	userID  string
+}
+
+func newOrderTest(t *testing.T) *orderTest {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("未设置TEST_DATABASE_URL，跳过需要数据库的测试")
+	}
+	db, err := postgres.GetDB(dsn)
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		t.Cleanup(func() { sqlDB.Close() })
+	}
+
+	ctx := context.Background()
+	credits := 10
+	plan := &membership.Plan{
+		Name:         "测试次卡",
+		Kind:         membership.KindClassPack,
+		Credits:      &credits,
+		ValidityDays: 90,
+		PriceCents:   99900,
+		Active:       true,
+	}
+	if err := postgres.NewMembershipRepository(db).CreatePlan(ctx, plan); err != nil {
+		t.Fatalf("创建方案失败: %v", err)
+	}
+
+	member := &user.User{ID: uuid.New(), OpenID: "test-" + uuid.NewString(), Role: user.RoleMember}
+	admin := &user.User{ID: uuid.New(), OpenID: "test-" + uuid.NewString(), Role: user.RoleAdmin}
+	userID := member.ID.String()
+	t.Cleanup(func() {
+		subs := db.Model(&membership.Subscription{}).Select("id").Where("user_id = ?", userID)
+		db.Where("user_id = ?", userID).Delete(&order.Order{})
+		db.Where("subscription_id IN (?)", subs).Delete(&membership.LedgerEntry{})
+		db.Where("user_id = ?", userID).Delete(&membership.Subscription{})
+		db.Delete(plan)
+	})
+
+	repo := postgres.NewOrderRepository(db)
+	gateway := payment.NewFakeGateway("test-secret")
+	return &orderTest{
+		db:      db,
+		svc:     NewService(repo, postgres.NewMembershipRepository(db), gateway, Config{OrderTTL: 15 * time.Minute}, zap.NewNop()),
+		repo:    repo,
+		gateway: gateway,
+		plan:    plan,
+		member:  user.WithUser(ctx, member),
+		admin:   user.WithUser(ctx, admin),
+		userID:  userID,
+	}
+}
+
+// createOrder 以会员身份下单
+func (ot *orderTest) createOrder(t *testing.T) *order.Order {
+	t.Helper()
+
+	o, params, err := ot.svc.CreateOrder(ot.member, ot.plan.ID)
+	if err != nil {
+		t.Fatalf("下单失败: %v", err)
+	}
+	if o.Status != order.StatusPending || o.AmountCents != ot.plan.PriceCents || params.PaySign == "" {
+		t.Fatalf("下单结果不正确: status=%s amount=%d", o.Status, o.AmountCents)
+	}
+	return o
+}
+
+// notify 模拟付款并处理网关发出的支付通知
+func (ot *orderTest) notify(t *testing.T, o *order.Order) error {
+	t.Helper()
+
+	req, err := ot.gateway.Pay(o.OrderNo)
+	if err != nil {
+		t.Fatalf("模拟付款失败: %v", err)
+	}
+	return ot.svc.HandleNotify(context.Background(), req)
+}
+
+// subscriptions 下单用户的会员卡
+func (ot *orderTest) subscriptions(t *testing.T) []*membership.Subscription {
+	t.Helper()
+
+	var subs []*membership.Subscription
+	if err := ot.db.Where("user_id = ?", ot.userID).Find(&subs).Error; err != nil {
+		t.Fatalf("查询会员卡失败: %v", err)
+	}
+	return subs
+}
+
+// reload 重新读取订单
+func (ot *orderTest) reload(t *testing.T, o *order.Order) *order.Order {
+	t.Helper()
+
+	o, err := ot.repo.GetOrder(context.Background(), o.ID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	return o
+}
+
+func TestPayAndNotify(t *testing.T) {
+	ot := newOrderTest(t)
+	o := ot.createOrder(t)
+
+	if err := ot.notify(t, o); err != nil {
+		t.Fatalf("处理支付通知失败: %v", err)
+	}
+
+	o = ot.reload(t, o)
+	if o.Status != order.StatusPaid || o.TransactionID == "" || o.PaidAt == nil || o.SubscriptionID == nil {
+		t.Fatalf("支付后订单状态不正确: status=%s transaction=%q", o.Status, o.TransactionID)
+	}
+	subs := ot.subscriptions(t)
+	if len(subs) != 1 || subs[0].ID != *o.SubscriptionID {
+		t.Fatalf("支付后开卡%d张，期望1张且与订单关联", len(subs))
+	}
+	if subs[0].Status != membership.StatusActive || subs[0].RemainingCredits == nil || *subs[0].RemainingCredits != *ot.plan.Credits {
+		t.Fatalf("开出的会员卡不正确: status=%s", subs[0].Status)
+	}
+}
+
+func TestDuplicateNotifyIssuesOneSubscription(t *testing.T) {
+	ot := newOrderTest(t)
+	o := ot.createOrder(t)
+
+	if err := ot.notify(t, o); err != nil {
+		t.Fatalf("处理支付通知失败: %v", err)
+	}
+	first := ot.reload(t, o)
+
+	// 网关重复通知同一笔交易，包括多个通知同时到达
+	if err := ot.notify(t, o); err != nil {
+		t.Fatalf("处理重复的支付通知失败: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		req, err := ot.gateway.Pay(o.OrderNo)
+		if err != nil {
+			t.Fatalf("模拟付款失败: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ot.svc.HandleNotify(context.Background(), req)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("处理并发的重复通知失败: %v", err)
+		}
+	}
+
+	o = ot.reload(t, o)
+	if o.Status != order.StatusPaid || *o.SubscriptionID != *first.SubscriptionID || o.TransactionID != first.TransactionID {
+		t.Fatalf("重复通知改变了已支付的订单")
+	}
+	if subs := ot.subscriptions(t); len(subs) != 1 {
+		t.Fatalf("重复通知后开卡%d张，期望1张", len(subs))
+	}
+}
+
+func TestNotifyTamperedSignature(t *testing.T) {
+	ot := newOrderTest(t)
+	o := ot.createOrder(t)
+
+	req, err := ot.gateway.Pay(o.OrderNo)
+	if err != nil {
+		t.Fatalf("模拟付款失败: %v", err)
+	}
+	// 改动签名的第一个字符
+	signature := []byte(req.Header.Get("Fakepay-Signature"))
+	if signature[0] == '0' {
+		signature[0] = '1'
+	} else {
+		signature[0] = '0'
+	}
+	req.Header.Set("Fakepay-Signature", string(signature))
+
+	if err := ot.svc.HandleNotify(context.Background(), req); !errors.Is(err, payment.ErrInvalidSignature) {
+		t.Fatalf("篡改签名的通知返回%v，期望ErrInvalidSignature", err)
+	}
+	if o = ot.reload(t, o); o.Status != order.StatusPending {
+		t.Fatalf("签名无效的通知改变了订单状态: %s", o.Status)
+	}
+	if subs := ot.subscriptions(t); len(subs) != 0 {
+		t.Fatalf("签名无效的通知开卡%d张", len(subs))
+	}
+}
+
+func TestNotifyAfterClose(t *testing.T) {
+	ot := newOrderTest(t)
+	o := ot.createOrder(t)
+
+	// 用户在订单关闭前一刻付款，支付通知在关闭后到达
+	if _, err := ot.repo.CloseOrder(context.Background(), o.ID, time.Now()); err != nil {
+		t.Fatalf("关闭订单失败: %v", err)
+	}
+	if err := ot.notify(t, o); err != nil {
+		t.Fatalf("处理关闭后的支付通知失败: %v", err)
+	}
+
+	o = ot.reload(t, o)
+	if o.Status != order.StatusPaid || o.SubscriptionID == nil {
+		t.Fatalf("关闭后付款的订单状态为%s，期望已支付并开卡", o.Status)
+	}
+	if subs := ot.subscriptions(t); len(subs) != 1 {
+		t.Fatalf("关闭后付款开卡%d张，期望1张", len(subs))
+	}
+}
+
+func TestRefundOrderCancelsSubscription(t *testing.T) {
+	ot := newOrderTest(t)
+	o := ot.createOrder(t)
+	if err := ot.notify(t, o); err != nil {
+		t.Fatalf("处理支付通知失败: %v", err)
+	}
+
+	if _, err := ot.svc.RefundOrder(ot.member, o.ID, "会员申请"); !errors.Is(err, user.ErrForbidden) {
+		t.Fatalf("会员退款返回%v，期望ErrForbidden", err)
+	}
+
+	refunded, err := ot.svc.RefundOrder(ot.admin, o.ID, "会员申请")
+	if err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	if refunded.Status != order.StatusRefunded || refunded.RefundNo != order.RefundNoFor(o.OrderNo) {
+		t.Fatalf("退款后订单状态为%s，期望已退款", refunded.Status)
+	}
+	subs := ot.subscriptions(t)
+	if len(subs) != 1 || subs[0].Status != membership.StatusCancelled {
+		t.Fatalf("退款后会员卡未作废")
+	}
+	txn, err := ot.gateway.QueryOrder(context.Background(), o.OrderNo)
+	if err != nil || txn.State != payment.TradeRefund {
+		t.Fatalf("网关交易未转入退款: %v", err)
+	}
+
+	// 重复退款返回已退款的订单
+	again, err := ot.svc.RefundOrder(ot.admin, o.ID, "会员申请")
+	if err != nil || again.Status != order.StatusRefunded {
+		t.Fatalf("重复退款返回%v", err)
+	}
+}
@@ -6,17 +6,49 @@ App({
     logs.unshift(Date.now())
     wx.setStorageSync('logs', logs)
 
-    // 登录
-    wx.login({
-      success: res => {
-        // 发送 res.code 到后台换取 openId, sessionKey, unionId
-        console.log('登录成功', res.code)
-      }
+    // 恢复本地保存的登录状态
+    this.globalData.token = wx.getStorageSync('token') || ''
+    this.globalData.userInfo = wx.getStorageSync('userInfo') || null
+
+    this.login()
+  },
+
+  // 登录：发送 wx.login 的 code 到后台换取会话令牌
+  login() {
+    return new Promise((resolve, reject) => {
+      wx.login({
+        success: res => {
+          wx.request({
+            url: this.globalData.apiBaseUrl + '/auth/wechat',
+            method: 'POST',
+            data: { code: res.code },
+            header: {
+              'content-type': 'application/json'
+            },
+            success: resp => {
+              if (resp.statusCode !== 200) {
+                reject(new Error(`登录失败: ${resp.statusCode}`))
+                return
+              }
+              this.globalData.token = resp.data.token
+              this.globalData.userInfo = resp.data.user
+              wx.setStorageSync('token', resp.data.token)
+              wx.setStorageSync('userInfo', resp.data.user)
+              resolve(resp.data.user)
+            },
+            fail: reject
+          })
+        },
+        fail: reject
+      })
     })
   },
   globalData: {
     userInfo: null,
-    apiBaseUrl: 'http://21.6.230.48:8080/api/v1' // 根据实际情况修改
+    token: '',
+    apiBaseUrl: 'http://21.6.230.48:8080/api/v1', // 根据实际情况修改
+    // 订阅消息模板ID（与服务端 WECHAT_SUBSCRIBE_TEMPLATES 一致），预订时请求用户订阅，最多3个
+    subscribeTemplateIds: []
   }
 })
 
@@ -229,7 +229,7 @@ Page({
     }
     
     try {
-      await api.bookClass(classId, userInfo.id || 'user1', userInfo.nickName || '用户')
+      await api.bookClass(classId)
       wx.showToast({
         title: '预订成功',
         icon: 'success'
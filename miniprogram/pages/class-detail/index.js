@@ -64,9 +64,7 @@ Page({
 
   async loadUserBooking() {
     try {
-      const app = getApp()
-      const userID = app.globalData.userInfo?.openId || 'test_user'
-      const response = await api.getUserBookings(userID)
+      const response = await api.getUserBookings()
       
       // 查找当前课程的预订
       const booking = (response.items || []).find(b => b.class_id === this.data.classId)
@@ -83,7 +81,8 @@ Page({
   async loadUserReview() {
     try {
       const app = getApp()
-      const userID = app.globalData.userInfo?.openId || 'test_user'
+      const userID = app.globalData.userInfo?.id
+      if (!userID) return
       const review = await api.getUserReview(this.data.classId, userID)
       if (review) {
         this.setData({ userReview: review })
@@ -114,12 +113,9 @@ Page({
 
   async bookClass() {
     try {
-      const app = getApp()
-      const userID = app.globalData.userInfo?.openId || 'test_user'
-      const userName = app.globalData.userInfo?.nickName || '用户'
-      
+      await api.requestSubscribe()
       wx.showLoading({ title: '预订中...' })
-      await api.bookClass(this.data.classId, userID, userName)
+      await api.bookClass(this.data.classId)
       wx.hideLoading()
       
       wx.showToast({
@@ -147,13 +143,20 @@ Page({
         if (res.confirm) {
           try {
             wx.showLoading({ title: '取消中...' })
-            await api.cancelBooking(this.data.userBooking.id)
+            const result = await api.cancelBooking(this.data.userBooking.id)
             wx.hideLoading()
             
-            wx.showToast({
-              title: '取消成功',
-              icon: 'success'
-            })
+            if (result.booking && result.booking.late_cancel) {
+              wx.showToast({
+                title: '已取消（迟取消）',
+                icon: 'none'
+              })
+            } else {
+              wx.showToast({
+                title: '取消成功',
+                icon: 'success'
+              })
+            }
             
             this.setData({ userBooking: null })
             this.loadClassDetail()
@@ -161,7 +164,7 @@ Page({
             wx.hideLoading()
             console.error('取消预订失败', error)
             wx.showToast({
-              title: '取消失败',
+              title: error.code ? error.message : '取消失败',
               icon: 'none'
             })
           }
@@ -249,15 +252,9 @@ Page({
     }
 
     try {
-      const app = getApp()
-      const userID = app.globalData.userInfo?.openId || 'test_user'
-      const userName = app.globalData.userInfo?.nickName || '用户'
-      
       wx.showLoading({ title: '提交中...' })
       await api.createReview(
         this.data.classId,
-        userID,
-        userName,
         this.data.reviewForm.rating,
         this.data.reviewForm.content,
         this.data.reviewForm.images
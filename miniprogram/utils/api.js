@@ -11,13 +11,21 @@ function request(url, method, data) {
       method: method,
       data: data,
       header: {
-        'content-type': 'application/json'
+        'content-type': 'application/json',
+        'Authorization': app.globalData.token ? `Bearer ${app.globalData.token}` : ''
       },
       success: (res) => {
         if (res.statusCode === 200 || res.statusCode === 201) {
           resolve(res.data)
+        } else if (res.statusCode === 401) {
+          // 令牌失效，重新登录
+          app.login().catch(() => {})
+          reject(new Error('请先登录'))
         } else {
-          reject(new Error(`请求失败: ${res.statusCode}`))
+          // 业务错误带有 code（如 cancel_deadline_passed），页面可据此提示
+          const error = new Error((res.data && res.data.error) || `请求失败: ${res.statusCode}`)
+          error.code = res.data && res.data.code
+          reject(error)
         }
       },
       fail: (err) => {
@@ -53,12 +61,13 @@ function getKnowledgeItems(baseId, limit = 20, offset = 0) {
 }
 
 /**
- * 获取课程列表
+ * 获取课程列表，locationId为空时查询全部场馆
  */
-function getClasses(startDate = '', endDate = '') {
+function getClasses(startDate = '', endDate = '', locationId = '') {
   let url = '/classes?'
   if (startDate) url += `start_date=${startDate}&`
-  if (endDate) url += `end_date=${endDate}`
+  if (endDate) url += `end_date=${endDate}&`
+  if (locationId) url += `location_id=${locationId}`
   return request(url, 'GET')
 }
 
@@ -72,11 +81,22 @@ function getClass(classId) {
 /**
  * 预订课程
  */
-function bookClass(classId, userId, userName) {
-  return request(`/classes/${classId}/book`, 'POST', {
-    user_id: userId,
-    user_name: userName
-  })
+function bookClass(classId) {
+  return request(`/classes/${classId}/book`, 'POST')
+}
+
+/**
+ * 加入候补名单（课程已满时）
+ */
+function joinWaitlist(classId) {
+  return request(`/classes/${classId}/waitlist`, 'POST')
+}
+
+/**
+ * 自助签到（开课前后的签到时间内）
+ */
+function checkIn(bookingId) {
+  return request(`/classes/bookings/${bookingId}/check-in`, 'POST')
 }
 
 /**
@@ -89,17 +109,15 @@ function cancelBooking(bookingId) {
 /**
  * 获取用户预订列表
  */
-function getUserBookings(userId, limit = 50, offset = 0) {
-  return request(`/classes/bookings?user_id=${userId}&limit=${limit}&offset=${offset}`, 'GET')
+function getUserBookings(limit = 50, offset = 0) {
+  return request(`/classes/bookings?limit=${limit}&offset=${offset}`, 'GET')
 }
 
 /**
  * 创建评价
  */
-function createReview(classId, userId, userName, rating, content, images = []) {
+function createReview(classId, rating, content, images = []) {
   return request(`/classes/${classId}/reviews`, 'POST', {
-    user_id: userId,
-    user_name: userName,
     rating: rating,
     content: content,
     images: images
@@ -127,6 +145,116 @@ async function getUserReview(classId, userId) {
   }
 }
 
+/**
+ * 获取在售的会员卡方案
+ */
+function getMembershipPlans() {
+  return request('/membership-plans', 'GET')
+}
+
+/**
+ * 获取我的会员卡（含剩余课时）
+ */
+function getMySubscriptions() {
+  return request('/subscriptions', 'GET')
+}
+
+/**
+ * 购买会员卡方案：下单后调起微信支付，支付完成后查单确认，返回最新订单。
+ * 服务端使用本地假网关时（signType为FAKE）改为调用模拟付款接口。
+ */
+function buyPlan(planId) {
+  return request('/orders', 'POST', { plan_id: planId }).then((res) => {
+    const order = res.order
+    const params = res.pay_params
+    if (params.signType === 'FAKE') {
+      return request(`/orders/${order.id}/simulate-pay`, 'POST')
+    }
+    return new Promise((resolve, reject) => {
+      wx.requestPayment({
+        timeStamp: params.timeStamp,
+        nonceStr: params.nonceStr,
+        package: params.package,
+        signType: params.signType,
+        paySign: params.paySign,
+        success: resolve,
+        fail: reject
+      })
+    }).then(() => request(`/orders/${order.id}/sync`, 'POST'))
+  })
+}
+
+/**
+ * 请求用户订阅上课提醒等消息，需在点击事件中调用；未配置模板或用户拒绝时不影响后续操作
+ */
+function requestSubscribe() {
+  const tmplIds = app.globalData.subscribeTemplateIds || []
+  if (tmplIds.length === 0) {
+    return Promise.resolve()
+  }
+  return new Promise((resolve) => {
+    wx.requestSubscribeMessage({
+      tmplIds: tmplIds.slice(0, 3),
+      complete: resolve
+    })
+  })
+}
+
+/**
+ * 获取我的通知偏好
+ */
+function getNotificationPreference() {
+  return request('/notification-preferences', 'GET')
+}
+
+/**
+ * 修改通知偏好，只修改传入的字段（reminders、booking_updates、class_changes、phone、email）
+ */
+function updateNotificationPreference(data) {
+  return request('/notification-preferences', 'PUT', data)
+}
+
+/**
+ * 获取我的预订日历私有订阅地址（url、webcal_url），reset为true时重新生成，原地址失效
+ */
+function getCalendarFeed(reset) {
+  if (reset) {
+    return request('/users/me/calendar/reset', 'POST')
+  }
+  return request('/users/me/calendar', 'GET')
+}
+
+/**
+ * 获取教练列表
+ */
+function getInstructors() {
+  return request('/instructors', 'GET')
+}
+
+/**
+ * 获取教练主页（简介、专长、证书、照片、平均评分）
+ */
+function getInstructor(instructorId) {
+  return request(`/instructors/${instructorId}`, 'GET')
+}
+
+/**
+ * 获取教练课程表
+ */
+function getInstructorSchedule(instructorId, startDate = '', endDate = '') {
+  let url = `/instructors/${instructorId}/schedule?`
+  if (startDate) url += `start_date=${startDate}&`
+  if (endDate) url += `end_date=${endDate}`
+  return request(url, 'GET')
+}
+
+/**
+ * 获取场馆及其教室列表
+ */
+function getLocations() {
+  return request('/locations', 'GET')
+}
+
 module.exports = {
   request,
   chat,
@@ -135,9 +263,22 @@ module.exports = {
   getClasses,
   getClass,
   bookClass,
+  joinWaitlist,
+  checkIn,
   cancelBooking,
   getUserBookings,
   createReview,
   getClassReviews,
-  getUserReview
+  getUserReview,
+  getMembershipPlans,
+  getMySubscriptions,
+  buyPlan,
+  requestSubscribe,
+  getNotificationPreference,
+  updateNotificationPreference,
+  getCalendarFeed,
+  getInstructors,
+  getInstructor,
+  getInstructorSchedule,
+  getLocations
 }
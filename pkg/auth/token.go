@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken 令牌格式错误或签名不匹配
+	ErrInvalidToken = errors.New("无效的令牌")
+	// ErrTokenExpired 令牌已过期
+	ErrTokenExpired = errors.New("令牌已过期")
+)
+
+// Claims 会话令牌中携带的声明
+type Claims struct {
+	Subject   string `json:"sub"` // 用户ID
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenSigner 使用HMAC-SHA256签发和校验会话令牌。
+// 令牌格式为 base64url(claims JSON) + "." + base64url(签名)。
+type TokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewTokenSigner 创建令牌签发器，ttl为令牌有效期
+func NewTokenSigner(secret []byte, ttl time.Duration) *TokenSigner {
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &TokenSigner{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Issue 为用户签发令牌，返回令牌及过期时间
+func (s *TokenSigner) Issue(subject string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+	claims := Claims{
+		Subject:   subject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("序列化令牌失败: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), expiresAt, nil
+}
+
+// Verify 校验令牌签名与有效期，返回其中的声明
+func (s *TokenSigner) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || encoded == "" || signature == "" {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// sign 计算签名
+func (s *TokenSigner) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,241 @@
+package chunker
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Chunk 文本分块
+type Chunk struct {
+	Index   int    // 分块序号，从0开始
+	Heading string // 所属Markdown标题路径，如 "体式 > 站立体式"
+	Content string // 分块文本（已去除首尾空白）
+	Start   int    // 在原文中的起始字符偏移（按rune计）
+	End     int    // 在原文中的结束字符偏移（不含）
+}
+
+// Splitter 文本分块器
+//
+// 长度按字符（rune）计算，中文场景下一个汉字大致对应一个token。
+// 分块优先在Markdown标题、段落、中英文句末标点处切分，单个句子超长时才按字符硬切；
+// 相邻分块之间保留Overlap个字符左右的重叠，以免语义在边界处被截断。
+type Splitter struct {
+	ChunkSize int
+	Overlap   int
+}
+
+// NewSplitter 创建文本分块器
+func NewSplitter(chunkSize, overlap int) *Splitter {
+	if chunkSize <= 0 {
+		chunkSize = 500
+	}
+	if overlap < 0 || overlap >= chunkSize/2 {
+		overlap = chunkSize / 5
+	}
+	return &Splitter{ChunkSize: chunkSize, Overlap: overlap}
+}
+
+// section Markdown标题划分出的文本段
+type section struct {
+	heading    string
+	start, end int
+	bodyStart  int // 首个正文行的起点，标题行不会单独成块
+}
+
+// Split 将文本切分为若干分块
+func (s *Splitter) Split(text string) []Chunk {
+	runes := []rune(text)
+	var chunks []Chunk
+
+	for _, sec := range splitSections(runes) {
+		boundaries := sentenceBoundaries(runes, sec.bodyStart, sec.end)
+
+		start := sec.start
+		for start < sec.end {
+			end := sec.end
+			if start+s.ChunkSize < sec.end {
+				// 在长度上限内寻找最后一个句子边界，找不到则在空白处或按字符硬切
+				end = start + s.ChunkSize
+				if b, ok := lastBoundary(boundaries, start, end); ok {
+					end = b
+				} else {
+					end = lastSpace(runes, start+s.ChunkSize/2, end)
+				}
+			}
+
+			if content := strings.TrimSpace(string(runes[start:end])); content != "" {
+				chunks = append(chunks, Chunk{
+					Index:   len(chunks),
+					Heading: sec.heading,
+					Content: content,
+					Start:   start,
+					End:     end,
+				})
+			}
+
+			if end >= sec.end {
+				break
+			}
+			start = s.nextStart(runes, boundaries, start, end)
+		}
+	}
+
+	return chunks
+}
+
+// nextStart 计算下一个分块的起点，尽量从重叠窗口内的句子边界（其次是空白）开始
+func (s *Splitter) nextStart(runes []rune, boundaries []int, start, end int) int {
+	if s.Overlap <= 0 {
+		return end
+	}
+	windowStart := end - s.Overlap
+	for _, b := range boundaries {
+		if b > start && b >= windowStart && b < end {
+			return b
+		}
+	}
+	if windowStart <= start {
+		return end
+	}
+	for i := windowStart; i < end; i++ {
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return windowStart
+}
+
+// lastBoundary 返回 (start, limit] 内最后一个边界
+func lastBoundary(boundaries []int, start, limit int) (int, bool) {
+	found, ok := 0, false
+	for _, b := range boundaries {
+		if b <= start {
+			continue
+		}
+		if b > limit {
+			break
+		}
+		found, ok = b, true
+	}
+	return found, ok
+}
+
+// lastSpace 返回 (from, limit] 内最后一个空白字符之后的位置，找不到则返回limit
+func lastSpace(runes []rune, from, limit int) int {
+	for i := limit - 1; i > from; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return limit
+}
+
+// splitSections 按Markdown标题（# ~ ######）划分文本段，代码块内的#不视为标题。
+// 只有标题、没有正文的段落会被跳过，其标题仍会出现在子段落的标题路径中。
+func splitSections(runes []rune) []section {
+	var (
+		sections []section
+		stack    []string // 各级标题，stack[i]为第i+1级
+		current  = section{start: 0}
+		hasBody  bool
+		inFence  bool
+	)
+
+	flush := func(end int) {
+		current.end = end
+		if hasBody && current.end > current.start {
+			sections = append(sections, current)
+		}
+	}
+
+	for lineStart := 0; lineStart < len(runes); {
+		lineEnd := lineStart
+		for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+			lineEnd++
+		}
+		line := strings.TrimSpace(string(runes[lineStart:lineEnd]))
+
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+
+		if level, title := parseHeading(line); !inFence && level > 0 {
+			flush(lineStart)
+			if level-1 < len(stack) {
+				stack = stack[:level-1]
+			}
+			for len(stack) < level-1 {
+				stack = append(stack, "")
+			}
+			stack = append(stack, title)
+			current = section{heading: joinHeadings(stack), start: lineStart}
+			hasBody = false
+		} else if line != "" && !hasBody {
+			hasBody = true
+			current.bodyStart = lineStart
+		}
+
+		lineStart = lineEnd + 1
+	}
+	flush(len(runes))
+
+	return sections
+}
+
+// parseHeading 解析Markdown标题行，返回级别与标题文本
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || (line[level] != ' ' && line[level] != '\t') {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(line[level:])
+}
+
+// joinHeadings 拼接标题路径，忽略跳级留下的空标题
+func joinHeadings(stack []string) string {
+	parts := make([]string, 0, len(stack))
+	for _, h := range stack {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// sentenceEnders 句末标点（中英文）
+var sentenceEnders = map[rune]bool{
+	'。': true, '！': true, '？': true, '；': true, '…': true,
+	'!': true, '?': true, ';': true,
+}
+
+// closingPunct 句末标点后可能紧跟的右引号、右括号
+var closingPunct = map[rune]bool{
+	'”': true, '’': true, '」': true, '』': true, '）': true, '】': true, '》': true,
+	'"': true, '\'': true, ')': true,
+}
+
+// sentenceBoundaries 返回 [start, end) 内可切分的位置（切分点位于边界字符之后）
+func sentenceBoundaries(runes []rune, start, end int) []int {
+	var boundaries []int
+	for i := start; i < end; i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			boundaries = append(boundaries, i+1)
+		case sentenceEnders[r]:
+			j := i + 1
+			for j < end && closingPunct[runes[j]] {
+				j++
+			}
+			boundaries = append(boundaries, j)
+			i = j - 1
+		case r == '.' && i+1 < end && unicode.IsSpace(runes[i+1]):
+			// 英文句号后须跟空白，避免在小数、缩写处切分
+			boundaries = append(boundaries, i+1)
+		}
+	}
+	return boundaries
+}
@@ -0,0 +1,167 @@
+package chunker
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNewSplitter(t *testing.T) {
+	tests := []struct {
+		name                  string
+		chunkSize, overlap    int
+		wantSize, wantOverlap int
+	}{
+		{"正常参数", 300, 50, 300, 50},
+		{"块大小非正时使用默认值", 0, 50, 500, 50},
+		{"重叠为负时取块大小的五分之一", 300, -1, 300, 60},
+		{"重叠不小于块大小一半时取五分之一", 300, 150, 300, 60},
+		{"不重叠", 300, 0, 300, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSplitter(tt.chunkSize, tt.overlap)
+			if s.ChunkSize != tt.wantSize || s.Overlap != tt.wantOverlap {
+				t.Errorf("NewSplitter(%d, %d) = {%d, %d}，期望{%d, %d}",
+					tt.chunkSize, tt.overlap, s.ChunkSize, s.Overlap, tt.wantSize, tt.wantOverlap)
+			}
+		})
+	}
+}
+
+func TestParseHeading(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantLevel int
+		wantTitle string
+	}{
+		{"# 体式", 1, "体式"},
+		{"### 站立体式  ", 3, "站立体式"},
+		{"######\t呼吸", 6, "呼吸"},
+		{"####### 七级", 0, ""},
+		{"#没有空格", 0, ""},
+		{"#", 0, ""},
+		{"正文", 0, ""},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		level, title := parseHeading(tt.line)
+		if level != tt.wantLevel || title != tt.wantTitle {
+			t.Errorf("parseHeading(%q) = (%d, %q)，期望(%d, %q)", tt.line, level, title, tt.wantLevel, tt.wantTitle)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	long := strings.Repeat("山式站立，双脚并拢。", 30) // 300个字符
+
+	tests := []struct {
+		name         string
+		chunkSize    int
+		overlap      int
+		text         string
+		wantChunks   int // -1表示只校验分块的通用性质
+		wantHeadings []string
+	}{
+		{"空文本", 100, 10, "", 0, nil},
+		{"只有空白", 100, 10, " \n\n ", 0, nil},
+		{"短文本一个分块", 100, 10, "山式是所有站立体式的基础。", 1, []string{""}},
+		{
+			"按标题划分并拼接标题路径", 100, 10,
+			"# 体式\n## 站立体式\n山式。\n## 坐姿体式\n简易坐。\n# 呼吸\n腹式呼吸。",
+			3, []string{"体式 > 站立体式", "体式 > 坐姿体式", "呼吸"},
+		},
+		{"只有标题没有正文的段落跳过", 100, 10, "# 体式\n# 呼吸\n", 0, nil},
+		{"代码块内的#不是标题", 100, 10, "# 示例\n```\n# 注释\n```\n说明。", 1, []string{"示例"}},
+		{"跳级标题忽略空层级", 100, 10, "# 体式\n### 扭转\n坐姿扭转。", 1, []string{"体式 > 扭转"}},
+		{"长文本按长度切分", 100, 20, long, -1, nil},
+		{"长文本不重叠", 100, 0, long, 3, nil},
+		{"没有标点时按字符硬切", 50, 0, strings.Repeat("瑜", 120), 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Splitter{ChunkSize: tt.chunkSize, Overlap: tt.overlap}
+			chunks := s.Split(tt.text)
+
+			if tt.wantChunks >= 0 && len(chunks) != tt.wantChunks {
+				t.Fatalf("得到%d个分块，期望%d个", len(chunks), tt.wantChunks)
+			}
+			if tt.wantChunks < 0 && len(chunks) < 2 {
+				t.Fatalf("得到%d个分块，期望多个", len(chunks))
+			}
+
+			runes := []rune(tt.text)
+			prevStart := -1
+			for i, c := range chunks {
+				if c.Index != i {
+					t.Errorf("第%d个分块的Index为%d", i, c.Index)
+				}
+				if n := utf8.RuneCountInString(c.Content); n > tt.chunkSize {
+					t.Errorf("第%d个分块%d个字符，超过上限%d", i, n, tt.chunkSize)
+				}
+				if c.Start < 0 || c.End > len(runes) || c.Start >= c.End {
+					t.Fatalf("第%d个分块的偏移[%d, %d)无效", i, c.Start, c.End)
+				}
+				if got := strings.TrimSpace(string(runes[c.Start:c.End])); got != c.Content {
+					t.Errorf("第%d个分块内容与偏移不一致: %q != %q", i, c.Content, got)
+				}
+				if c.Start <= prevStart {
+					t.Errorf("第%d个分块的起点%d没有前进", i, c.Start)
+				}
+				prevStart = c.Start
+				if tt.wantHeadings != nil && c.Heading != tt.wantHeadings[i] {
+					t.Errorf("第%d个分块的标题为%q，期望%q", i, c.Heading, tt.wantHeadings[i])
+				}
+			}
+
+			// 分块覆盖全部正文
+			if len(chunks) > 0 && strings.TrimSpace(tt.text) == tt.text {
+				if last := chunks[len(chunks)-1]; last.End != len(runes) {
+					t.Errorf("最后一个分块止于%d，期望%d", last.End, len(runes))
+				}
+			}
+		})
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	text := strings.Repeat("山式站立，双脚并拢。", 30)
+	chunks := (&Splitter{ChunkSize: 100, Overlap: 20}).Split(text)
+	for i := 1; i < len(chunks); i++ {
+		prev, cur := chunks[i-1], chunks[i]
+		if cur.Start >= prev.End {
+			t.Errorf("第%d个分块起点%d不早于上一块终点%d，没有重叠", i, cur.Start, prev.End)
+		}
+		if prev.End-cur.Start > 20 {
+			t.Errorf("第%d个分块与上一块重叠%d个字符，超过20", i, prev.End-cur.Start)
+		}
+	}
+}
+
+func TestSentenceBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{"中文句号", "一。二。", []int{2, 4}},
+		{"句末标点后的右引号归入前一句", "他说：“好。”然后", []int{7}},
+		{"英文句号后须跟空白", "Pi is 3.14. Ok", []int{11}},
+		{"换行", "一\n二", []int{2}},
+		{"连续问叹号", "真的吗？！好", []int{4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runes := []rune(tt.text)
+			got := sentenceBoundaries(runes, 0, len(runes))
+			if len(got) != len(tt.want) {
+				t.Fatalf("sentenceBoundaries(%q) = %v，期望%v", tt.text, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("sentenceBoundaries(%q) = %v，期望%v", tt.text, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package ical 生成iCalendar（RFC 5545）日历订阅源。
+// 事件时间一律以UTC输出（如 20261017T010000Z），日历客户端按设备时区显示，不依赖VTIMEZONE定义。
+package ical
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType 日历订阅源的MIME类型
+const ContentType = "text/calendar; charset=utf-8"
+
+// 事件状态
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// utcLayout UTC日期时间格式
+const utcLayout = "20060102T150405Z"
+
+// maxLineOctets 内容行的最大字节数（不含CRLF），超出时折行
+const maxLineOctets = 75
+
+// Calendar 日历
+type Calendar struct {
+	ProdID   string        // 生成方标识，如 -//Yoga Studio//Booking//ZH
+	Name     string        // 日历名称（X-WR-CALNAME）
+	TimeZone string        // 日历的默认显示时区（X-WR-TIMEZONE），IANA名称
+	Refresh  time.Duration // 建议的刷新间隔，零值不输出
+	Events   []Event
+}
+
+// Event 日历事件
+type Event struct {
+	UID          string // 全局唯一且稳定，客户端按UID更新或删除事件
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	Status       string // StatusConfirmed、StatusTentative、StatusCancelled
+	Sequence     int    // 修订号，事件变更时递增
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Encode 编码为iCalendar文本
+func (c *Calendar) Encode() []byte {
+	var buf bytes.Buffer
+	_, _ = c.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo 将日历写入w
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: w}
+	now := time.Now()
+
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + c.ProdID)
+	e.line("CALSCALE:GREGORIAN")
+	e.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	if c.TimeZone != "" {
+		e.line("X-WR-TIMEZONE:" + c.TimeZone)
+	}
+	if c.Refresh > 0 {
+		e.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration(c.Refresh))
+		e.line("X-PUBLISHED-TTL:" + duration(c.Refresh))
+	}
+
+	for _, ev := range c.Events {
+		e.line("BEGIN:VEVENT")
+		e.line("UID:" + escape(ev.UID))
+		e.line("DTSTAMP:" + utc(now))
+		e.line("DTSTART:" + utc(ev.Start))
+		e.line("DTEND:" + utc(ev.End))
+		e.line("SUMMARY:" + escape(ev.Summary))
+		if ev.Description != "" {
+			e.line("DESCRIPTION:" + escape(ev.Description))
+		}
+		if ev.Location != "" {
+			e.line("LOCATION:" + escape(ev.Location))
+		}
+		if ev.Status != "" {
+			e.line("STATUS:" + ev.Status)
+		}
+		e.line("SEQUENCE:" + strconv.Itoa(ev.Sequence))
+		if !ev.Created.IsZero() {
+			e.line("CREATED:" + utc(ev.Created))
+		}
+		if !ev.LastModified.IsZero() {
+			e.line("LAST-MODIFIED:" + utc(ev.LastModified))
+		}
+		e.line("END:VEVENT")
+	}
+
+	e.line("END:VCALENDAR")
+	return e.n, e.err
+}
+
+// encoder 按行写入，超长的行按RFC 5545折行（续行以空格开头），不拆分UTF-8字符
+type encoder struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (e *encoder) line(s string) {
+	for first := true; ; first = false {
+		limit := maxLineOctets
+		if !first {
+			limit-- // 续行开头的空格
+		}
+		if len(s) <= limit {
+			e.write(s)
+			break
+		}
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		e.write(s[:cut])
+		e.write("\r\n ")
+		s = s[cut:]
+	}
+	e.write("\r\n")
+}
+
+func (e *encoder) write(s string) {
+	if e.err != nil {
+		return
+	}
+	n, err := io.WriteString(e.w, s)
+	e.n += int64(n)
+	e.err = err
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape 转义TEXT类型的值
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
+
+// duration 以RFC 5545的DURATION格式输出（精确到分钟），如 PT1H、PT30M
+func duration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	if minutes <= 0 {
+		minutes = 1
+	}
+	s := "PT"
+	if h := minutes / 60; h > 0 {
+		s += strconv.Itoa(h) + "H"
+	}
+	if m := minutes % 60; m > 0 {
+		s += strconv.Itoa(m) + "M"
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"流瑜伽", "流瑜伽"},
+		{`a\b`, `a\\b`},
+		{"静安店;3号教室", `静安店\;3号教室`},
+		{"瑜伽垫,毛巾", `瑜伽垫\,毛巾`},
+		{"第一行\n第二行", `第一行\n第二行`},
+		{"第一行\r\n第二行", `第一行\n第二行`},
+		{"第一行\r第二行", `第一行\n第二行`},
+		{`\;,`, `\\\;\,`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q，期望%q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantLines int
+	}{
+		{"不超长不折行", "SUMMARY:" + strings.Repeat("a", 67), 1},
+		{"超出一个字节折行", "SUMMARY:" + strings.Repeat("a", 68), 2},
+		{"续行计入开头的空格", "SUMMARY:" + strings.Repeat("a", 67+74), 2},
+		{"续行超长再次折行", "SUMMARY:" + strings.Repeat("a", 67+75), 3},
+		{"不拆分多字节字符", "SUMMARY:" + strings.Repeat("瑜伽", 40), 4},
+		{"空行", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := &encoder{w: &buf}
+			e.line(tt.line)
+			if e.err != nil {
+				t.Fatalf("写入失败: %v", e.err)
+			}
+			if e.n != int64(buf.Len()) {
+				t.Errorf("记录写入%d字节，实际%d字节", e.n, buf.Len())
+			}
+
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("输出%q没有以CRLF结尾", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(lines) != tt.wantLines {
+				t.Fatalf("折为%d行，期望%d行: %q", len(lines), tt.wantLines, out)
+			}
+
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > maxLineOctets {
+					t.Errorf("第%d行%d字节，超过%d", i, len(l), maxLineOctets)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Fatalf("续行%q没有以空格开头", l)
+					}
+					l = l[1:]
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("第%d行拆分了UTF-8字符: %q", i, l)
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("展开后为%q，期望%q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{time.Hour, "PT1H"},
+		{30 * time.Minute, "PT30M"},
+		{90 * time.Minute, "PT1H30M"},
+		{24 * time.Hour, "PT24H"},
+		{10 * time.Second, "PT1M"},
+	}
+	for _, tt := range tests {
+		if got := duration(tt.in); got != tt.want {
+			t.Errorf("duration(%v) = %q，期望%q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarEncode(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	start := time.Date(2026, 10, 17, 9, 0, 0, 0, shanghai)
+	cal := &Calendar{
+		ProdID:   "-//Yoga Studio//Booking//ZH",
+		Name:     "我的课程",
+		TimeZone: "Asia/Shanghai",
+		Refresh:  time.Hour,
+		Events: []Event{
+			{
+				UID:         "class-1@yoga",
+				Summary:     "流瑜伽, 初级",
+				Description: "带好瑜伽垫\n提前10分钟到场",
+				Location:    "静安店;3号教室",
+				Start:       start,
+				End:         start.Add(time.Hour),
+				Status:      StatusConfirmed,
+				Sequence:    2,
+			},
+			{
+				UID:     "class-2@yoga",
+				Summary: "阴瑜伽",
+				Start:   start.Add(24 * time.Hour),
+				End:     start.Add(25 * time.Hour),
+				Status:  StatusCancelled,
+			},
+		},
+	}
+	out := string(cal.Encode())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Yoga Studio//Booking//ZH\r\n",
+		"X-WR-CALNAME:我的课程\r\n",
+		"X-WR-TIMEZONE:Asia/Shanghai\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n",
+		"DTSTART:20261017T010000Z\r\nDTEND:20261017T020000Z\r\n",
+		`SUMMARY:流瑜伽\, 初级` + "\r\n",
+		`DESCRIPTION:带好瑜伽垫\n提前10分钟到场` + "\r\n",
+		`LOCATION:静安店\;3号教室` + "\r\n",
+		"SEQUENCE:2\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出缺少%q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("输出%d个事件，期望2个", n)
+	}
+	// 第二个事件没有描述与地点
+	second := out[strings.LastIndex(out, "BEGIN:VEVENT"):]
+	if strings.Contains(second, "DESCRIPTION:") || strings.Contains(second, "LOCATION:") {
+		t.Errorf("空的描述或地点不应输出:\n%s", second)
+	}
+}
@@ -0,0 +1,31 @@
+package mcp
+
+import "context"
+
+// adminKey 管理员身份在context中的键
+type adminKey struct{}
+
+// WithAdmin 标记调用方是否为管理员，管理员才能看到并调用AdminOnly工具
+func WithAdmin(ctx context.Context, admin bool) context.Context {
+	return context.WithValue(ctx, adminKey{}, admin)
+}
+
+// IsAdmin 调用方是否为管理员
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// VisibleTools 过滤出调用方有权使用的工具
+func VisibleTools(ctx context.Context, tools []Tool) []Tool {
+	if IsAdmin(ctx) {
+		return tools
+	}
+	visible := make([]Tool, 0, len(tools))
+	for _, tool := range tools {
+		if !tool.AdminOnly {
+			visible = append(visible, tool)
+		}
+	}
+	return visible
+}
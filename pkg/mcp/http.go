@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionIDHeader Streamable HTTP会话ID请求头
+	SessionIDHeader = "Mcp-Session-Id"
+	// ProtocolVersionHeader 客户端协商后的协议版本请求头
+	ProtocolVersionHeader = "MCP-Protocol-Version"
+
+	// maxMessageSize 单条HTTP消息的最大字节数
+	maxMessageSize = 4 << 20
+	// sessionIdleTimeout 会话空闲超过该时长后被清理
+	sessionIdleTimeout = time.Hour
+)
+
+// HTTPHandler Streamable HTTP传输：客户端通过POST发送JSON-RPC消息，服务器以application/json返回响应。
+// 服务器不主动推送消息，因此GET（SSE流）返回405。
+type HTTPHandler struct {
+	handler    *Handler
+	adminToken string
+
+	mu       sync.Mutex
+	sessions map[string]time.Time // 会话ID -> 最近活跃时间
+}
+
+// NewHTTPHandler 创建Streamable HTTP处理器。请求携带 Authorization: Bearer <adminToken>
+// 时以管理员身份调用工具；adminToken为空时不开放令牌方式的管理员访问。
+func NewHTTPHandler(h *Handler, adminToken string) *HTTPHandler {
+	return &HTTPHandler{
+		handler:    h,
+		adminToken: adminToken,
+		sessions:   make(map[string]time.Time),
+	}
+}
+
+// ServeHTTP 实现http.Handler
+func (s *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodDelete:
+		s.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost 处理客户端发送的JSON-RPC消息
+func (s *HTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize+1))
+	if err != nil {
+		http.Error(w, "读取请求失败", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxMessageSize {
+		http.Error(w, "消息过大", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if version := r.Header.Get(ProtocolVersionHeader); version != "" && !supportedProtocolVersions[version] {
+		writeJSON(w, http.StatusBadRequest, errorResponse(nil, CodeInvalidRequest, "不支持的协议版本: "+version))
+		return
+	}
+
+	sessionID := r.Header.Get(SessionIDHeader)
+	if isInitialize(body) {
+		// initialize开启新会话
+		sessionID = s.createSession()
+		w.Header().Set(SessionIDHeader, sessionID)
+	} else {
+		if sessionID == "" {
+			writeJSON(w, http.StatusBadRequest, errorResponse(nil, CodeInvalidRequest, "缺少"+SessionIDHeader+"请求头"))
+			return
+		}
+		if !s.touchSession(sessionID) {
+			// 会话不存在或已过期，客户端需要重新initialize
+			writeJSON(w, http.StatusNotFound, errorResponse(nil, CodeInvalidRequest, "会话不存在或已过期"))
+			return
+		}
+	}
+
+	// 上游已认证为管理员（见WithAdmin）或携带管理员令牌时可使用管理员工具
+	ctx := WithAdmin(r.Context(), IsAdmin(r.Context()) || s.isAdmin(r))
+	resp := s.handler.HandleMessage(ctx, body)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(resp)
+}
+
+// handleDelete 客户端主动结束会话
+func (s *HTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(SessionIDHeader)
+	if sessionID == "" {
+		http.Error(w, "缺少"+SessionIDHeader+"请求头", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	_, ok := s.sessions[sessionID]
+	delete(s.sessions, sessionID)
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "会话不存在", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isAdmin 校验管理员令牌
+func (s *HTTPHandler) isAdmin(r *http.Request) bool {
+	if s.adminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// createSession 创建会话，并顺带清理空闲过久的会话
+func (s *HTTPHandler) createSession() string {
+	now := time.Now()
+	id := uuid.New().String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sid, lastSeen := range s.sessions {
+		if now.Sub(lastSeen) > sessionIdleTimeout {
+			delete(s.sessions, sid)
+		}
+	}
+	s.sessions[id] = now
+	return id
+}
+
+// touchSession 刷新会话活跃时间，会话不存在或已过期时返回false
+func (s *HTTPHandler) touchSession(id string) bool {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lastSeen, ok := s.sessions[id]
+	if !ok {
+		return false
+	}
+	if now.Sub(lastSeen) > sessionIdleTimeout {
+		delete(s.sessions, id)
+		return false
+	}
+	s.sessions[id] = now
+	return true
+}
+
+// isInitialize 判断消息是否为initialize请求（initialize不允许出现在批量消息中）
+func isInitialize(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return false
+	}
+	var probe struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(body, &probe) == nil && probe.Method == "initialize"
+}
+
+// writeJSON 写出JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
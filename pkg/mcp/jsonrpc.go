@@ -0,0 +1,319 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// LatestProtocolVersion 服务器支持的最新MCP协议版本
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions 支持的MCP协议版本，客户端请求其中之一时原样返回
+var supportedProtocolVersions = map[string]bool{
+	"2025-06-18": true,
+	"2025-03-26": true,
+	"2024-11-05": true,
+}
+
+// JSON-RPC 2.0 错误码
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request JSON-RPC请求或通知（ID为空时为通知）
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification 是否为通知（无需响应）
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response JSON-RPC响应
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError JSON-RPC错误
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error 实现error接口
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("JSON-RPC错误 %d: %s", e.Code, e.Message)
+}
+
+// ServerInfo 服务器信息，在initialize响应中返回
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// toolDescriptor tools/list中的工具描述
+type toolDescriptor struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// Content 工具结果内容
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CallToolResult tools/call的结果
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Handler MCP协议处理器，将JSON-RPC消息分发到Server上注册的工具，与传输方式无关
+type Handler struct {
+	server Server
+	info   ServerInfo
+}
+
+// NewHandler 创建MCP协议处理器
+func NewHandler(server Server, info ServerInfo) *Handler {
+	return &Handler{
+		server: server,
+		info:   info,
+	}
+}
+
+// HandleMessage 处理一条原始JSON-RPC消息（单条或批量），返回需要写回的响应。
+// 消息只包含通知或客户端响应时返回nil。
+func (h *Handler) HandleMessage(ctx context.Context, data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return h.handleBatch(ctx, data)
+	}
+
+	resp := h.handleSingle(ctx, data)
+	if resp == nil {
+		return nil
+	}
+	out, _ := json.Marshal(resp)
+	return out
+}
+
+// handleBatch 处理批量消息
+func (h *Handler) handleBatch(ctx context.Context, data []byte) []byte {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		out, _ := json.Marshal(errorResponse(nil, CodeParseError, "解析JSON失败"))
+		return out
+	}
+	if len(batch) == 0 {
+		out, _ := json.Marshal(errorResponse(nil, CodeInvalidRequest, "批量请求不能为空"))
+		return out
+	}
+
+	var responses []*Response
+	for _, msg := range batch {
+		if resp := h.handleSingle(ctx, msg); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	out, _ := json.Marshal(responses)
+	return out
+}
+
+// handleSingle 处理单条消息
+func (h *Handler) handleSingle(ctx context.Context, data []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return errorResponse(nil, CodeParseError, "解析JSON失败")
+	}
+
+	// 客户端发来的响应（如对ping的回复），服务器不主动发请求，直接忽略
+	if req.Method == "" && !req.IsNotification() {
+		var probe struct {
+			Result json.RawMessage `json:"result"`
+			Error  json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(data, &probe) == nil && (probe.Result != nil || probe.Error != nil) {
+			return nil
+		}
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.IsNotification() {
+			return nil
+		}
+		return errorResponse(req.ID, CodeInvalidRequest, "无效的JSON-RPC请求")
+	}
+
+	result, rpcErr := h.dispatch(ctx, &req)
+	if req.IsNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return &Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch 按方法名分发请求
+func (h *Handler) dispatch(ctx context.Context, req *Request) (interface{}, *RPCError) {
+	switch req.Method {
+	case "initialize":
+		return h.initialize(req.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return h.listTools(ctx), nil
+	case "tools/call":
+		return h.callTool(ctx, req.Params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	default:
+		return nil, &RPCError{Code: CodeMethodNotFound, Message: fmt.Sprintf("不支持的方法: %s", req.Method)}
+	}
+}
+
+// initialize 协商协议版本并声明服务器能力
+func (h *Handler) initialize(params json.RawMessage) (interface{}, *RPCError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: "无效的initialize参数"}
+		}
+	}
+
+	version := LatestProtocolVersion
+	if supportedProtocolVersions[p.ProtocolVersion] {
+		version = p.ProtocolVersion
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{
+				"listChanged": false,
+			},
+		},
+		"serverInfo": h.info,
+	}, nil
+}
+
+// listTools 列出工具，按名称排序保证结果稳定
+func (h *Handler) listTools(ctx context.Context) interface{} {
+	tools := VisibleTools(ctx, h.server.ListTools())
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+
+	descriptors := make([]toolDescriptor, len(tools))
+	for i, tool := range tools {
+		schema := tool.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		descriptors[i] = toolDescriptor{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		}
+	}
+
+	return map[string]interface{}{"tools": descriptors}
+}
+
+// callTool 调用工具。工具不存在属于协议错误；工具执行失败通过isError返回给客户端
+func (h *Handler) callTool(ctx context.Context, params json.RawMessage) (interface{}, *RPCError) {
+	var p struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.Name == "" {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: "无效的tools/call参数"}
+	}
+	if !h.hasTool(ctx, p.Name) {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("工具 %s 不存在", p.Name)}
+	}
+	if p.Arguments == nil {
+		p.Arguments = make(map[string]interface{})
+	}
+
+	result, err := h.server.CallTool(ctx, p.Name, p.Arguments)
+	if err != nil {
+		text := err.Error()
+		// 参数校验错误附带逐项说明，便于客户端（模型）修正参数后重试
+		var validationErr *ValidationError
+		var toolErr *ToolError
+		if errors.As(err, &validationErr) {
+			if data, marshalErr := json.Marshal(map[string]interface{}{
+				"error":             text,
+				"invalid_arguments": validationErr.Errors,
+			}); marshalErr == nil {
+				text = string(data)
+			}
+		} else if errors.As(err, &toolErr) {
+			if data, marshalErr := json.Marshal(map[string]interface{}{
+				"error": text,
+				"code":  toolErr.Code,
+			}); marshalErr == nil {
+				text = string(data)
+			}
+		}
+		return &CallToolResult{
+			Content: []Content{{Type: "text", Text: text}},
+			IsError: true,
+		}, nil
+	}
+
+	text, err := json.Marshal(result.Data)
+	if err != nil {
+		return nil, &RPCError{Code: CodeInternalError, Message: "序列化工具结果失败"}
+	}
+	return &CallToolResult{
+		Content: []Content{{Type: "text", Text: string(text)}},
+	}, nil
+}
+
+// hasTool 判断工具是否已注册且对调用方可见
+func (h *Handler) hasTool(ctx context.Context, name string) bool {
+	for _, tool := range VisibleTools(ctx, h.server.ListTools()) {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// errorResponse 构建错误响应，id未知时为null
+func errorResponse(id json.RawMessage, code int, message string) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &RPCError{Code: code, Message: message},
+	}
+}
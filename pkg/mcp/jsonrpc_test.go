@@ -0,0 +1,260 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHandler 创建注册了echo、fail与管理员专用工具的处理器
+func newTestHandler() *Handler {
+	server := NewServer(time.Second)
+	server.RegisterTool(Tool{
+		Name: "echo",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+			"required":   []string{"text"},
+		},
+	}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"text": args["text"]}, nil
+	})
+	server.RegisterTool(Tool{Name: "fail"}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, &ToolError{Code: "class_full", Err: errors.New("课程已满")}
+	})
+	server.RegisterTool(Tool{Name: "admin_only", AdminOnly: true}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	return NewHandler(server, ServerInfo{Name: "test", Version: "1.0"})
+}
+
+// rpcResponse 测试中解析的响应
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		admin      bool
+		wantNil    bool   // 不需要响应
+		wantID     string // 响应的id
+		wantCode   int    // JSON-RPC错误码，0表示成功
+		wantResult string // 成功结果中应包含的文本
+	}{
+		{
+			name:       "initialize协商支持的版本",
+			message:    `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+			wantID:     "1",
+			wantResult: `"protocolVersion":"2024-11-05"`,
+		},
+		{
+			name:       "initialize不支持的版本返回最新版本",
+			message:    `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+			wantID:     "2",
+			wantResult: `"protocolVersion":"` + LatestProtocolVersion + `"`,
+		},
+		{
+			name:       "ping",
+			message:    `{"jsonrpc":"2.0","id":"a","method":"ping"}`,
+			wantID:     `"a"`,
+			wantResult: `{}`,
+		},
+		{
+			name:       "tools/list按名称排序且隐藏管理员工具",
+			message:    `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`,
+			wantID:     "3",
+			wantResult: `"tools":[{"name":"echo"`,
+		},
+		{
+			name:       "管理员可以看到管理员工具",
+			message:    `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`,
+			admin:      true,
+			wantID:     "4",
+			wantResult: `{"name":"admin_only","inputSchema":{"type":"object"}}`,
+		},
+		{
+			name:       "tools/call成功",
+			message:    `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+			wantID:     "5",
+			wantResult: `{"type":"text","text":"{\"text\":\"hi\"}"}`,
+		},
+		{
+			name:       "参数校验失败通过isError返回",
+			message:    `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+			wantID:     "6",
+			wantResult: `invalid_arguments`,
+		},
+		{
+			name:       "工具错误附带错误码",
+			message:    `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"fail"}}`,
+			wantID:     "7",
+			wantResult: `\"code\":\"class_full\"`,
+		},
+		{
+			name:     "调用不存在的工具",
+			message:  `{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"missing"}}`,
+			wantID:   "8",
+			wantCode: CodeInvalidParams,
+		},
+		{
+			name:     "非管理员调用管理员工具视为不存在",
+			message:  `{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"name":"admin_only"}}`,
+			wantID:   "9",
+			wantCode: CodeInvalidParams,
+		},
+		{
+			name:     "tools/call缺少工具名",
+			message:  `{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{}}`,
+			wantID:   "10",
+			wantCode: CodeInvalidParams,
+		},
+		{
+			name:     "不支持的方法",
+			message:  `{"jsonrpc":"2.0","id":11,"method":"resources/list"}`,
+			wantID:   "11",
+			wantCode: CodeMethodNotFound,
+		},
+		{
+			name:     "解析失败",
+			message:  `{"jsonrpc":`,
+			wantID:   "null",
+			wantCode: CodeParseError,
+		},
+		{
+			name:     "缺少jsonrpc版本",
+			message:  `{"id":12,"method":"ping"}`,
+			wantID:   "12",
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:    "通知不需要响应",
+			message: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			wantNil: true,
+		},
+		{
+			name:    "未知方法的通知也不响应",
+			message: `{"jsonrpc":"2.0","method":"unknown"}`,
+			wantNil: true,
+		},
+		{
+			name:    "忽略客户端发来的响应",
+			message: `{"jsonrpc":"2.0","id":13,"result":{}}`,
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithAdmin(context.Background(), tt.admin)
+			out := newTestHandler().HandleMessage(ctx, []byte(tt.message))
+
+			if tt.wantNil {
+				if out != nil {
+					t.Fatalf("期望不响应，实际为%s", out)
+				}
+				return
+			}
+
+			var resp rpcResponse
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("解析响应%s失败: %v", out, err)
+			}
+			if string(resp.ID) != tt.wantID {
+				t.Errorf("响应id为%s，期望%s", resp.ID, tt.wantID)
+			}
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Fatalf("响应%s，期望错误码%d", out, tt.wantCode)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("返回错误: %v", resp.Error)
+			}
+			if !strings.Contains(string(resp.Result), tt.wantResult) {
+				t.Errorf("结果%s不包含%s", resp.Result, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestHandleMessageToolListHidesAdminTools(t *testing.T) {
+	out := newTestHandler().HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if strings.Contains(string(out), "admin_only") {
+		t.Errorf("非管理员的工具列表包含管理员工具: %s", out)
+	}
+}
+
+func TestHandleMessageBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		wantNil  bool
+		wantIDs  []string
+		wantCode int // 整批失败时的错误码
+	}{
+		{
+			name: "只返回请求的响应",
+			message: `[{"jsonrpc":"2.0","id":1,"method":"ping"},
+				{"jsonrpc":"2.0","method":"notifications/initialized"},
+				{"jsonrpc":"2.0","id":2,"method":"unknown"}]`,
+			wantIDs: []string{"1", "2"},
+		},
+		{
+			name:    "全部为通知时不响应",
+			message: `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`,
+			wantNil: true,
+		},
+		{
+			name:     "空批量",
+			message:  `[]`,
+			wantCode: CodeInvalidRequest,
+		},
+		{
+			name:     "批量解析失败",
+			message:  `[{"jsonrpc":"2.0"`,
+			wantCode: CodeParseError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := newTestHandler().HandleMessage(context.Background(), []byte(tt.message))
+
+			if tt.wantNil {
+				if out != nil {
+					t.Fatalf("期望不响应，实际为%s", out)
+				}
+				return
+			}
+			if tt.wantCode != 0 {
+				var resp rpcResponse
+				if err := json.Unmarshal(out, &resp); err != nil {
+					t.Fatalf("解析响应%s失败: %v", out, err)
+				}
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Fatalf("响应%s，期望错误码%d", out, tt.wantCode)
+				}
+				return
+			}
+
+			var responses []rpcResponse
+			if err := json.Unmarshal(out, &responses); err != nil {
+				t.Fatalf("解析响应%s失败: %v", out, err)
+			}
+			if len(responses) != len(tt.wantIDs) {
+				t.Fatalf("得到%d个响应，期望%d个", len(responses), len(tt.wantIDs))
+			}
+			for i, resp := range responses {
+				if string(resp.ID) != tt.wantIDs[i] {
+					t.Errorf("第%d个响应id为%s，期望%s", i, resp.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,35 @@
 package mcp
 
+import (
+	"context"
+	"time"
+)
+
 // Tool MCP工具定义
 type Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters"`
+	// Timeout 单次调用的超时时间，为0时使用服务器的默认超时
+	Timeout time.Duration `json:"-"`
+	// AdminOnly 仅管理员可见、可调用（见WithAdmin）
+	AdminOnly bool `json:"-"`
+}
+
+// ToolError 带业务错误码的工具执行错误，错误码随错误信息一并返回给客户端
+type ToolError struct {
+	Code string
+	Err  error
+}
+
+// Error 实现error接口
+func (e *ToolError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *ToolError) Unwrap() error {
+	return e.Err
 }
 
 // ToolCall MCP工具调用
@@ -24,9 +49,8 @@ type ToolResult struct {
 type Server interface {
 	RegisterTool(tool Tool, handler ToolHandler)
 	ListTools() []Tool
-	CallTool(name string, args map[string]interface{}) (*ToolResult, error)
+	CallTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error)
 }
 
-// ToolHandler 工具处理函数
-type ToolHandler func(args map[string]interface{}) (interface{}, error)
-
+// ToolHandler 工具处理函数，ctx携带调用方的追踪信息、取消信号与工具超时
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
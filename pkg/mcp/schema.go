@@ -0,0 +1,371 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FieldError 单个参数的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError 工具参数校验失败，Errors逐项说明需要修正的参数，便于模型自行更正后重试
+type ValidationError struct {
+	Tool   string       `json:"tool"`
+	Errors []FieldError `json:"errors"`
+}
+
+// Error 实现error接口
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return fmt.Sprintf("工具 %s 参数校验失败: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+// datePattern YYYY-MM-DD
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// ValidateArguments 按工具声明的JSON Schema校验并转换参数，返回转换后的新参数（不修改args）。
+//
+// 支持的关键字：type、properties、required、enum、default、format（date、date-time、uuid）、
+// minimum、maximum、minLength、maxLength、items。模型常见的类型偏差会被自动转换：
+// 数字字符串转为number/integer，"true"/"false"转为boolean，数字转为string。
+// integer类型的参数统一转换为int，number类型为float64。
+func ValidateArguments(tool string, schema map[string]interface{}, args map[string]interface{}) (map[string]interface{}, error) {
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	if schema == nil {
+		return args, nil
+	}
+
+	v := &validator{}
+	result := v.validateObject("", schema, args)
+	if len(v.errors) > 0 {
+		return nil, &ValidationError{Tool: tool, Errors: v.errors}
+	}
+	return result, nil
+}
+
+// validator 收集校验过程中的全部错误
+type validator struct {
+	errors []FieldError
+}
+
+// fail 记录一个字段错误
+func (v *validator) fail(field, format string, a ...interface{}) {
+	if field == "" {
+		field = "(root)"
+	}
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// validateObject 校验对象的required与各属性，返回转换后的对象
+func (v *validator) validateObject(path string, schema map[string]interface{}, obj map[string]interface{}) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	result := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		result[key] = value
+	}
+
+	required := stringList(schema["required"])
+	missing := make(map[string]bool)
+	for _, name := range required {
+		if value, ok := obj[name]; !ok || value == nil || value == "" {
+			missing[name] = true
+			v.fail(joinPath(path, name), "缺少必填参数")
+		}
+	}
+
+	// 按名称排序，保证错误顺序稳定
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propSchema, ok := properties[name].(map[string]interface{})
+		if !ok || missing[name] {
+			continue
+		}
+		// 可选参数为null或空字符串时视为未传
+		value := obj[name]
+		if value == nil || value == "" {
+			delete(result, name)
+			if def, ok := propSchema["default"]; ok {
+				result[name] = def
+			}
+			continue
+		}
+		result[name] = v.validateValue(joinPath(path, name), propSchema, value)
+	}
+
+	return result
+}
+
+// validateValue 校验并转换单个值
+func (v *validator) validateValue(path string, schema map[string]interface{}, value interface{}) interface{} {
+	switch schemaType(schema) {
+	case "string":
+		s, ok := coerceString(value)
+		if !ok {
+			v.fail(path, "应为字符串，实际为%s", describe(value))
+			return value
+		}
+		v.checkString(path, schema, s)
+		return s
+
+	case "integer":
+		n, ok := coerceNumber(value)
+		if !ok || n != math.Trunc(n) {
+			v.fail(path, "应为整数，实际为%s", describe(value))
+			return value
+		}
+		v.checkRange(path, schema, n)
+		v.checkEnum(path, schema, int(n))
+		return int(n)
+
+	case "number":
+		n, ok := coerceNumber(value)
+		if !ok {
+			v.fail(path, "应为数字，实际为%s", describe(value))
+			return value
+		}
+		v.checkRange(path, schema, n)
+		v.checkEnum(path, schema, n)
+		return n
+
+	case "boolean":
+		b, ok := coerceBool(value)
+		if !ok {
+			v.fail(path, "应为布尔值，实际为%s", describe(value))
+			return value
+		}
+		return b
+
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, "应为对象，实际为%s", describe(value))
+			return value
+		}
+		return v.validateObject(path, schema, obj)
+
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, "应为数组，实际为%s", describe(value))
+			return value
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		if itemSchema == nil {
+			return arr
+		}
+		result := make([]interface{}, len(arr))
+		for i, item := range arr {
+			result[i] = v.validateValue(fmt.Sprintf("%s[%d]", path, i), itemSchema, item)
+		}
+		return result
+
+	default:
+		v.checkEnum(path, schema, value)
+		return value
+	}
+}
+
+// checkString 校验字符串的长度、格式与枚举
+func (v *validator) checkString(path string, schema map[string]interface{}, s string) {
+	length := len([]rune(s))
+	if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+		v.fail(path, "长度不能少于%d个字符", int(min))
+	}
+	if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+		v.fail(path, "长度不能超过%d个字符", int(max))
+	}
+
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil || !datePattern.MatchString(s) {
+			v.fail(path, "应为YYYY-MM-DD格式的日期，实际为%q", s)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			v.fail(path, "应为RFC3339格式的时间（如2024-01-02T15:04:05+08:00），实际为%q", s)
+		}
+	case "uuid":
+		if _, err := uuid.Parse(s); err != nil {
+			v.fail(path, "应为UUID，实际为%q", s)
+		}
+	}
+
+	v.checkEnum(path, schema, s)
+}
+
+// checkRange 校验数值范围
+func (v *validator) checkRange(path string, schema map[string]interface{}, n float64) {
+	if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+		v.fail(path, "不能小于%v", min)
+	}
+	if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+		v.fail(path, "不能大于%v", max)
+	}
+}
+
+// checkEnum 校验枚举值
+func (v *validator) checkEnum(path string, schema map[string]interface{}, value interface{}) {
+	enum := enumValues(schema["enum"])
+	if len(enum) == 0 {
+		return
+	}
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return
+		}
+	}
+	options := make([]string, len(enum))
+	for i, allowed := range enum {
+		options[i] = fmt.Sprint(allowed)
+	}
+	v.fail(path, "取值应为 %s 之一，实际为%v", strings.Join(options, "、"), value)
+}
+
+// schemaType 读取schema的type
+func schemaType(schema map[string]interface{}) string {
+	t, _ := schema["type"].(string)
+	return t
+}
+
+// schemaNumber 读取schema中的数值关键字
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	return coerceNumber(schema[key])
+}
+
+// coerceString 转换为字符串，数字与布尔值按字面转换
+func coerceString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// coerceNumber 转换为数字，支持数字字符串
+func coerceNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// coerceBool 转换为布尔值，支持"true"/"false"字符串
+func coerceBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return b, err == nil
+	default:
+		return false, false
+	}
+}
+
+// describe 描述值的JSON类型，用于错误信息
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("字符串%q", v)
+	case float64, int:
+		return fmt.Sprintf("数字%v", v)
+	case bool:
+		return fmt.Sprintf("布尔值%v", v)
+	case map[string]interface{}:
+		return "对象"
+	case []interface{}:
+		return "数组"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// stringList 读取字符串列表（schema在Go中声明时为[]string，JSON解码后为[]interface{}）
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// enumValues 读取枚举列表
+func enumValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	case []int:
+		list := make([]interface{}, len(v))
+		for i, n := range v {
+			list[i] = n
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// joinPath 拼接字段路径
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateArguments(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"class_id": map[string]interface{}{"type": "string", "format": "uuid"},
+			"date":     map[string]interface{}{"type": "string", "format": "date"},
+			"start":    map[string]interface{}{"type": "string", "format": "date-time"},
+			"level":    map[string]interface{}{"type": "string", "enum": []string{"beginner", "advanced"}},
+			"note":     map[string]interface{}{"type": "string", "minLength": 2, "maxLength": 4},
+			"limit":    map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 50, "default": 10},
+			"score":    map[string]interface{}{"type": "number"},
+			"waitlist": map[string]interface{}{"type": "boolean"},
+			"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			"filter": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"room": map[string]interface{}{"type": "string"}},
+				"required":   []string{"room"},
+			},
+		},
+		"required": []string{"class_id"},
+	}
+	const classID = "3b241101-e2bb-4255-8caf-4136c566a962"
+
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		want       map[string]interface{}
+		wantFields []string // 期望出错的字段，按出现顺序
+	}{
+		{
+			name: "合法参数并补充默认值",
+			args: map[string]interface{}{"class_id": classID, "date": "2024-03-01", "level": "beginner"},
+			want: map[string]interface{}{"class_id": classID, "date": "2024-03-01", "level": "beginner", "limit": 10},
+		},
+		{
+			name: "类型偏差自动转换",
+			args: map[string]interface{}{"class_id": classID, "limit": "20", "score": "4.5", "waitlist": "true", "tags": []interface{}{"1", 2.0}},
+			want: map[string]interface{}{"class_id": classID, "limit": 20, "score": 4.5, "waitlist": true, "tags": []interface{}{1, 2}},
+		},
+		{
+			name: "数字转为字符串",
+			args: map[string]interface{}{"class_id": classID, "note": 123.0},
+			want: map[string]interface{}{"class_id": classID, "note": "123", "limit": 10},
+		},
+		{
+			name: "可选参数为null或空字符串时视为未传",
+			args: map[string]interface{}{"class_id": classID, "date": "", "limit": nil},
+			want: map[string]interface{}{"class_id": classID, "limit": 10},
+		},
+		{
+			name: "未声明的参数原样保留",
+			args: map[string]interface{}{"class_id": classID, "extra": "x"},
+			want: map[string]interface{}{"class_id": classID, "extra": "x", "limit": 10},
+		},
+		{
+			name:       "缺少必填参数",
+			args:       map[string]interface{}{},
+			wantFields: []string{"class_id"},
+		},
+		{
+			name:       "必填参数为空字符串",
+			args:       map[string]interface{}{"class_id": ""},
+			wantFields: []string{"class_id"},
+		},
+		{
+			name:       "格式错误",
+			args:       map[string]interface{}{"class_id": "abc", "date": "2024-3-1", "start": "2024-03-01 10:00"},
+			wantFields: []string{"class_id", "date", "start"},
+		},
+		{
+			name:       "不存在的日期",
+			args:       map[string]interface{}{"class_id": classID, "date": "2024-02-30"},
+			wantFields: []string{"date"},
+		},
+		{
+			name:       "枚举与长度",
+			args:       map[string]interface{}{"class_id": classID, "level": "expert", "note": "太长的备注内容"},
+			wantFields: []string{"level", "note"},
+		},
+		{
+			name:       "数值范围与整数",
+			args:       map[string]interface{}{"class_id": classID, "limit": 1.5},
+			wantFields: []string{"limit"},
+		},
+		{
+			name:       "超出最大值",
+			args:       map[string]interface{}{"class_id": classID, "limit": 100.0},
+			wantFields: []string{"limit"},
+		},
+		{
+			name:       "无法转换的类型",
+			args:       map[string]interface{}{"class_id": classID, "waitlist": "yes", "score": "high", "tags": "1,2"},
+			wantFields: []string{"score", "tags", "waitlist"},
+		},
+		{
+			name:       "数组元素与嵌套对象的字段路径",
+			args:       map[string]interface{}{"class_id": classID, "tags": []interface{}{1.0, "x"}, "filter": map[string]interface{}{}},
+			wantFields: []string{"filter.room", "tags[1]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateArguments("test_tool", schema, tt.args)
+
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("校验失败: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("转换结果为%#v，期望%#v", got, tt.want)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("返回%v，期望*ValidationError", err)
+			}
+			if validationErr.Tool != "test_tool" {
+				t.Errorf("Tool为%q", validationErr.Tool)
+			}
+			fields := make([]string, len(validationErr.Errors))
+			for i, fe := range validationErr.Errors {
+				fields[i] = fe.Field
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("出错的字段为%v，期望%v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateArgumentsDoesNotModifyArgs(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"limit": map[string]interface{}{"type": "integer", "default": 10}},
+	}
+	args := map[string]interface{}{"limit": "5"}
+	if _, err := ValidateArguments("test_tool", schema, args); err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if args["limit"] != "5" {
+		t.Errorf("原参数被修改为%#v", args["limit"])
+	}
+}
+
+func TestValidateArgumentsWithoutSchema(t *testing.T) {
+	got, err := ValidateArguments("test_tool", nil, nil)
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("期望空参数，实际为%#v", got)
+	}
+}
@@ -1,22 +1,37 @@
 package mcp
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
+
+	"github.com/yoga/knowledge-base/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// DefaultToolTimeout 未配置时工具调用的默认超时时间
+const DefaultToolTimeout = 30 * time.Second
+
 // DefaultServer 默认MCP服务器实现
 type DefaultServer struct {
-	tools   map[string]Tool
-	handlers map[string]ToolHandler
-	mu      sync.RWMutex
+	tools          map[string]Tool
+	handlers       map[string]ToolHandler
+	defaultTimeout time.Duration
+	mu             sync.RWMutex
 }
 
-// NewServer 创建MCP服务器
-func NewServer() *DefaultServer {
+// NewServer 创建MCP服务器，defaultTimeout为未单独配置超时的工具的调用超时
+func NewServer(defaultTimeout time.Duration) *DefaultServer {
+	if defaultTimeout <= 0 {
+		defaultTimeout = DefaultToolTimeout
+	}
 	return &DefaultServer{
-		tools:    make(map[string]Tool),
-		handlers: make(map[string]ToolHandler),
+		tools:          make(map[string]Tool),
+		handlers:       make(map[string]ToolHandler),
+		defaultTimeout: defaultTimeout,
 	}
 }
 
@@ -42,10 +57,12 @@ func (s *DefaultServer) ListTools() []Tool {
 	return tools
 }
 
-// CallTool 调用工具
-func (s *DefaultServer) CallTool(name string, args map[string]interface{}) (*ToolResult, error) {
+// CallTool 调用工具：先按schema校验参数（失败时返回*ValidationError），
+// 每次调用创建独立的span并受工具超时限制
+func (s *DefaultServer) CallTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error) {
 	s.mu.RLock()
-	handler, exists := s.handlers[name]
+	tool, exists := s.tools[name]
+	handler := s.handlers[name]
 	s.mu.RUnlock()
 
 	if !exists {
@@ -55,8 +72,42 @@ func (s *DefaultServer) CallTool(name string, args map[string]interface{}) (*Too
 		}, fmt.Errorf("工具 %s 不存在", name)
 	}
 
-	result, err := handler(args)
+	if tool.AdminOnly && !IsAdmin(ctx) {
+		return &ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("工具 %s 需要管理员权限", name),
+		}, fmt.Errorf("工具 %s 需要管理员权限", name)
+	}
+
+	ctx, span := observability.StartSpan(ctx, "mcp-server", "tool/"+name)
+	defer span.End()
+	span.SetAttributes(attribute.String("mcp.tool", name))
+
+	// 按工具声明的schema校验并转换参数，校验失败不调用处理函数
+	args, err := ValidateArguments(name, tool.Parameters, args)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return &ToolResult{
+			Success: false,
+			Error:   err.Error(),
+		}, err
+	}
+
+	timeout := tool.Timeout
+	if timeout <= 0 {
+		timeout = s.defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := invoke(ctx, handler, args)
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("工具 %s 执行超时（%s）: %w", name, timeout, err)
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return &ToolResult{
 			Success: false,
 			Error:   err.Error(),
@@ -69,3 +120,29 @@ func (s *DefaultServer) CallTool(name string, args map[string]interface{}) (*Too
 	}, nil
 }
 
+// invoke 执行工具处理函数。处理函数未及时响应ctx时，超时或取消后立即返回，
+// 处理函数中的panic转换为错误，避免拖垮整个服务。
+func invoke(ctx context.Context, handler ToolHandler, args map[string]interface{}) (interface{}, error) {
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("工具执行异常: %v", r)}
+			}
+		}()
+		result, err := handler(ctx, args)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
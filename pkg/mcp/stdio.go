@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ServeStdio 以stdio传输方式提供MCP服务：每行一条JSON-RPC消息，响应同样按行写出。
+// 请求并发处理，响应可能乱序返回（由id关联）。输入结束或ctx取消时返回。
+func ServeStdio(ctx context.Context, h *Handler, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	defer wg.Wait()
+
+	write := func(data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := out.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("写入响应失败: %w", err)
+		}
+		return nil
+	}
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr <- fmt.Errorf("读取消息失败: %w", err)
+				}
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp := h.HandleMessage(ctx, line); resp != nil {
+					_ = write(resp)
+				}
+			}()
+		}
+	}
+}
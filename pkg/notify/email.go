@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig SMTP配置
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// EmailChannel 邮件渠道
+type EmailChannel struct {
+	cfg EmailConfig
+}
+
+// NewEmailChannel 创建邮件渠道
+func NewEmailChannel(cfg EmailConfig) *EmailChannel {
+	return &EmailChannel{cfg: cfg}
+}
+
+// Name 渠道名称
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send 以纯文本邮件发送消息，标题作为邮件主题
+func (c *EmailChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Email == "" {
+		return ErrNoAddress
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("From: " + c.cfg.From + "\r\n")
+	sb.WriteString("To: " + to.Email + "\r\n")
+	sb.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Title) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	sb.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if c.cfg.Username != "" {
+		auth = smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
+	}
+	addr := net.JoinHostPort(c.cfg.Host, strconv.Itoa(c.cfg.Port))
+	if err := smtp.SendMail(addr, auth, c.cfg.From, []string{to.Email}, []byte(sb.String())); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LogChannel 将消息写入日志，本地开发时代替真实渠道
+type LogChannel struct {
+	logger *zap.Logger
+}
+
+// NewLogChannel 创建日志渠道
+func NewLogChannel(logger *zap.Logger) *LogChannel {
+	return &LogChannel{logger: logger}
+}
+
+// Name 渠道名称
+func (c *LogChannel) Name() string {
+	return "log"
+}
+
+// Send 记录消息
+func (c *LogChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	c.logger.Info("通知",
+		zap.String("user_id", to.UserID),
+		zap.String("template", msg.Template),
+		zap.String("title", msg.Title),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
+
+// FileChannel 将消息按JSON行追加写入文件，便于本地开发与测试时检查发出的消息
+type FileChannel struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileChannel 创建文件渠道
+func NewFileChannel(path string) *FileChannel {
+	return &FileChannel{path: path}
+}
+
+// Name 渠道名称
+func (c *FileChannel) Name() string {
+	return "file"
+}
+
+// fileRecord 文件渠道的一行记录
+type fileRecord struct {
+	SentAt   time.Time         `json:"sent_at"`
+	To       Recipient         `json:"to"`
+	Template string            `json:"template"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+	Page     string            `json:"page,omitempty"`
+}
+
+// Send 追加一行消息记录
+func (c *FileChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	line, err := json.Marshal(fileRecord{
+		SentAt:   time.Now(),
+		To:       to,
+		Template: msg.Template,
+		Title:    msg.Title,
+		Body:     msg.Body,
+		Data:     msg.Data,
+		Page:     msg.Page,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开消息文件失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入消息文件失败: %w", err)
+	}
+	return nil
+}
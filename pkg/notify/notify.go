@@ -0,0 +1,73 @@
+// Package notify 消息通知：按模板渲染消息，经由一个或多个渠道（微信订阅消息、短信、邮件、本地日志/文件）发送。
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ErrNoAddress 收件人没有该渠道的联系方式（如未填写手机号），渠道跳过该收件人
+var ErrNoAddress = errors.New("收件人没有该渠道的联系方式")
+
+// Recipient 收件人及其在各渠道的联系方式，为空表示无法通过对应渠道送达
+type Recipient struct {
+	UserID string
+	OpenID string // 小程序openid，用于订阅消息
+	Phone  string
+	Email  string
+}
+
+// Message 渲染后的消息
+type Message struct {
+	Template string            // 模板键，如 class_reminder
+	Title    string            // 标题（邮件主题）
+	Body     string            // 正文（短信、邮件内容）
+	Data     map[string]string // 模板数据，订阅消息按字段取值
+	Page     string            // 点击订阅消息后打开的小程序页面
+}
+
+// Channel 通知渠道
+type Channel interface {
+	// Name 渠道名称，用于日志
+	Name() string
+	// Send 发送消息，收件人没有该渠道的联系方式时返回ErrNoAddress
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Dispatcher 将消息发送到所有已配置的渠道
+type Dispatcher struct {
+	channels []Channel
+	logger   *zap.Logger
+}
+
+// NewDispatcher 创建消息分发器
+func NewDispatcher(logger *zap.Logger, channels ...Channel) *Dispatcher {
+	return &Dispatcher{
+		channels: channels,
+		logger:   logger,
+	}
+}
+
+// Send 依次通过各渠道发送消息。收件人缺少联系方式的渠道被跳过；
+// 单个渠道失败不影响其他渠道，返回所有失败渠道的错误。
+func (d *Dispatcher) Send(ctx context.Context, to Recipient, msg Message) error {
+	var errs []error
+	for _, ch := range d.channels {
+		err := ch.Send(ctx, to, msg)
+		switch {
+		case err == nil:
+			d.logger.Debug("通知已发送",
+				zap.String("channel", ch.Name()),
+				zap.String("template", msg.Template),
+				zap.String("user_id", to.UserID),
+			)
+		case errors.Is(err, ErrNoAddress):
+		default:
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SMSConfig 短信网关配置。网关接收JSON请求 {"phone","template","content","data"}，
+// 以 Authorization: Bearer <APIKey> 鉴权，返回2xx表示发送成功。
+type SMSConfig struct {
+	URL    string
+	APIKey string
+	Sign   string // 短信签名，加在正文前，如【悦瑜伽】
+}
+
+// SMSChannel 短信渠道
+type SMSChannel struct {
+	cfg        SMSConfig
+	httpClient *http.Client
+}
+
+// NewSMSChannel 创建短信渠道
+func NewSMSChannel(cfg SMSConfig) *SMSChannel {
+	return &SMSChannel{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Name 渠道名称
+func (c *SMSChannel) Name() string {
+	return "sms"
+}
+
+// Send 通过短信网关发送消息正文
+func (c *SMSChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Phone == "" {
+		return ErrNoAddress
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"phone":    to.Phone,
+		"template": msg.Template,
+		"content":  c.cfg.Sign + msg.Body,
+		"data":     msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("短信网关返回状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
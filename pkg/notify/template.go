@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Template 消息模板，标题与正文使用 text/template 语法，以消息数据（map[string]string）渲染
+type Template struct {
+	Title string
+	Body  string
+	Page  string // 点击订阅消息后打开的小程序页面，可引用消息数据
+}
+
+// Renderer 消息模板渲染器
+type Renderer struct {
+	templates map[string]*parsedTemplate
+}
+
+type parsedTemplate struct {
+	title *template.Template
+	body  *template.Template
+	page  *template.Template
+}
+
+// NewRenderer 解析模板，模板语法错误时返回错误
+func NewRenderer(templates map[string]Template) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*parsedTemplate, len(templates))}
+	for key, tpl := range templates {
+		parsed := &parsedTemplate{}
+		var err error
+		if parsed.title, err = parse(key+".title", tpl.Title); err != nil {
+			return nil, err
+		}
+		if parsed.body, err = parse(key+".body", tpl.Body); err != nil {
+			return nil, err
+		}
+		if parsed.page, err = parse(key+".page", tpl.Page); err != nil {
+			return nil, err
+		}
+		r.templates[key] = parsed
+	}
+	return r, nil
+}
+
+// Render 按模板键渲染消息，缺少的数据字段渲染为空
+func (r *Renderer) Render(key string, data map[string]string) (Message, error) {
+	tpl, ok := r.templates[key]
+	if !ok {
+		return Message{}, fmt.Errorf("消息模板%s不存在", key)
+	}
+
+	msg := Message{Template: key, Data: data}
+	var err error
+	if msg.Title, err = execute(tpl.title, data); err != nil {
+		return Message{}, err
+	}
+	if msg.Body, err = execute(tpl.body, data); err != nil {
+		return Message{}, err
+	}
+	if msg.Page, err = execute(tpl.page, data); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	tpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析消息模板%s失败: %w", name, err)
+	}
+	return tpl, nil
+}
+
+func execute(tpl *template.Template, data map[string]string) (string, error) {
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("渲染消息模板%s失败: %w", tpl.Name(), err)
+	}
+	return sb.String(), nil
+}
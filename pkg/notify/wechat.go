@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/yoga/knowledge-base/pkg/wechat"
+)
+
+// SubscribeSender 发送小程序订阅消息，由 wechat.Client 实现
+type SubscribeSender interface {
+	SendSubscribeMessage(ctx context.Context, msg wechat.SubscribeMessage) error
+}
+
+// SubscribeTemplate 消息模板对应的订阅消息模板
+type SubscribeTemplate struct {
+	ID     string            `json:"id"`     // 小程序后台申请的模板ID
+	Fields map[string]string `json:"fields"` // 订阅消息关键词（如 thing1）到消息数据字段（如 class_name）
+}
+
+// LoadSubscribeTemplates 从JSON文件加载订阅消息模板配置，键为消息模板键
+func LoadSubscribeTemplates(path string) (map[string]SubscribeTemplate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取订阅消息模板配置失败: %w", err)
+	}
+	var templates map[string]SubscribeTemplate
+	if err := json.Unmarshal(content, &templates); err != nil {
+		return nil, fmt.Errorf("解析订阅消息模板配置失败: %w", err)
+	}
+	return templates, nil
+}
+
+// WeChatChannel 小程序订阅消息渠道
+type WeChatChannel struct {
+	sender    SubscribeSender
+	templates map[string]SubscribeTemplate
+}
+
+// NewWeChatChannel 创建订阅消息渠道，未配置订阅消息模板的消息不通过该渠道发送
+func NewWeChatChannel(sender SubscribeSender, templates map[string]SubscribeTemplate) *WeChatChannel {
+	return &WeChatChannel{
+		sender:    sender,
+		templates: templates,
+	}
+}
+
+// Name 渠道名称
+func (c *WeChatChannel) Name() string {
+	return "wechat"
+}
+
+// Send 按模板配置将消息数据填入订阅消息关键词后发送
+func (c *WeChatChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.OpenID == "" {
+		return ErrNoAddress
+	}
+	tpl, ok := c.templates[msg.Template]
+	if !ok || tpl.ID == "" {
+		return nil
+	}
+
+	data := make(map[string]string, len(tpl.Fields))
+	for keyword, field := range tpl.Fields {
+		data[keyword] = truncate(msg.Data[field], 20)
+	}
+	return c.sender.SendSubscribeMessage(ctx, wechat.SubscribeMessage{
+		ToUser:     to.OpenID,
+		TemplateID: tpl.ID,
+		Page:       msg.Page,
+		Data:       data,
+	})
+}
+
+// truncate 订阅消息的thing类关键词最多20个字符，超出时截断
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -19,7 +19,16 @@ func NewAdapter(client *Client) *Adapter {
 
 // Chat 实现AI服务的OpenAIClient接口
 func (a *Adapter) Chat(ctx context.Context, messages []ai.ChatMessage, tools []aiservice.Tool) (*ai.ChatResponse, error) {
-	// 转换工具类型
+	return a.client.Chat(ctx, messages, convertTools(tools))
+}
+
+// ChatStream 实现AI服务的OpenAIClient接口（流式）
+func (a *Adapter) ChatStream(ctx context.Context, messages []ai.ChatMessage, tools []aiservice.Tool, onDelta func(content string) error) (*ai.ChatResponse, error) {
+	return a.client.ChatStream(ctx, messages, convertTools(tools), onDelta)
+}
+
+// convertTools 转换工具类型
+func convertTools(tools []aiservice.Tool) []Tool {
 	openAITools := make([]Tool, len(tools))
 	for i, tool := range tools {
 		openAITools[i] = Tool{
@@ -31,6 +40,5 @@ func (a *Adapter) Chat(ctx context.Context, messages []ai.ChatMessage, tools []a
 			},
 		}
 	}
-
-	return a.client.Chat(ctx, messages, openAITools)
+	return openAITools
 }
@@ -18,6 +18,8 @@ type Client struct {
 	baseURL string
 	model   string
 	client  *http.Client
+	// streamClient 用于流式请求，不设置整体超时
+	streamClient *http.Client
 }
 
 // NewClient 创建OpenAI客户端
@@ -29,6 +31,7 @@ func NewClient(apiKey, baseURL, model string) *Client {
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		streamClient: &http.Client{},
 	}
 }
 
@@ -100,23 +103,14 @@ type Choice struct {
 
 // Chat 发送聊天请求
 func (c *Client) Chat(ctx context.Context, messages []ai.ChatMessage, tools []Tool) (*ai.ChatResponse, error) {
-	// 转换消息格式
-	openAIMessages := make([]Message, len(messages))
-	for i, msg := range messages {
-		openAIMessages[i] = Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
-
 	// 工具已经是正确的格式，直接使用
-	openAITools := tools
-
 	reqBody := ChatRequest{
-		Model:      c.model,
-		Messages:   openAIMessages,
-		Tools:      openAITools,
-		ToolChoice: "auto",
+		Model:    c.model,
+		Messages: convertMessages(messages),
+		Tools:    tools,
+	}
+	if len(tools) > 0 {
+		reqBody.ToolChoice = "auto"
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -141,23 +135,7 @@ func (c *Client) Chat(ctx context.Context, messages []ai.ChatMessage, tools []To
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		
-		// 处理常见的错误状态码，提供更友好的错误信息
-		var errorMsg string
-		switch resp.StatusCode {
-		case 401:
-			errorMsg = "API密钥无效，请检查OPENAI_API_KEY配置"
-		case 402:
-			errorMsg = "账户余额不足，请前往DeepSeek平台充值"
-		case 429:
-			errorMsg = "请求频率过高，请稍后重试"
-		case 500, 502, 503:
-			errorMsg = "AI服务暂时不可用，请稍后重试"
-		default:
-			errorMsg = fmt.Sprintf("请求失败，状态码: %d", resp.StatusCode)
-		}
-		
-		return nil, fmt.Errorf("%s (响应: %s)", errorMsg, string(bodyBytes))
+		return nil, fmt.Errorf("%s (响应: %s)", statusErrorMessage(resp.StatusCode), string(bodyBytes))
 	}
 
 	var openAIResp ChatResponse
@@ -184,6 +162,7 @@ func (c *Client) Chat(ctx context.Context, messages []ai.ChatMessage, tools []To
 			}
 
 			result.ToolCalls[i] = ai.ToolCall{
+				ID:        toolCall.ID,
 				Name:      toolCall.Function.Name,
 				Arguments: args,
 			}
@@ -192,3 +171,51 @@ func (c *Client) Chat(ctx context.Context, messages []ai.ChatMessage, tools []To
 
 	return result, nil
 }
+
+// convertMessages 转换消息格式，包括assistant消息的工具调用和tool消息的调用ID
+func convertMessages(messages []ai.ChatMessage) []Message {
+	openAIMessages := make([]Message, len(messages))
+	for i, msg := range messages {
+		openAIMessages[i] = Message{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+
+		if len(msg.ToolCalls) > 0 {
+			toolCalls := make([]ToolCall, len(msg.ToolCalls))
+			for j, toolCall := range msg.ToolCalls {
+				args, err := json.Marshal(toolCall.Arguments)
+				if err != nil {
+					args = []byte("{}")
+				}
+				toolCalls[j] = ToolCall{
+					ID:   toolCall.ID,
+					Type: "function",
+					Function: FunctionCall{
+						Name:      toolCall.Name,
+						Arguments: string(args),
+					},
+				}
+			}
+			openAIMessages[i].ToolCalls = toolCalls
+		}
+	}
+	return openAIMessages
+}
+
+// statusErrorMessage 将常见的错误状态码转换为更友好的错误信息
+func statusErrorMessage(statusCode int) string {
+	switch statusCode {
+	case 401:
+		return "API密钥无效，请检查OPENAI_API_KEY配置"
+	case 402:
+		return "账户余额不足，请前往DeepSeek平台充值"
+	case 429:
+		return "请求频率过高，请稍后重试"
+	case 500, 502, 503:
+		return "AI服务暂时不可用，请稍后重试"
+	default:
+		return fmt.Sprintf("请求失败，状态码: %d", statusCode)
+	}
+}
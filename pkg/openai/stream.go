@@ -0,0 +1,176 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/yoga/knowledge-base/internal/domain/ai"
+)
+
+// streamRequest 流式聊天请求
+type streamRequest struct {
+	ChatRequest
+	Stream bool `json:"stream"`
+}
+
+// streamChunk 流式响应中的单个数据块
+type streamChunk struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Delta struct {
+			Role      string `json:"role"`
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// toolCallBuilder 累积流式返回的工具调用片段
+type toolCallBuilder struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+// ChatStream 以流式方式发送聊天请求，每收到一段文本增量调用一次onDelta，
+// 结束后返回完整的响应（含工具调用）。ctx取消时会中断上游请求。
+func (c *Client) ChatStream(ctx context.Context, messages []ai.ChatMessage, tools []Tool, onDelta func(content string) error) (*ai.ChatResponse, error) {
+	reqBody := streamRequest{
+		ChatRequest: ChatRequest{
+			Model:    c.model,
+			Messages: convertMessages(messages),
+			Tools:    tools,
+		},
+		Stream: true,
+	}
+	if len(tools) > 0 {
+		reqBody.ToolChoice = "auto"
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/chat/completions", c.baseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+
+	// 流式响应的总时长不可预知，不使用带整体超时的client，由ctx控制生命周期
+	resp, err := c.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s (响应: %s)", statusErrorMessage(resp.StatusCode), string(bodyBytes))
+	}
+
+	var (
+		content   strings.Builder
+		toolCalls = make(map[int]*toolCallBuilder)
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			if onDelta != nil {
+				if err := onDelta(delta.Content); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, tc := range delta.ToolCalls {
+			builder, ok := toolCalls[tc.Index]
+			if !ok {
+				builder = &toolCallBuilder{}
+				toolCalls[tc.Index] = builder
+			}
+			if tc.ID != "" {
+				builder.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				builder.name = tc.Function.Name
+			}
+			builder.arguments.WriteString(tc.Function.Arguments)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	result := &ai.ChatResponse{
+		Message: content.String(),
+	}
+
+	// 处理工具调用
+	if len(toolCalls) > 0 {
+		indexes := make([]int, 0, len(toolCalls))
+		for index := range toolCalls {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+
+		result.ToolCalls = make([]ai.ToolCall, 0, len(indexes))
+		for _, index := range indexes {
+			builder := toolCalls[index]
+			var args map[string]interface{}
+			if err := json.Unmarshal([]byte(builder.arguments.String()), &args); err != nil {
+				args = make(map[string]interface{})
+			}
+
+			result.ToolCalls = append(result.ToolCalls, ai.ToolCall{
+				ID:        builder.id,
+				Name:      builder.name,
+				Arguments: args,
+			})
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,192 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FakeGateway 本地开发与测试用的支付网关，不访问微信支付：
+// 交易保存在内存中，通过Pay模拟用户付款并生成带HMAC签名的支付通知，
+// 使下单、回调验签、查单与退款的完整流程可以离线验证。重启后内存交易丢失。
+type FakeGateway struct {
+	secret []byte
+
+	mu      sync.Mutex
+	seq     int
+	trades  map[string]*Transaction
+	refunds map[string]*Refund
+}
+
+// NewFakeGateway 创建本地假网关，secret用于支付通知签名
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{
+		secret:  []byte(secret),
+		trades:  make(map[string]*Transaction),
+		refunds: make(map[string]*Refund),
+	}
+}
+
+// fakeNotify 假网关的支付通知报文（明文）
+type fakeNotify struct {
+	OutTradeNo    string     `json:"out_trade_no"`
+	TransactionID string     `json:"transaction_id"`
+	TradeState    TradeState `json:"trade_state"`
+	AmountCents   int        `json:"amount_cents"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+}
+
+// CreateOrder 记录一笔待支付交易，重复下单返回同一交易
+func (g *FakeGateway) CreateOrder(ctx context.Context, req OrderRequest) (*Prepay, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.trades[req.OutTradeNo]; !ok {
+		g.trades[req.OutTradeNo] = &Transaction{
+			OutTradeNo:  req.OutTradeNo,
+			State:       TradeNotPay,
+			AmountCents: req.AmountCents,
+		}
+	}
+
+	prepayID := "fake_prepay_" + req.OutTradeNo
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return &Prepay{
+		PrepayID: prepayID,
+		Params: PayParams{
+			AppID:     "fake",
+			TimeStamp: timestamp,
+			NonceStr:  newNonce(),
+			Package:   "prepay_id=" + prepayID,
+			SignType:  "FAKE",
+			PaySign:   g.sign(timestamp, []byte(prepayID)),
+		},
+	}, nil
+}
+
+// Pay 模拟用户完成付款，返回发往支付通知地址的请求。
+// 对已付款的交易再次调用会生成内容相同的通知，用于验证重复通知的幂等处理。
+func (g *FakeGateway) Pay(outTradeNo string) (*http.Request, error) {
+	g.mu.Lock()
+	txn, ok := g.trades[outTradeNo]
+	if !ok {
+		g.mu.Unlock()
+		return nil, ErrTradeNotFound
+	}
+	if txn.State == TradeNotPay {
+		g.seq++
+		now := time.Now()
+		txn.State = TradeSuccess
+		txn.TransactionID = fmt.Sprintf("fake_txn_%d_%d", now.Unix(), g.seq)
+		txn.PaidAt = &now
+	}
+	notify := fakeNotify{
+		OutTradeNo:    txn.OutTradeNo,
+		TransactionID: txn.TransactionID,
+		TradeState:    txn.State,
+		AmountCents:   txn.AmountCents,
+		PaidAt:        txn.PaidAt,
+	}
+	g.mu.Unlock()
+
+	body, err := json.Marshal(notify)
+	if err != nil {
+		return nil, fmt.Errorf("序列化支付通知失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建支付通知失败: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Fakepay-Timestamp", timestamp)
+	req.Header.Set("Fakepay-Signature", g.sign(timestamp, body))
+	return req, nil
+}
+
+// ParseNotify 校验HMAC签名并解析支付通知
+func (g *FakeGateway) ParseNotify(ctx context.Context, r *http.Request) (*Transaction, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取支付通知失败: %w", err)
+	}
+
+	expected := g.sign(r.Header.Get("Fakepay-Timestamp"), body)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("Fakepay-Signature"))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var notify fakeNotify
+	if err := json.Unmarshal(body, &notify); err != nil {
+		return nil, fmt.Errorf("解析支付通知失败: %w", err)
+	}
+	return &Transaction{
+		OutTradeNo:    notify.OutTradeNo,
+		TransactionID: notify.TransactionID,
+		State:         notify.TradeState,
+		AmountCents:   notify.AmountCents,
+		PaidAt:        notify.PaidAt,
+	}, nil
+}
+
+// QueryOrder 查询内存中的交易
+func (g *FakeGateway) QueryOrder(ctx context.Context, outTradeNo string) (*Transaction, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	txn, ok := g.trades[outTradeNo]
+	if !ok {
+		return nil, ErrTradeNotFound
+	}
+	copied := *txn
+	return &copied, nil
+}
+
+// Refund 退款立即成功，同一退款单号重复提交返回首次的结果
+func (g *FakeGateway) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if refund, ok := g.refunds[req.OutRefundNo]; ok {
+		return refund, nil
+	}
+
+	txn, ok := g.trades[req.OutTradeNo]
+	if !ok {
+		return nil, ErrTradeNotFound
+	}
+	if txn.State != TradeSuccess {
+		return nil, fmt.Errorf("微信支付退款失败: 交易状态为%s", txn.State)
+	}
+	if req.RefundCents <= 0 || req.RefundCents > txn.AmountCents {
+		return nil, fmt.Errorf("微信支付退款失败: 退款金额无效")
+	}
+
+	txn.State = TradeRefund
+	g.seq++
+	refund := &Refund{
+		OutRefundNo: req.OutRefundNo,
+		RefundID:    fmt.Sprintf("fake_refund_%d", g.seq),
+		Status:      "SUCCESS",
+	}
+	g.refunds[req.OutRefundNo] = refund
+	return refund, nil
+}
+
+// sign 计算HMAC-SHA256签名
+func (g *FakeGateway) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
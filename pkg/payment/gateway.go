@@ -0,0 +1,89 @@
+// Package payment 支付网关抽象，按微信支付JSAPI（小程序支付）建模：
+// 下单得到调起支付的参数，支付结果通过异步通知回调，另可主动查单与退款。
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// TradeState 交易状态，取值与微信支付一致
+type TradeState string
+
+const (
+	TradeSuccess  TradeState = "SUCCESS"  // 支付成功
+	TradeNotPay   TradeState = "NOTPAY"   // 未支付
+	TradeClosed   TradeState = "CLOSED"   // 已关闭
+	TradeRefund   TradeState = "REFUND"   // 转入退款
+	TradePayError TradeState = "PAYERROR" // 支付失败
+)
+
+// ErrInvalidSignature 回调签名校验失败
+var ErrInvalidSignature = errors.New("支付回调签名校验失败")
+
+// ErrTradeNotFound 网关侧不存在该订单
+var ErrTradeNotFound = errors.New("支付订单不存在")
+
+// OrderRequest 下单参数
+type OrderRequest struct {
+	OutTradeNo  string    // 商户订单号
+	Description string    // 商品描述
+	AmountCents int       // 金额（分）
+	PayerOpenID string    // 付款人在小程序下的openid
+	ExpiresAt   time.Time // 订单失效时间
+}
+
+// PayParams 小程序 wx.requestPayment 所需参数
+type PayParams struct {
+	AppID     string `json:"appId"`
+	TimeStamp string `json:"timeStamp"`
+	NonceStr  string `json:"nonceStr"`
+	Package   string `json:"package"`
+	SignType  string `json:"signType"`
+	PaySign   string `json:"paySign"`
+}
+
+// Prepay 下单结果
+type Prepay struct {
+	PrepayID string
+	Params   PayParams
+}
+
+// Transaction 交易详情，查单与支付通知均返回该结构
+type Transaction struct {
+	OutTradeNo    string
+	TransactionID string // 网关侧交易号
+	State         TradeState
+	AmountCents   int
+	PaidAt        *time.Time
+}
+
+// RefundRequest 退款参数
+type RefundRequest struct {
+	OutTradeNo  string
+	OutRefundNo string // 商户退款单号，同一退款单号重复提交不会重复退款
+	RefundCents int
+	TotalCents  int
+	Reason      string
+}
+
+// Refund 退款受理结果
+type Refund struct {
+	OutRefundNo string
+	RefundID    string // 网关侧退款单号
+	Status      string // SUCCESS / PROCESSING 等
+}
+
+// Gateway 支付网关
+type Gateway interface {
+	// CreateOrder 下单并返回小程序调起支付的参数
+	CreateOrder(ctx context.Context, req OrderRequest) (*Prepay, error)
+	// ParseNotify 校验支付通知的签名并解析交易，签名不正确时返回ErrInvalidSignature
+	ParseNotify(ctx context.Context, r *http.Request) (*Transaction, error)
+	// QueryOrder 按商户订单号查询交易
+	QueryOrder(ctx context.Context, outTradeNo string) (*Transaction, error)
+	// Refund 申请退款
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+}
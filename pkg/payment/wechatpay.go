@@ -0,0 +1,389 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// notifyMaxSkew 支付通知时间戳允许的最大偏差，超出视为重放
+const notifyMaxSkew = 5 * time.Minute
+
+// WeChatPayConfig 微信支付商户配置（APIv3）
+type WeChatPayConfig struct {
+	AppID              string // 小程序appid
+	MchID              string // 商户号
+	MchSerialNo        string // 商户API证书序列号
+	PrivateKeyPath     string // 商户API私钥（PEM）
+	APIv3Key           string // APIv3密钥，用于解密回调报文
+	PlatformPublicPath string // 微信支付平台公钥（PEM），用于验证应答与回调签名
+	NotifyURL          string // 支付结果通知地址
+}
+
+// WeChatPay 微信支付JSAPI网关
+type WeChatPay struct {
+	cfg         WeChatPayConfig
+	privateKey  *rsa.PrivateKey
+	platformKey *rsa.PublicKey
+	baseURL     string
+	httpClient  *http.Client
+}
+
+// NewWeChatPay 创建微信支付网关。未配置商户号时仍返回网关，调用时报未配置错误。
+func NewWeChatPay(cfg WeChatPayConfig) (*WeChatPay, error) {
+	g := &WeChatPay{
+		cfg:     cfg,
+		baseURL: "https://api.mch.weixin.qq.com",
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	if cfg.MchID == "" {
+		return g, nil
+	}
+
+	privateKey, err := loadPrivateKey(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载商户私钥失败: %w", err)
+	}
+	platformKey, err := loadPublicKey(cfg.PlatformPublicPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载微信支付平台公钥失败: %w", err)
+	}
+	g.privateKey = privateKey
+	g.platformKey = platformKey
+	return g, nil
+}
+
+// wechatTransaction 微信支付交易报文（查单应答与回调解密后的内容）
+type wechatTransaction struct {
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	TradeState    string `json:"trade_state"`
+	SuccessTime   string `json:"success_time"`
+	Amount        struct {
+		Total int `json:"total"`
+	} `json:"amount"`
+}
+
+func (t *wechatTransaction) toTransaction() *Transaction {
+	txn := &Transaction{
+		OutTradeNo:    t.OutTradeNo,
+		TransactionID: t.TransactionID,
+		State:         TradeState(t.TradeState),
+		AmountCents:   t.Amount.Total,
+	}
+	if paidAt, err := time.Parse(time.RFC3339, t.SuccessTime); err == nil {
+		txn.PaidAt = &paidAt
+	}
+	return txn
+}
+
+// CreateOrder JSAPI下单，并对小程序调起支付的参数签名
+func (g *WeChatPay) CreateOrder(ctx context.Context, req OrderRequest) (*Prepay, error) {
+	body := map[string]interface{}{
+		"appid":        g.cfg.AppID,
+		"mchid":        g.cfg.MchID,
+		"description":  req.Description,
+		"out_trade_no": req.OutTradeNo,
+		"notify_url":   g.cfg.NotifyURL,
+		"amount":       map[string]interface{}{"total": req.AmountCents, "currency": "CNY"},
+		"payer":        map[string]interface{}{"openid": req.PayerOpenID},
+	}
+	if !req.ExpiresAt.IsZero() {
+		body["time_expire"] = req.ExpiresAt.Format(time.RFC3339)
+	}
+
+	var result struct {
+		PrepayID string `json:"prepay_id"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/v3/pay/transactions/jsapi", body, &result); err != nil {
+		return nil, fmt.Errorf("微信支付下单失败: %w", err)
+	}
+
+	params := PayParams{
+		AppID:     g.cfg.AppID,
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  newNonce(),
+		Package:   "prepay_id=" + result.PrepayID,
+		SignType:  "RSA",
+	}
+	sign, err := g.sign(params.AppID + "\n" + params.TimeStamp + "\n" + params.NonceStr + "\n" + params.Package + "\n")
+	if err != nil {
+		return nil, err
+	}
+	params.PaySign = sign
+
+	return &Prepay{PrepayID: result.PrepayID, Params: params}, nil
+}
+
+// ParseNotify 验证支付通知签名并解密交易报文
+func (g *WeChatPay) ParseNotify(ctx context.Context, r *http.Request) (*Transaction, error) {
+	if err := g.configured(); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取支付通知失败: %w", err)
+	}
+
+	ts, err := strconv.ParseInt(r.Header.Get("Wechatpay-Timestamp"), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > notifyMaxSkew || skew < -notifyMaxSkew {
+		return nil, ErrInvalidSignature
+	}
+	if err := g.verify(r.Header, body); err != nil {
+		return nil, err
+	}
+
+	var notify struct {
+		EventType string `json:"event_type"`
+		Resource  struct {
+			Algorithm      string `json:"algorithm"`
+			Ciphertext     string `json:"ciphertext"`
+			AssociatedData string `json:"associated_data"`
+			Nonce          string `json:"nonce"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal(body, &notify); err != nil {
+		return nil, fmt.Errorf("解析支付通知失败: %w", err)
+	}
+
+	plaintext, err := g.decrypt(notify.Resource.Ciphertext, notify.Resource.Nonce, notify.Resource.AssociatedData)
+	if err != nil {
+		return nil, fmt.Errorf("解密支付通知失败: %w", err)
+	}
+
+	var txn wechatTransaction
+	if err := json.Unmarshal(plaintext, &txn); err != nil {
+		return nil, fmt.Errorf("解析支付通知失败: %w", err)
+	}
+	return txn.toTransaction(), nil
+}
+
+// QueryOrder 按商户订单号查单
+func (g *WeChatPay) QueryOrder(ctx context.Context, outTradeNo string) (*Transaction, error) {
+	path := fmt.Sprintf("/v3/pay/transactions/out-trade-no/%s?mchid=%s",
+		url.PathEscape(outTradeNo), url.QueryEscape(g.cfg.MchID))
+
+	var txn wechatTransaction
+	if err := g.do(ctx, http.MethodGet, path, nil, &txn); err != nil {
+		return nil, err
+	}
+	return txn.toTransaction(), nil
+}
+
+// Refund 申请退款
+func (g *WeChatPay) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	body := map[string]interface{}{
+		"out_trade_no":  req.OutTradeNo,
+		"out_refund_no": req.OutRefundNo,
+		"reason":        req.Reason,
+		"amount": map[string]interface{}{
+			"refund":   req.RefundCents,
+			"total":    req.TotalCents,
+			"currency": "CNY",
+		},
+	}
+
+	var result struct {
+		RefundID    string `json:"refund_id"`
+		OutRefundNo string `json:"out_refund_no"`
+		Status      string `json:"status"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/v3/refund/domestic/refunds", body, &result); err != nil {
+		return nil, fmt.Errorf("微信支付退款失败: %w", err)
+	}
+	return &Refund{OutRefundNo: result.OutRefundNo, RefundID: result.RefundID, Status: result.Status}, nil
+}
+
+// do 发送签名请求，校验应答签名并解析应答
+func (g *WeChatPay) do(ctx context.Context, method, path string, body, out interface{}) error {
+	if err := g.configured(); err != nil {
+		return err
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := newNonce()
+	signature, err := g.sign(method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + string(payload) + "\n")
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", fmt.Sprintf(
+		`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		g.cfg.MchID, nonce, signature, timestamp, g.cfg.MchSerialNo))
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &apiErr)
+		if apiErr.Code == "ORDER_NOT_EXIST" || apiErr.Code == "RESOURCE_NOT_EXISTS" {
+			return ErrTradeNotFound
+		}
+		return fmt.Errorf("请求失败，状态码: %d %s %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+
+	if err := g.verify(resp.Header, respBody); err != nil {
+		return fmt.Errorf("应答签名校验失败: %w", err)
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// configured 检查商户配置是否完整
+func (g *WeChatPay) configured() error {
+	if g.cfg.MchID == "" || g.privateKey == nil || g.platformKey == nil {
+		return fmt.Errorf("微信支付未配置（WECHAT_PAY_MCH_ID等）")
+	}
+	return nil
+}
+
+// sign 使用商户私钥对消息做SHA256-RSA签名
+func (g *WeChatPay) sign(message string) (string, error) {
+	digest := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, g.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("签名失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verify 使用平台公钥验证应答或回调的签名
+func (g *WeChatPay) verify(header http.Header, body []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(header.Get("Wechatpay-Signature"))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+
+	message := header.Get("Wechatpay-Timestamp") + "\n" + header.Get("Wechatpay-Nonce") + "\n" + string(body) + "\n"
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(g.platformKey, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// decrypt 使用APIv3密钥解密AEAD_AES_256_GCM报文
+func (g *WeChatPay) decrypt(ciphertext, nonce, associatedData string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher([]byte(g.cfg.APIv3Key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, []byte(nonce), data, []byte(associatedData))
+}
+
+// loadPrivateKey 读取PKCS#8格式的RSA私钥
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("不是RSA私钥")
+	}
+	return rsaKey, nil
+}
+
+// loadPublicKey 读取PKIX格式的RSA公钥
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("不是RSA公钥")
+	}
+	return rsaKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是有效的PEM文件", path)
+	}
+	return block, nil
+}
+
+// newNonce 生成32位随机串
+func newNonce() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
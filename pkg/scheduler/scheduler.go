@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// JobFunc 定时任务函数，ctx在调度器关闭时取消
+type JobFunc func(ctx context.Context) error
+
+// job 已注册的定时任务
+type job struct {
+	name     string
+	interval time.Duration
+	fn       JobFunc
+}
+
+// Scheduler 按固定间隔执行后台任务的简单调度器。
+// 每个任务在独立的协程中串行执行（上一次未结束时不会重叠执行），启动时立即执行一次。
+// 多实例部署时任务本身需保证幂等。
+type Scheduler struct {
+	logger *zap.Logger
+	jobs   []job
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建调度器
+func New(logger *zap.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Every 注册按interval间隔执行的任务，需在Start之前调用
+func (s *Scheduler) Every(name string, interval time.Duration, fn JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, fn: fn})
+}
+
+// Start 启动所有任务（非阻塞）
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.run(ctx, j)
+	}
+
+	s.logger.Info("定时任务调度器已启动", zap.Int("jobs", len(s.jobs)))
+}
+
+// Shutdown 停止调度并等待正在执行的任务结束；ctx到期时直接返回ctx的错误
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("定时任务调度器已停止")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 循环执行单个任务
+func (s *Scheduler) run(ctx context.Context, j job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := j.fn(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("定时任务执行失败", zap.String("job", j.name), zap.Error(err))
+		} else {
+			s.logger.Debug("定时任务执行完成", zap.String("job", j.name), zap.Duration("elapsed", time.Since(start)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Session code2session换取的登录会话
+type Session struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+}
+
+// Client 微信小程序服务端接口客户端
+type Client struct {
+	appID      string
+	appSecret  string
+	baseURL    string
+	httpClient *http.Client
+
+	// 接口调用凭证缓存
+	tokenMu     sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewClient 创建微信小程序客户端
+func NewClient(appID, appSecret string) *Client {
+	return &Client{
+		appID:     appID,
+		appSecret: appSecret,
+		baseURL:   "https://api.weixin.qq.com",
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// code2SessionResponse code2session接口响应
+type code2SessionResponse struct {
+	Session
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// Code2Session 用小程序wx.login获取的code换取openid与session_key
+func (c *Client) Code2Session(ctx context.Context, code string) (*Session, error) {
+	if c.appID == "" || c.appSecret == "" {
+		return nil, fmt.Errorf("微信登录未配置（WECHAT_APP_ID/WECHAT_APP_SECRET）")
+	}
+
+	query := url.Values{}
+	query.Set("appid", c.appID)
+	query.Set("secret", c.appSecret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+	reqURL := fmt.Sprintf("%s/sns/jscode2session?%s", c.baseURL, query.Encode())
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求失败，状态码: %d", resp.StatusCode)
+	}
+
+	// 微信接口出错时同样返回200，通过errcode区分
+	var result code2SessionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return nil, fmt.Errorf("code2session失败: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	if result.OpenID == "" {
+		return nil, fmt.Errorf("code2session未返回openid")
+	}
+
+	return &result.Session, nil
+}
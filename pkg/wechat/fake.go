@@ -0,0 +1,28 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FakeClient 本地开发与测试用的code2session实现，不访问微信服务器：
+// openid由code直接派生（"fake_" + code），同一code总是得到同一用户
+type FakeClient struct{}
+
+// NewFakeClient 创建本地假客户端
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+// Code2Session 由code派生openid
+func (c *FakeClient) Code2Session(ctx context.Context, code string) (*Session, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, fmt.Errorf("code2session失败: 40029 invalid code")
+	}
+	return &Session{
+		OpenID:     "fake_" + code,
+		SessionKey: "fake_session_key",
+	}, nil
+}
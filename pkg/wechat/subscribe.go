@@ -0,0 +1,137 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SubscribeMessage 小程序订阅消息
+type SubscribeMessage struct {
+	ToUser     string            // 接收者openid
+	TemplateID string            // 订阅消息模板ID
+	Page       string            // 点击消息后打开的小程序页面
+	Data       map[string]string // 模板关键词（如 thing1、time2）到内容
+}
+
+// wechatResponse 微信接口的通用错误字段
+type wechatResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// SendSubscribeMessage 发送订阅消息。用户需先在小程序内通过 wx.requestSubscribeMessage 订阅对应模板。
+func (c *Client) SendSubscribeMessage(ctx context.Context, msg SubscribeMessage) error {
+	token, err := c.AccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]map[string]string, len(msg.Data))
+	for key, value := range msg.Data {
+		data[key] = map[string]string{"value": value}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"touser":      msg.ToUser,
+		"template_id": msg.TemplateID,
+		"page":        msg.Page,
+		"data":        data,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("%s/cgi-bin/message/subscribe/send?access_token=%s", c.baseURL, url.QueryEscape(token))
+	var result wechatResponse
+	if err := c.postJSON(ctx, reqURL, body, &result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		// 凭证失效时清除缓存，下次调用重新获取
+		if result.ErrCode == 40001 || result.ErrCode == 42001 {
+			c.tokenMu.Lock()
+			c.accessToken = ""
+			c.tokenMu.Unlock()
+		}
+		return fmt.Errorf("发送订阅消息失败: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// AccessToken 获取接口调用凭证，过期前复用缓存
+func (c *Client) AccessToken(ctx context.Context) (string, error) {
+	if c.appID == "" || c.appSecret == "" {
+		return "", fmt.Errorf("微信接口未配置（WECHAT_APP_ID/WECHAT_APP_SECRET）")
+	}
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.tokenExpiry) {
+		return c.accessToken, nil
+	}
+
+	query := url.Values{}
+	query.Set("grant_type", "client_credential")
+	query.Set("appid", c.appID)
+	query.Set("secret", c.appSecret)
+	reqURL := fmt.Sprintf("%s/cgi-bin/token?%s", c.baseURL, query.Encode())
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("请求失败，状态码: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		wechatResponse
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.ErrCode != 0 || result.AccessToken == "" {
+		return "", fmt.Errorf("获取access_token失败: %d %s", result.ErrCode, result.ErrMsg)
+	}
+
+	// 提前5分钟刷新
+	c.accessToken = result.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 5*time.Minute)
+	return c.accessToken, nil
+}
+
+// postJSON 发送JSON请求并解析响应
+func (c *Client) postJSON(ctx context.Context, reqURL string, body []byte, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求失败，状态码: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}